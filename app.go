@@ -15,6 +15,7 @@ import (
 	"os"
 	"os/exec"
 	_ "os/exec"
+	"path"
 	"path/filepath"
 	//	"runtime"
 	_ "runtime"
@@ -34,8 +35,21 @@ type App struct {
 	fileDialogService *services.FileDialogService
 	cacheService      *services.CacheService
 	clipService       *services.ClipService
+	bookmarkService   *services.BookmarkService
+	watchHistory      *services.WatchHistoryService
+	playlistService   *services.PlaylistService
+	recordingService  *services.RecordingService
+	seekPreviews      *services.SeekPreviewService
+	contactSheets     *services.ContactSheetService
+	trackService      *services.TrackService
+	deadAir           *services.DeadAirService
+	audioAnalysis     *services.AudioAnalysisService
+	timing            *services.TimingService
+	integrity         *services.IntegrityService
+	faststart         *services.FaststartService
 	integrations      *integrations.Manager
 	currentVideoPath  string
+	currentRecording  *services.RecordingGroup // Set while a multi-part recording is loaded
 	appDataDir        string
 }
 
@@ -80,12 +94,25 @@ func NewApp() *App {
 	}
 
 	cacheService := services.NewCacheService(appDataDir)
+	watchHistory := services.NewWatchHistoryService(appDataDir, cacheService)
 
 	return &App{
-		videoService:      services.NewVideoService(),
+		videoService:      services.NewVideoService(cacheService),
 		fileDialogService: services.NewFileDialogService(),
 		cacheService:      cacheService,
-		clipService:       services.NewClipService(appDataDir),
+		clipService:       services.NewClipService(appDataDir, cacheService),
+		bookmarkService:   services.NewBookmarkService(appDataDir, cacheService),
+		watchHistory:      watchHistory,
+		playlistService:   services.NewPlaylistService(appDataDir, cacheService, watchHistory),
+		recordingService:  services.NewRecordingService(cacheService),
+		seekPreviews:      services.NewSeekPreviewService(appDataDir, cacheService),
+		contactSheets:     services.NewContactSheetService(cacheService),
+		trackService:      services.NewTrackService(appDataDir, cacheService),
+		deadAir:           services.NewDeadAirService(appDataDir, cacheService),
+		audioAnalysis:     services.NewAudioAnalysisService(appDataDir, cacheService),
+		timing:            services.NewTimingService(appDataDir, cacheService),
+		integrity:         services.NewIntegrityService(appDataDir, cacheService),
+		faststart:         services.NewFaststartService(cacheService),
 		integrations:      integrations.NewManager(appDataDir, cacheService),
 		appDataDir:        appDataDir,
 	}
@@ -129,10 +156,56 @@ func (a *App) startup(ctx context.Context) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		http.ServeFile(w, r, filePath)
 	})
+	// Set up a handler for serving seek-bar sprite sheets and thumbnail tracks
+	http.HandleFunc("/previews/", serveTrackFiles("/previews/", a.seekPreviews.PreviewsDir()))
+	// Set up a handler for serving audio track variants and converted subtitles
+	http.HandleFunc("/tracks/", serveTrackFiles("/tracks/", a.trackService.TracksDir()))
+	a.seekPreviews.SetReadyHandler(func(previews services.SeekPreviews) {
+		wailsRuntime.EventsEmit(a.ctx, "seekpreviews:ready", previews)
+	})
+	a.deadAir.SetReadyHandler(func(analysis services.DeadAirAnalysis) {
+		wailsRuntime.EventsEmit(a.ctx, "deadair:ready", analysis)
+	})
+	a.audioAnalysis.SetReadyHandler(func(analysis services.AudioAnalysis) {
+		wailsRuntime.EventsEmit(a.ctx, "audioanalysis:ready", analysis)
+	})
+	a.trackService.SetReadyHandler(func(variant services.TrackVariant) {
+		wailsRuntime.EventsEmit(a.ctx, "trackvariant:ready", variant)
+	})
+	a.clipService.SetThumbnailReadyHandler(func(record services.ClipRecord) {
+		wailsRuntime.EventsEmit(a.ctx, "clipthumbnail:ready", record)
+	})
 	// Start the HTTP server
 	go http.ListenAndServe(":8080", nil)
 }
 
+// shutdown is called when the app is closing
+func (a *App) shutdown(ctx context.Context) {
+	if err := a.watchHistory.Flush(); err != nil {
+		fmt.Printf("Failed to save watch history: %v\n", err)
+	}
+}
+
+// serveTrackFiles returns a handler serving the files of a generated media
+// directory, such as seek previews or track variants, under prefix
+func serveTrackFiles(prefix string, dir string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Cleaning the path keeps requests inside the directory
+		relPath := path.Clean("/" + strings.TrimPrefix(r.URL.Path, prefix))
+		filePath := filepath.Join(dir, filepath.FromSlash(relPath))
+		if _, err := os.Stat(filePath); err != nil {
+			http.Error(w, "File not found", http.StatusNotFound)
+			return
+		}
+		// Text tracks are loaded cross-origin by the video element
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if strings.HasSuffix(filePath, ".vtt") {
+			w.Header().Set("Content-Type", "text/vtt")
+		}
+		http.ServeFile(w, r, filePath)
+	}
+}
+
 // GetFanslyStreams retrieves all streams from the Fansly database
 func (a *App) GetFanslyStreams() (fansly.StreamsResult, error) {
 	return a.integrations.FanslyService.GetStreams()
@@ -164,14 +237,48 @@ func (a *App) OpenVideoFile() (string, error) {
 
 // LoadVideoFromPath loads a video from a specific path
 func (a *App) LoadVideoFromPath(path string) (string, error) {
+	a.currentRecording = nil
+	return a.loadVideo(path)
+}
+
+// loadVideo makes path the video being played and served
+func (a *App) loadVideo(path string) (string, error) {
 	err := a.videoService.LoadVideo(path)
 	if err != nil {
 		return "", err
 	}
 	// Store the current video path
 	a.currentVideoPath = path
-	// Return a URL that can be used by the video element
-	return "http://localhost:8080/video/" + filepath.Base(path), nil
+	// Put the chat back in sync after recording drops. The parts of a
+	// multi-part recording share one chat, which isn't corrected.
+	timeMap := services.TimeMap{}
+	if a.currentRecording == nil {
+		if analysis, err := a.timing.GetTimingAnalysis(path); err == nil {
+			timeMap = analysis.TimeMap
+		} else {
+			fmt.Printf("Failed to read timing analysis: %v\n", err)
+		}
+	}
+	a.videoService.SetTimeMap(timeMap)
+	if err := a.watchHistory.RecordOpened(path); err != nil {
+		fmt.Printf("Failed to update watch history: %v\n", err)
+	}
+	return videoURL(path), nil
+}
+
+// videoURL returns the URL the video element plays the current video from
+func videoURL(path string) string {
+	return "http://localhost:8080/video/" + filepath.Base(path)
+}
+
+// historyVideoPath returns the video that watch history and bookmarks are
+// kept for: the first part of a loaded multi-part recording, whose positions
+// are on the recording's timeline, otherwise the current video
+func (a *App) historyVideoPath() string {
+	if a.currentRecording != nil {
+		return a.currentRecording.Parts[0].Path
+	}
+	return a.currentVideoPath
 }
 
 // OpenChatFile opens a dialog to select a chat JSON file
@@ -204,6 +311,7 @@ func (a *App) OpenChatFile(path []string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to load chat file: %v", err)
 	}
+	a.rememberChatFile(chatFilePath)
 	// Return the URL for the chat file
 	return fmt.Sprintf("http://localhost:8080/video/%s", filepath.Base(chatFilePath)), nil
 }
@@ -214,9 +322,20 @@ func (a *App) LoadChatFromPath(path string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to load chat file: %v", err)
 	}
+	a.rememberChatFile(path)
 	return path, nil
 }
 
+// rememberChatFile records the chat file paired with the current video in the watch history
+func (a *App) rememberChatFile(chatPath string) {
+	if a.currentVideoPath == "" {
+		return
+	}
+	if err := a.watchHistory.SetChatFile(a.historyVideoPath(), chatPath); err != nil {
+		fmt.Printf("Failed to update watch history: %v\n", err)
+	}
+}
+
 // GetMessagesAtTime returns messages at a specific time
 func (a *App) GetMessagesAtTime(currentTime float64, windowSize float64) []models.ChatMessage {
 	return a.videoService.GetMessagesAtTime(currentTime, windowSize)
@@ -227,6 +346,85 @@ func (a *App) GetVideoFileInfo() map[string]string {
 	return a.videoService.GetVideoFileInfo()
 }
 
+// GetMediaInfo returns the container, streams, chapters and tags of the current video
+func (a *App) GetMediaInfo() (services.MediaInfo, error) {
+	return a.videoService.GetMediaInfo()
+}
+
+// GetMediaTracks returns the audio and subtitle tracks of the current video
+func (a *App) GetMediaTracks() (services.MediaTracks, error) {
+	if a.currentVideoPath == "" {
+		return services.MediaTracks{}, fmt.Errorf("no video is currently loaded")
+	}
+	return a.trackService.GetTracks(a.currentVideoPath)
+}
+
+// SelectAudioTracks returns the copy of the current video that plays the
+// selected audio tracks. The copy is built in the background the first time
+// a selection is played, and the "trackvariant:ready" event reports when it
+// is done; an empty selection plays the video as is.
+func (a *App) SelectAudioTracks(selection services.TrackSelection) (services.TrackVariant, error) {
+	if a.currentVideoPath == "" {
+		return services.TrackVariant{}, fmt.Errorf("no video is currently loaded")
+	}
+	if len(selection.AudioStreams) == 0 {
+		return services.TrackVariant{
+			VideoPath: a.currentVideoPath,
+			Selection: selection,
+			URL:       videoURL(a.currentVideoPath),
+		}, nil
+	}
+	return a.trackService.PrepareTrackVariant(a.currentVideoPath, selection)
+}
+
+// GetSubtitleTrack converts a subtitle track of the current video to WebVTT
+// and returns its URL
+func (a *App) GetSubtitleTrack(streamIndex int) (string, error) {
+	if a.currentVideoPath == "" {
+		return "", fmt.Errorf("no video is currently loaded")
+	}
+	return a.trackService.PrepareSubtitleTrack(a.currentVideoPath, streamIndex)
+}
+
+// GetTranscript returns the transcript of the current video, without cues if it has none
+func (a *App) GetTranscript() services.Transcript {
+	return a.videoService.Transcript
+}
+
+// LoadTranscriptFile loads an SRT, WebVTT or Whisper JSON transcript for the current video
+func (a *App) LoadTranscriptFile(path string) (services.Transcript, error) {
+	if err := a.videoService.LoadTranscriptFile(path); err != nil {
+		return services.Transcript{}, fmt.Errorf("failed to load transcript: %v", err)
+	}
+	return a.videoService.Transcript, nil
+}
+
+// GetTranscriptAtTime returns the transcript cues showing within a time window
+func (a *App) GetTranscriptAtTime(currentTime float64, windowSize float64) []models.TranscriptCue {
+	return a.videoService.Transcript.CuesAt(currentTime, windowSize)
+}
+
+// SearchTranscript returns the transcript cues containing every word of query.
+// Each hit's start time is where to seek to.
+func (a *App) SearchTranscript(query string) []services.TranscriptHit {
+	return a.videoService.Transcript.Search(query)
+}
+
+// GetTranscriptTrack returns the URL of the current transcript as a WebVTT track
+func (a *App) GetTranscriptTrack() (string, error) {
+	if a.currentVideoPath == "" {
+		return "", fmt.Errorf("no video is currently loaded")
+	}
+	if a.videoService.Transcript.Path == "" {
+		return "", fmt.Errorf("no transcript is loaded")
+	}
+	// A track plays on one part's time, the stitched transcript is on the recording's
+	if a.currentRecording != nil {
+		return "", fmt.Errorf("a multi-part recording's transcript can't be shown as a track, use GetTranscriptAtTime")
+	}
+	return a.trackService.PrepareTranscriptTrack(a.currentVideoPath, a.videoService.Transcript)
+}
+
 // GetAllChatMessages returns all chat messages
 func (a *App) GetAllChatMessages() []models.ChatMessage {
 	return a.videoService.ChatMessages
@@ -261,27 +459,278 @@ func decodeFilePath(encodedPath string) (string, error) {
 
 // CreateClip creates a video clip from the current video
 func (a *App) CreateClip(startTime float64, duration float64, title string) services.ClipResult {
+	return a.CreateClipWithOptions(startTime, duration, title, services.ClipOptions{})
+}
+
+// CreateClipWithOptions creates a video clip from the current video with extra
+// options such as burning the loaded chat into the clip
+func (a *App) CreateClipWithOptions(startTime float64, duration float64, title string, options services.ClipOptions) services.ClipResult {
+	if errorMessage := a.clipPrerequisiteError(); errorMessage != "" {
+		return services.ClipResult{Success: false, ErrorMessage: errorMessage}
+	}
+
+	return a.clipService.CreateClipWithOptions(a.currentClipSource(), startTime, duration, title, options)
+}
+
+// CreateClipAroundMessage clips the moment around a loaded chat message, e.g.
+// one found through chat search. Pre/post-roll of 0 use the defaults and an
+// empty title uses the message text.
+func (a *App) CreateClipAroundMessage(messageID string, preRoll float64, postRoll float64, title string, options services.ClipOptions) services.ClipResult {
+	if errorMessage := a.clipPrerequisiteError(); errorMessage != "" {
+		return services.ClipResult{Success: false, ErrorMessage: errorMessage}
+	}
+
+	message, ok := a.videoService.FindChatMessage(messageID)
+	if !ok {
+		return services.ClipResult{Success: false, ErrorMessage: "Chat message not found"}
+	}
+
+	return a.clipService.CreateClipAroundMessage(a.currentClipSource(), message, preRoll, postRoll, title, options)
+}
+
+// CreateAnimatedClip exports a short range of the current video as an animated GIF, WebP or APNG
+func (a *App) CreateAnimatedClip(startTime float64, duration float64, title string, options services.AnimatedClipOptions) services.ClipResult {
+	if errorMessage := a.clipPrerequisiteError(); errorMessage != "" {
+		return services.ClipResult{Success: false, ErrorMessage: errorMessage}
+	}
+
+	return a.clipService.CreateAnimatedClip(a.currentClipSource(), startTime, duration, title, options)
+}
+
+// GrabFrame saves the frame of the current video at time as an image
+func (a *App) GrabFrame(time float64, title string, options services.FrameGrabOptions) services.ClipResult {
+	if errorMessage := a.clipPrerequisiteError(); errorMessage != "" {
+		return services.ClipResult{Success: false, ErrorMessage: errorMessage}
+	}
+
+	return a.clipService.GrabFrame(a.currentClipSource(), time, title, options)
+}
+
+// GrabFrameBurst grabs frames of the current video every few seconds over a
+// range and saves them as a grid image
+func (a *App) GrabFrameBurst(startTime float64, endTime float64, title string, options services.FrameBurstOptions) services.FrameBurstResult {
+	if errorMessage := a.clipPrerequisiteError(); errorMessage != "" {
+		return services.FrameBurstResult{Success: false, ErrorMessage: errorMessage}
+	}
+
+	return a.clipService.GrabFrameBurst(a.currentClipSource(), startTime, endTime, title, options)
+}
+
+// CreateCompilation joins ranges from one or more videos into a single highlight
+// reel. Progress is reported through the "compilation:progress" event.
+func (a *App) CreateCompilation(segments []services.CompilationSegment, title string, options services.CompilationOptions) services.ClipResult {
+	if errorMessage := ffmpegMissingError(); errorMessage != "" {
+		return services.ClipResult{Success: false, ErrorMessage: errorMessage}
+	}
+
+	// Hand-picked segments are kept as they are. When asked, segments are kept
+	// from opening or closing on dead air in analysed videos, scored with the
+	// loaded chat like the player's skip buttons.
+	if options.TrimDeadAir {
+		for i, segment := range segments {
+			var chat []models.ChatMessage
+			if segment.SourcePath == a.currentVideoPath && a.currentRecording == nil {
+				chat = a.videoService.ChatMessages
+			}
+			analysis, err := a.deadAir.CachedSkipSegments(segment.SourcePath, chat)
+			if err != nil || !analysis.Analyzed {
+				continue
+			}
+			segments[i].StartTime, segments[i].EndTime = services.TrimToContent(analysis.Segments, segment.StartTime, segment.EndTime)
+		}
+	}
+
+	return a.clipService.CreateCompilation(segments, title, options, func(progress services.CompilationProgress) {
+		wailsRuntime.EventsEmit(a.ctx, "compilation:progress", progress)
+	})
+}
+
+// PreviewBatchClipsFromFile reads a CSV/TSV or EDL marker file and checks its
+// rows against the current video without creating clips. An empty path opens
+// a file dialog; an empty preview means the dialog was cancelled.
+func (a *App) PreviewBatchClipsFromFile(path string) (services.BatchClipPreview, error) {
+	if a.currentVideoPath == "" {
+		return services.BatchClipPreview{}, fmt.Errorf("no video is currently loaded")
+	}
+
+	if path == "" {
+		var err error
+		path, err = a.fileDialogService.OpenMarkerFile()
+		if err != nil {
+			return services.BatchClipPreview{}, fmt.Errorf("failed to open file dialog: %v", err)
+		}
+		if path == "" {
+			return services.BatchClipPreview{}, nil // User cancelled
+		}
+	}
+
+	rows, err := services.ParseMarkerFile(path, a.currentVideoPath)
+	if err != nil {
+		return services.BatchClipPreview{}, err
+	}
+	return services.PreviewBatchClips(a.currentVideoPath, a.batchClipDuration(), rows)
+}
+
+// PreviewBatchClipsFromChapters lists the current video's chapters as clip
+// rows. For a multi-part recording these are the chapters of the part playing
+// now, moved onto the recording's timeline.
+func (a *App) PreviewBatchClipsFromChapters() (services.BatchClipPreview, error) {
 	if a.currentVideoPath == "" {
-		return services.ClipResult{Success: false, ErrorMessage: "No video is currently loaded"}
+		return services.BatchClipPreview{}, fmt.Errorf("no video is currently loaded")
 	}
 
-	// Check if ffmpeg is available
-	_, err := exec.LookPath("ffmpeg")
+	rows, err := services.VideoChapters(a.currentVideoPath)
 	if err != nil {
-		return services.ClipResult{
-			Success:      false,
-			ErrorMessage: "FFmpeg is not installed or not in PATH. Please install FFmpeg to use the clip feature.",
+		return services.BatchClipPreview{}, err
+	}
+	if a.currentRecording != nil {
+		for _, part := range a.currentRecording.Parts {
+			if part.Path != a.currentVideoPath {
+				continue
+			}
+			for i := range rows {
+				rows[i].StartTime += part.Offset
+				rows[i].EndTime += part.Offset
+			}
 		}
 	}
+	return services.PreviewBatchClips(a.currentVideoPath, a.batchClipDuration(), rows)
+}
+
+// batchClipDuration returns the length of the timeline batch clips are cut
+// from: the whole recording when one is loaded, or 0 to read it from the video
+func (a *App) batchClipDuration() float64 {
+	if a.currentRecording != nil {
+		return a.currentRecording.Duration
+	}
+	return 0
+}
+
+// CreateBatchClips creates one clip of the current video per previewed row.
+// Progress is reported through the "batchclip:progress" event.
+func (a *App) CreateBatchClips(rows []services.BatchClipRow, options services.ClipOptions) []services.ClipResult {
+	if errorMessage := a.clipPrerequisiteError(); errorMessage != "" {
+		return []services.ClipResult{{Success: false, ErrorMessage: errorMessage}}
+	}
 
-	return a.clipService.CreateClip(a.currentVideoPath, startTime, duration, title)
+	return a.clipService.CreateBatchClips(a.currentClipSource(), rows, options, func(progress services.BatchClipProgress) {
+		wailsRuntime.EventsEmit(a.ctx, "batchclip:progress", progress)
+	})
 }
 
-// GetClips returns a list of all saved clips
-func (a *App) GetClips() []string {
+// ExportMarkers saves markers for the current video as an EDL, FCPXML or
+// marker CSV chosen in a save dialog, using the video's frame rate. Returns
+// the saved path, or "" if the dialog was cancelled.
+func (a *App) ExportMarkers(markers []services.EditorMarker, options services.MarkerExportOptions) (string, error) {
+	if a.currentVideoPath == "" {
+		return "", fmt.Errorf("no video is currently loaded")
+	}
+
+	baseName := strings.TrimSuffix(filepath.Base(a.currentVideoPath), filepath.Ext(a.currentVideoPath))
+	outputPath, err := a.fileDialogService.SaveExportFile("Export Markers", baseName+services.MarkerFileExtension(options.Format))
+	if err != nil {
+		return "", fmt.Errorf("failed to open file dialog: %v", err)
+	}
+	if outputPath == "" {
+		return "", nil // User cancelled
+	}
+
+	if err := services.ExportMarkers(a.currentVideoPath, markers, a.videoService.ChatMessages, options, outputPath); err != nil {
+		return "", err
+	}
+	return outputPath, nil
+}
+
+// clipPrerequisiteError checks that a video is loaded and ffmpeg is available,
+// returning a user facing message if not
+func (a *App) clipPrerequisiteError() string {
+	if a.currentVideoPath == "" {
+		return "No video is currently loaded"
+	}
+
+	return ffmpegMissingError()
+}
+
+// ffmpegMissingError returns a user facing message if ffmpeg is not available
+func ffmpegMissingError() string {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return "FFmpeg is not installed or not in PATH. Please install FFmpeg to use the clip feature."
+	}
+
+	return ""
+}
+
+// currentClipSource describes the loaded video and chat for the clip service
+func (a *App) currentClipSource() services.ClipSource {
+	source := services.ClipSource{
+		Path:         a.currentVideoPath,
+		Model:        a.integrations.FanslyService.GetModelForPath(a.currentVideoPath),
+		ChatMessages: a.videoService.ChatMessages,
+	}
+	// Clip times of a multi-part recording are on the recording's timeline
+	if a.currentRecording != nil {
+		source.Path = a.currentRecording.Parts[0].Path
+		source.Parts = a.currentRecording.Parts
+		return source
+	}
+	// Only a finished analysis is used; clipping doesn't wait for one
+	if analysis, err := a.deadAir.CachedSkipSegments(a.currentVideoPath, a.videoService.ChatMessages); err == nil {
+		source.SkipSegments = analysis.Segments
+	}
+	return source
+}
+
+// GetClips returns all saved clips with their metadata and thumbnails.
+// Missing thumbnails are generated in the background; the
+// "clipthumbnail:ready" event delivers each updated clip.
+func (a *App) GetClips() []services.ClipRecord {
 	return a.clipService.GetClips()
 }
 
+// GetClipMetadata returns the full metadata of a clip, including its chat slice
+func (a *App) GetClipMetadata(clipPath string) (services.ClipMetadata, error) {
+	return a.clipService.GetClipMetadata(clipPath)
+}
+
+// RenameClip renames a clip and updates its title
+func (a *App) RenameClip(clipPath string, newTitle string) (services.ClipRecord, error) {
+	return a.clipService.RenameClip(clipPath, newTitle)
+}
+
+// DeleteClip moves a clip to the system trash
+func (a *App) DeleteClip(clipPath string) error {
+	return a.clipService.DeleteClip(clipPath)
+}
+
+// SetClipTags replaces the tags of a clip
+func (a *App) SetClipTags(clipPath string, tags []string) (services.ClipRecord, error) {
+	return a.clipService.SetClipTags(clipPath, tags)
+}
+
+// JumpToClipSource loads the video (and chat) a clip was cut from so the
+// player can seek to the clip's start time
+func (a *App) JumpToClipSource(clipPath string) (services.ClipSourceMoment, error) {
+	moment, err := a.clipService.GetClipSourceMoment(clipPath)
+	if err != nil {
+		return moment, err
+	}
+
+	videoURL, err := a.LoadVideoFromPath(moment.VideoPath)
+	if err != nil {
+		return moment, err
+	}
+	moment.VideoURL = videoURL
+
+	if moment.ChatPath != "" {
+		if err := a.videoService.LoadChatFile(moment.ChatPath); err != nil {
+			// The video is still useful without its chat
+			moment.ChatPath = ""
+		}
+	}
+
+	return moment, nil
+}
+
 // OpenClipsFolder opens the folder containing saved clips
 /*func (a *App) OpenClipsFolder() error {
 	clipsDir := filepath.Join(a.appDataDir, "clips")
@@ -329,6 +778,31 @@ func (a *App) SetClipStorageOption(option string, customDir string) error {
 	return nil
 }
 
+// GetClipNamingOptions returns the clip file naming template and folder settings
+func (a *App) GetClipNamingOptions() services.ClipNamingOptions {
+	return a.clipService.GetNamingOptions()
+}
+
+// SetClipNamingOptions sets the clip file naming template and folder settings
+func (a *App) SetClipNamingOptions(options services.ClipNamingOptions) error {
+	return a.clipService.SetNamingOptions(options)
+}
+
+// GetClipPresets returns the saved clip presets
+func (a *App) GetClipPresets() []services.ClipPreset {
+	return a.clipService.GetClipPresets()
+}
+
+// SaveClipPreset saves clip options, including polish filters, under a preset name
+func (a *App) SaveClipPreset(preset services.ClipPreset) error {
+	return a.clipService.SaveClipPreset(preset)
+}
+
+// DeleteClipPreset removes a saved clip preset
+func (a *App) DeleteClipPreset(name string) error {
+	return a.clipService.DeleteClipPreset(name)
+}
+
 // GetCurrentClipsDir returns the current directory where clips will be saved
 func (a *App) GetCurrentClipsDir() string {
 	return a.clipService.GetCurrentClipsDir(a.currentVideoPath)
@@ -338,3 +812,565 @@ func (a *App) GetCurrentClipsDir() string {
 func (a *App) OpenClipsFolder() error {
 	return a.clipService.OpenClipsFolder(a.currentVideoPath)
 }
+
+// GetBookmarks returns the bookmarks of the current video
+func (a *App) GetBookmarks() ([]services.Bookmark, error) {
+	if a.currentVideoPath == "" {
+		return []services.Bookmark{}, nil
+	}
+	return a.bookmarkService.GetBookmarks(a.historyVideoPath())
+}
+
+// AddBookmark adds a point or range bookmark to the current video
+func (a *App) AddBookmark(bookmark services.Bookmark) (services.Bookmark, error) {
+	if a.currentVideoPath == "" {
+		return services.Bookmark{}, fmt.Errorf("no video is currently loaded")
+	}
+	return a.bookmarkService.AddBookmark(a.historyVideoPath(), bookmark)
+}
+
+// UpdateBookmark saves changes to a bookmark of the current video
+func (a *App) UpdateBookmark(bookmark services.Bookmark) (services.Bookmark, error) {
+	if a.currentVideoPath == "" {
+		return services.Bookmark{}, fmt.Errorf("no video is currently loaded")
+	}
+	return a.bookmarkService.UpdateBookmark(a.historyVideoPath(), bookmark)
+}
+
+// DeleteBookmark removes a bookmark from the current video
+func (a *App) DeleteBookmark(id string) error {
+	if a.currentVideoPath == "" {
+		return fmt.Errorf("no video is currently loaded")
+	}
+	return a.bookmarkService.DeleteBookmark(a.historyVideoPath(), id)
+}
+
+// GetAllBookmarks returns the bookmarks of every video in the library
+func (a *App) GetAllBookmarks() ([]services.VideoBookmarks, error) {
+	return a.bookmarkService.GetAllBookmarks()
+}
+
+// GetBookmarkMarkers returns the current video's bookmarks as markers for ExportMarkers
+func (a *App) GetBookmarkMarkers() ([]services.EditorMarker, error) {
+	bookmarks, err := a.GetBookmarks()
+	if err != nil {
+		return nil, err
+	}
+	return services.BookmarkMarkers(bookmarks), nil
+}
+
+// ExportBookmarksMarkdown saves the current video's bookmarks as a Markdown
+// file chosen in a save dialog. Returns the saved path, or "" if cancelled.
+func (a *App) ExportBookmarksMarkdown() (string, error) {
+	if a.currentVideoPath == "" {
+		return "", fmt.Errorf("no video is currently loaded")
+	}
+
+	markdown, err := a.bookmarkService.ExportBookmarksMarkdown(a.historyVideoPath())
+	if err != nil {
+		return "", err
+	}
+
+	baseName := strings.TrimSuffix(filepath.Base(a.historyVideoPath()), filepath.Ext(a.historyVideoPath()))
+	outputPath, err := a.fileDialogService.SaveExportFile("Export Bookmarks", baseName+"_bookmarks.md")
+	if err != nil {
+		return "", fmt.Errorf("failed to open file dialog: %v", err)
+	}
+	if outputPath == "" {
+		return "", nil // User cancelled
+	}
+
+	if err := os.WriteFile(outputPath, []byte(markdown), 0644); err != nil {
+		return "", fmt.Errorf("failed to save bookmarks: %v", err)
+	}
+	return outputPath, nil
+}
+
+// UpdateWatchPosition saves the playback position of the current video so it can be resumed
+func (a *App) UpdateWatchPosition(position float64, duration float64) error {
+	if a.currentVideoPath == "" {
+		return nil
+	}
+	if a.currentRecording != nil {
+		duration = a.currentRecording.Duration
+	}
+	return a.watchHistory.UpdatePosition(a.historyVideoPath(), position, duration)
+}
+
+// FlushWatchHistory saves the latest playback position. The frontend calls
+// it when playback pauses; positions are otherwise saved every few seconds.
+func (a *App) FlushWatchHistory() error {
+	return a.watchHistory.Flush()
+}
+
+// GetWatchState returns the saved playback state of the current video,
+// including where to resume and the paired chat file
+func (a *App) GetWatchState() (services.WatchEntry, error) {
+	if a.currentVideoPath == "" {
+		return services.WatchEntry{}, fmt.Errorf("no video is currently loaded")
+	}
+	entry, _, err := a.watchHistory.GetWatchEntry(a.historyVideoPath())
+	return entry, err
+}
+
+// GetContinueWatching returns started but unfinished videos, most recent first
+func (a *App) GetContinueWatching(limit int) ([]services.WatchEntry, error) {
+	return a.watchHistory.GetContinueWatching(limit)
+}
+
+// GetWatchHistory returns every opened video, most recent first
+func (a *App) GetWatchHistory() ([]services.WatchEntry, error) {
+	return a.watchHistory.GetWatchHistory()
+}
+
+// MarkVideoWatched marks a video as watched, or clears the flag and its resume position
+func (a *App) MarkVideoWatched(videoPath string, watched bool) error {
+	return a.watchHistory.MarkWatched(videoPath, watched)
+}
+
+// GetPlaylists returns the saved playlists
+func (a *App) GetPlaylists() ([]services.Playlist, error) {
+	return a.playlistService.GetPlaylists()
+}
+
+// CreatePlaylist creates a playlist. With a rule it's a smart playlist, e.g.
+// unwatched streams of one model, oldest first.
+func (a *App) CreatePlaylist(name string, smart *services.SmartPlaylistRule) (services.Playlist, error) {
+	return a.playlistService.CreatePlaylist(name, smart)
+}
+
+// UpdatePlaylist saves a playlist's name, rule and item order
+func (a *App) UpdatePlaylist(playlist services.Playlist) (services.Playlist, error) {
+	return a.playlistService.UpdatePlaylist(playlist)
+}
+
+// DeletePlaylist removes a playlist
+func (a *App) DeletePlaylist(id string) error {
+	return a.playlistService.DeletePlaylist(id)
+}
+
+// AddToPlaylist appends videos to a playlist
+func (a *App) AddToPlaylist(id string, videoPaths []string) error {
+	return a.playlistService.AddToPlaylist(id, videoPaths, a.integrations.FanslyService.GetModelForPath)
+}
+
+// RemoveFromPlaylist removes a video, as listed by GetPlaylistItems, from a playlist
+func (a *App) RemoveFromPlaylist(id string, videoPath string) error {
+	return a.playlistService.RemoveFromPlaylist(id, videoPath)
+}
+
+// GetPlaylistItems returns the videos of a playlist, evaluating smart playlists
+func (a *App) GetPlaylistItems(id string) ([]services.PlaylistItem, error) {
+	_, items, err := a.playlistService.GetPlaylistItems(id, a.playlistCandidates())
+	return items, err
+}
+
+// PlayPlaylist starts playing a playlist from the given video, or from the
+// start when videoPath is empty. The video is passed rather than its position
+// since a smart playlist may have changed since it was shown.
+func (a *App) PlayPlaylist(id string, videoPath string) (services.NowPlaying, error) {
+	playlist, items, err := a.playlistService.GetPlaylistItems(id, a.playlistCandidates())
+	if err != nil {
+		return services.NowPlaying{}, err
+	}
+	startIndex := 0
+	if videoPath != "" {
+		startIndex = -1
+		for i, item := range items {
+			if item.VideoPath == videoPath {
+				startIndex = i
+				break
+			}
+		}
+	}
+	item, err := a.playlistService.StartPlaylist(playlist, items, startIndex)
+	if err != nil {
+		return services.NowPlaying{}, err
+	}
+	return a.loadQueueItem(item)
+}
+
+// QueuePlayNext adds a video to the ad-hoc queue played after the current video
+func (a *App) QueuePlayNext(videoPath string) {
+	a.playlistService.QueuePlayNext(videoPath, a.integrations.FanslyService.GetModelForPath(videoPath))
+}
+
+// GetPlayQueue returns the ad-hoc queue and the playing playlist
+func (a *App) GetPlayQueue() services.QueueState {
+	return a.playlistService.GetQueue()
+}
+
+// ClearPlayQueue empties the ad-hoc queue and stops the playing playlist
+func (a *App) ClearPlayQueue() {
+	a.playlistService.ClearQueue()
+}
+
+// PlayNext loads the next queued video with its chat and contact sheet. The
+// frontend calls it when a video ends; Finished is set when nothing is left.
+func (a *App) PlayNext() (services.NowPlaying, error) {
+	for {
+		item, ok := a.playlistService.Advance()
+		if !ok {
+			return services.NowPlaying{Finished: true}, nil
+		}
+
+		nowPlaying, err := a.loadQueueItem(item)
+		if err == nil {
+			return nowPlaying, nil
+		}
+		// Skip videos that were deleted since they were queued
+		fmt.Printf("Failed to play queued video %s: %v\n", item.VideoPath, err)
+	}
+}
+
+// loadQueueItem loads a video and the chat file and contact sheet paired with
+// it, the same way Fansly streams are loaded
+func (a *App) loadQueueItem(item services.PlaylistItem) (services.NowPlaying, error) {
+	stream, err := a.integrations.FanslyService.LoadStream(item.VideoPath)
+	if err != nil {
+		return services.NowPlaying{}, err
+	}
+	if !stream.Success {
+		return services.NowPlaying{}, fmt.Errorf("%s", stream.Error)
+	}
+
+	videoURL, err := a.LoadVideoFromPath(stream.VideoPath)
+	if err != nil {
+		return services.NowPlaying{}, err
+	}
+
+	nowPlaying := services.NowPlaying{
+		VideoPath:    stream.VideoPath,
+		VideoURL:     videoURL,
+		ContactSheet: stream.ContactSheet,
+	}
+	if stream.ChatPath != "" {
+		if _, err := a.LoadChatFromPath(stream.ChatPath); err != nil {
+			fmt.Printf("Failed to load chat for queued video: %v\n", err)
+			a.videoService.ClearChat()
+		} else {
+			nowPlaying.ChatPath = stream.ChatPath
+		}
+	} else {
+		// Don't leave the previous video's chat showing
+		a.videoService.ClearChat()
+	}
+
+	if entry, _, err := a.watchHistory.GetWatchEntry(stream.VideoPath); err == nil {
+		nowPlaying.ResumeFrom = entry.ResumeFrom
+	}
+	return nowPlaying, nil
+}
+
+// playlistCandidates returns the Fansly livestreams smart playlists pick from
+func (a *App) playlistCandidates() []services.PlaylistCandidate {
+	result, err := a.integrations.FanslyService.GetStreams()
+	if err != nil || result.Error != "" {
+		return []services.PlaylistCandidate{}
+	}
+
+	candidates := []services.PlaylistCandidate{}
+	for _, stream := range result.Streams {
+		if stream.FileType == "livestream" {
+			candidates = append(candidates, services.PlaylistCandidate{Path: stream.Path, Model: stream.Model})
+		}
+	}
+	return candidates
+}
+
+// knownVideoPaths returns the Fansly livestreams followed by the other videos
+// in the watch history that can still be found
+func (a *App) knownVideoPaths() []string {
+	paths := []string{}
+	seen := make(map[string]bool)
+	for _, candidate := range a.playlistCandidates() {
+		if !seen[candidate.Path] {
+			seen[candidate.Path] = true
+			paths = append(paths, candidate.Path)
+		}
+	}
+	entries, err := a.watchHistory.GetWatchHistory()
+	if err != nil {
+		fmt.Printf("Failed to read watch history: %v\n", err)
+	}
+	for _, entry := range entries {
+		if entry.VideoPath != "" && !seen[entry.VideoPath] {
+			seen[entry.VideoPath] = true
+			paths = append(paths, entry.VideoPath)
+		}
+	}
+	return paths
+}
+
+// GetRecordingGroups returns the Fansly livestreams that were split into
+// several files and can be played as one recording
+func (a *App) GetRecordingGroups() []services.RecordingGroup {
+	candidates := a.playlistCandidates()
+	paths := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		paths = append(paths, candidate.Path)
+	}
+	return a.recordingService.DetectRecordingGroups(paths)
+}
+
+// LoadRecording loads the multi-part recording videoPath belongs to. The
+// chat of every part is stitched onto one timeline, and the first part is
+// loaded; SeekRecording switches parts as playback moves along the timeline.
+func (a *App) LoadRecording(videoPath string) (services.RecordingGroup, error) {
+	group, found := a.recordingService.FindRecordingGroup(videoPath)
+	if !found {
+		return services.RecordingGroup{}, fmt.Errorf("video isn't part of a multi-part recording")
+	}
+
+	if _, err := a.loadVideo(group.Parts[0].Path); err != nil {
+		return services.RecordingGroup{}, err
+	}
+	a.videoService.SetTimeMap(services.TimeMap{})
+	a.videoService.SetChatMessages(services.LoadRecordingChat(group))
+	a.videoService.SetTranscript(services.LoadRecordingTranscript(group))
+	a.currentRecording = &group
+	return group, nil
+}
+
+// SeekRecording resolves a time on the loaded recording's timeline to a
+// part, switching to that part if a different one is playing. Chat,
+// transcript, bookmarks and watch history keep using the timeline time.
+func (a *App) SeekRecording(timelineTime float64) (services.RecordingPosition, error) {
+	if a.currentRecording == nil {
+		return services.RecordingPosition{}, fmt.Errorf("no multi-part recording is loaded")
+	}
+
+	index, localTime := a.currentRecording.Locate(timelineTime)
+	part := a.currentRecording.Parts[index]
+	position := services.RecordingPosition{
+		PartIndex: index,
+		PartPath:  part.Path,
+		LocalTime: localTime,
+	}
+	if part.Path != a.currentVideoPath {
+		if err := a.videoService.SetPlayingPart(part.Path); err != nil {
+			return services.RecordingPosition{}, err
+		}
+		a.currentVideoPath = part.Path
+		position.VideoURL = videoURL(part.Path)
+		position.Switched = true
+	}
+	return position, nil
+}
+
+// GetSeekPreviews returns the seek-bar thumbnails track of the current video.
+// Sprite sheets are generated in the background when missing; the
+// "seekpreviews:ready" event reports when they are done.
+func (a *App) GetSeekPreviews() (services.SeekPreviews, error) {
+	if a.currentVideoPath == "" {
+		return services.SeekPreviews{}, fmt.Errorf("no video is currently loaded")
+	}
+	return a.seekPreviews.GetSeekPreviews(a.currentVideoPath)
+}
+
+// GenerateLibrarySeekPreviews queues sprite sheet generation for every Fansly
+// livestream that doesn't have them yet and returns how many were queued
+func (a *App) GenerateLibrarySeekPreviews() int {
+	candidates := a.playlistCandidates()
+	paths := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		paths = append(paths, candidate.Path)
+	}
+	return a.seekPreviews.QueueSeekPreviews(paths)
+}
+
+// GetSkipSegments returns the dead air of the current video, such as a
+// "starting soon" screen or AFK breaks, for "skip intro" and "skip break"
+// buttons. The first call starts the analysis in the background; the
+// "deadair:ready" event delivers the segments when it's done.
+func (a *App) GetSkipSegments() (services.DeadAirAnalysis, error) {
+	if a.currentVideoPath == "" {
+		return services.DeadAirAnalysis{}, fmt.Errorf("no video is currently loaded")
+	}
+	// A recording's chat is on the whole recording's timeline, but the
+	// analysis covers the part playing now
+	chat := a.videoService.ChatMessages
+	if a.currentRecording != nil {
+		chat = nil
+	}
+	return a.deadAir.GetSkipSegments(a.currentVideoPath, chat)
+}
+
+// GetAudioAnalysis returns the waveform and loudness curve of the current
+// video for the seek bar, with a suggested playback gain. The first call
+// starts the analysis in the background; the "audioanalysis:ready" event
+// delivers the result when it's done.
+func (a *App) GetAudioAnalysis() (services.AudioAnalysis, error) {
+	if a.currentVideoPath == "" {
+		return services.AudioAnalysis{}, fmt.Errorf("no video is currently loaded")
+	}
+	return a.audioAnalysis.GetAudioAnalysis(a.currentVideoPath)
+}
+
+// ScanDiscontinuities scans the current video for timestamp gaps and resets
+// left by recording drops and corrects the loaded chat for the time missing
+// from the video
+func (a *App) ScanDiscontinuities() (services.TimingAnalysis, error) {
+	if a.currentVideoPath == "" {
+		return services.TimingAnalysis{}, fmt.Errorf("no video is currently loaded")
+	}
+	if a.currentRecording != nil {
+		return services.TimingAnalysis{}, fmt.Errorf("chat drift can't be corrected for a multi-part recording")
+	}
+	analysis, err := a.timing.ScanDiscontinuities(a.currentVideoPath, a.videoService.RecordedChat())
+	if err != nil {
+		return services.TimingAnalysis{}, err
+	}
+	a.videoService.SetTimeMap(analysis.TimeMap)
+	return analysis, nil
+}
+
+// GetTimingAnalysis returns the discontinuities and chat time map of the current video
+func (a *App) GetTimingAnalysis() (services.TimingAnalysis, error) {
+	if a.currentVideoPath == "" {
+		return services.TimingAnalysis{}, fmt.Errorf("no video is currently loaded")
+	}
+	return a.timing.GetTimingAnalysis(a.currentVideoPath)
+}
+
+// SyncChatMessage fixes drift the scan couldn't place: the chat message is
+// shown at videoTime, and later chat moves with it
+func (a *App) SyncChatMessage(messageID string, videoTime float64) (services.TimingAnalysis, error) {
+	if a.currentVideoPath == "" {
+		return services.TimingAnalysis{}, fmt.Errorf("no video is currently loaded")
+	}
+	if a.currentRecording != nil {
+		return services.TimingAnalysis{}, fmt.Errorf("chat drift can't be corrected for a multi-part recording")
+	}
+	chatTime, ok := a.videoService.RecordedChatTime(messageID)
+	if !ok {
+		return services.TimingAnalysis{}, fmt.Errorf("chat message not found")
+	}
+	analysis, err := a.timing.AddSyncAnchor(a.currentVideoPath, videoTime, chatTime)
+	if err != nil {
+		return services.TimingAnalysis{}, err
+	}
+	a.videoService.SetTimeMap(analysis.TimeMap)
+	return analysis, nil
+}
+
+// ClearChatSync removes the chat sync points of the current video
+func (a *App) ClearChatSync() (services.TimingAnalysis, error) {
+	if a.currentVideoPath == "" {
+		return services.TimingAnalysis{}, fmt.Errorf("no video is currently loaded")
+	}
+	analysis, err := a.timing.ClearSyncAnchors(a.currentVideoPath)
+	if err != nil {
+		return services.TimingAnalysis{}, err
+	}
+	if a.currentRecording == nil {
+		a.videoService.SetTimeMap(analysis.TimeMap)
+	}
+	return analysis, nil
+}
+
+// CheckVideoIntegrity checks a video for a missing index, truncation and
+// corrupt data, and proposes repairs. A deep check decodes every frame.
+func (a *App) CheckVideoIntegrity(videoPath string, deep bool) (services.IntegrityResult, error) {
+	return a.integrity.CheckIntegrity(videoPath, deep)
+}
+
+// CheckLibraryIntegrity checks the Fansly livestreams in the background.
+// Each result is reported through the "integrity:progress" event.
+func (a *App) CheckLibraryIntegrity(deep bool) {
+	candidates := a.playlistCandidates()
+	paths := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		paths = append(paths, candidate.Path)
+	}
+	go func() {
+		err := a.integrity.CheckLibraryIntegrity(paths, deep, func(progress services.IntegrityProgress) {
+			wailsRuntime.EventsEmit(a.ctx, "integrity:progress", progress)
+		})
+		if err != nil {
+			wailsRuntime.EventsEmit(a.ctx, "integrity:progress", services.IntegrityProgress{Error: err.Error(), Done: true})
+		}
+	}()
+}
+
+// GetIntegrityReport returns the last integrity check of every Fansly
+// livestream that has been checked since it last changed
+func (a *App) GetIntegrityReport() []services.IntegrityResult {
+	report := []services.IntegrityResult{}
+	for _, candidate := range a.playlistCandidates() {
+		if result, checked := a.integrity.CachedIntegrity(candidate.Path); checked {
+			report = append(report, result)
+		}
+	}
+	return report
+}
+
+// RepairVideo writes a repaired copy of a video next to it using one of the
+// repairs proposed by its integrity check. An MP4 that lost its index
+// ("reference_index") can only be rebuilt with untrunc installed and a
+// healthy recording made with the same settings as referencePath; when it's
+// empty the newest intact MP4 in the same folder is used.
+func (a *App) RepairVideo(videoPath string, action string, referencePath string) services.RepairResult {
+	return a.integrity.RepairVideo(videoPath, action, referencePath)
+}
+
+// FindNonFaststartVideos returns the Fansly livestreams saved as MP4s with
+// their index at the end, which the player has to fetch before starting
+func (a *App) FindNonFaststartVideos() []services.FaststartCandidate {
+	candidates := a.playlistCandidates()
+	paths := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		paths = append(paths, candidate.Path)
+	}
+	return a.faststart.FindNonFaststartVideos(paths)
+}
+
+// OptimizeFaststart moves the index of the given videos to the front in the
+// background, or of every video FindNonFaststartVideos returns when none are
+// given. The video being played is skipped, since it's being served. Progress
+// is reported through the "faststart:progress" event.
+func (a *App) OptimizeFaststart(videoPaths []string) {
+	if len(videoPaths) == 0 {
+		for _, candidate := range a.FindNonFaststartVideos() {
+			videoPaths = append(videoPaths, candidate.VideoPath)
+		}
+	}
+	paths := make([]string, 0, len(videoPaths))
+	for _, path := range videoPaths {
+		if path != a.currentVideoPath {
+			paths = append(paths, path)
+		}
+	}
+	go func() {
+		err := a.faststart.OptimizeLibrary(paths, func(progress services.FaststartProgress) {
+			wailsRuntime.EventsEmit(a.ctx, "faststart:progress", progress)
+		})
+		if err != nil {
+			wailsRuntime.EventsEmit(a.ctx, "faststart:progress", services.FaststartProgress{Error: err.Error(), Done: true})
+		}
+	}()
+}
+
+// GenerateContactSheet generates a contact sheet for a video and returns its path
+func (a *App) GenerateContactSheet(videoPath string, options services.ContactSheetOptions) (string, error) {
+	return a.contactSheets.GenerateContactSheet(videoPath, options)
+}
+
+// GenerateMissingContactSheets generates contact sheets in the background for
+// the given videos that don't have one. Without paths it covers the Fansly
+// livestreams and every other video that was opened in the player. Progress
+// is reported through the "contactsheet:progress" event.
+func (a *App) GenerateMissingContactSheets(videoPaths []string, options services.ContactSheetOptions) {
+	paths := videoPaths
+	if len(paths) == 0 {
+		paths = a.knownVideoPaths()
+	}
+	go func() {
+		err := a.contactSheets.GenerateMissingContactSheets(paths, options, func(progress services.ContactSheetProgress) {
+			wailsRuntime.EventsEmit(a.ctx, "contactsheet:progress", progress)
+		})
+		if err != nil {
+			wailsRuntime.EventsEmit(a.ctx, "contactsheet:progress", services.ContactSheetProgress{Error: err.Error(), Done: true})
+		}
+	}()
+}
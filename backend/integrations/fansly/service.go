@@ -6,10 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
-	"strconv"
 	"strings"
 	_ "time"
 
@@ -185,7 +183,7 @@ func (s *Service) GetStreams() (StreamsResult, error) {
 
 			if needsUpdate {
 				// Get video duration if possible
-				if duration, err := getVideoDuration(stream.Path); err == nil {
+				if duration, err := s.cacheService.GetVideoDuration(stream.Path); err == nil {
 					// Update cache
 					videoCache.Videos[stream.Path] = services.VideoMetadata{
 						Path:         stream.Path,
@@ -213,12 +211,8 @@ func (s *Service) GetStreams() (StreamsResult, error) {
 				result.ChatFiles = append(result.ChatFiles, chatPath)
 			}
 
-			// Check for contact sheet
-			contactSheetPath := strings.TrimSuffix(stream.Path, filepath.Ext(stream.Path)) + "_contact_sheet.jpg"
-			if _, err := os.Stat(contactSheetPath); err == nil {
-				// Update the stream in the result with the contact sheet path
-				result.Streams[i].ContactSheet = contactSheetPath
-			}
+			// Check for contact sheet, from fansly-scraper or generated by the player
+			result.Streams[i].ContactSheet = services.FindContactSheet(s.cacheService, stream.Path)
 
 			// Get duration from cache
 			if cachedVideo, exists := videoCache.Videos[stream.Path]; exists && cachedVideo.Duration > 0 {
@@ -249,16 +243,34 @@ func (s *Service) LoadStream(streamPath string) (StreamResult, error) {
 		result.ChatPath = chatPath
 	}
 
-	// Check for contact sheet
-	contactSheetPath := strings.TrimSuffix(streamPath, filepath.Ext(streamPath)) + "_contact_sheet.jpg"
-	if _, err := os.Stat(contactSheetPath); err == nil {
-		result.ContactSheet = contactSheetPath
-	}
+	// Check for contact sheet, from fansly-scraper or generated by the player
+	result.ContactSheet = services.FindContactSheet(s.cacheService, streamPath)
 
 	result.Success = true
 	return result, nil
 }
 
+// GetModelForPath returns the model a downloaded file belongs to, or an empty
+// string if the file is not in the Fansly database
+func (s *Service) GetModelForPath(path string) string {
+	config, err := s.GetConfig()
+	if err != nil || config.DbPath == "" || path == "" {
+		return ""
+	}
+
+	db, err := sql.Open("sqlite3", filepath.Join(config.DbPath, "downloads.db"))
+	if err != nil {
+		return ""
+	}
+	defer db.Close()
+
+	var model string
+	if err := db.QueryRow("SELECT model FROM files WHERE path = ? LIMIT 1", path).Scan(&model); err != nil {
+		return ""
+	}
+	return model
+}
+
 // Helper function to get the default Fansly config path
 func getDefaultConfigPath() string {
 	currentDirConfig := "config.toml"
@@ -282,35 +294,3 @@ func getDefaultConfigPath() string {
 	}
 	return filepath.Join(configDir, "fansly-scraper", "config.toml")
 }
-
-// Helper function to get video duration
-func getVideoDuration(videoPath string) (float64, error) {
-	// Check if ffprobe is available
-	_, err := exec.LookPath("ffprobe")
-	if err != nil {
-		return 0, errors.New("ffprobe not found")
-	}
-
-	// Run ffprobe to get duration
-	cmd := exec.Command(
-		"ffprobe",
-		"-v", "error",
-		"-select_streams", "v:0",
-		"-show_entries", "format=duration",
-		"-of", "default=noprint_wrappers=1:nokey=1",
-		videoPath,
-	)
-	output, err := cmd.Output()
-	if err != nil {
-		return 0, err
-	}
-
-	// Parse duration
-	durationStr := strings.TrimSpace(string(output))
-	duration, err := strconv.ParseFloat(durationStr, 64)
-	if err != nil {
-		return 0, err
-	}
-
-	return duration, nil
-}
@@ -0,0 +1,9 @@
+package models
+
+// TranscriptCue is one timed line of a transcript
+type TranscriptCue struct {
+	Start   float64 `json:"start"`
+	End     float64 `json:"end"`
+	Text    string  `json:"text"`
+	Speaker string  `json:"speaker,omitempty"`
+}
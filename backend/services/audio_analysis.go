@@ -0,0 +1,309 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AudioAnalysis is the seek-bar audio dataset of a video: a downsampled
+// waveform, a short-term loudness curve and a normalization suggestion
+type AudioAnalysis struct {
+	VideoPath          string    `json:"videoPath"`
+	Duration           float64   `json:"duration"`
+	WaveformInterval   float64   `json:"waveformInterval"` // Seconds covered by each waveform point
+	Waveform           []float64 `json:"waveform"`         // Peak level of each interval, 0-1
+	LoudnessInterval   float64   `json:"loudnessInterval"` // Seconds between loudness points
+	Loudness           []float64 `json:"loudness"`         // Short-term loudness in LUFS
+	IntegratedLoudness float64   `json:"integratedLoudness"`
+	LoudnessRange      float64   `json:"loudnessRange"` // LRA in LU
+	TruePeak           float64   `json:"truePeak"`      // dBTP
+	SuggestedGain      float64   `json:"suggestedGain"` // dB to apply during playback to reach the target loudness
+	AnalyzedAt         time.Time `json:"analyzedAt"`
+	Analyzed           bool      `json:"analyzed"`  // False until the video has been analysed
+	Analyzing          bool      `json:"analyzing"` // Set while the analysis runs in the background
+}
+
+// AudioAnalysisService measures the audio of videos for the seek-bar overlay.
+// Results are cached per video hash next to the dead-air analysis.
+type AudioAnalysisService struct {
+	analysisDir  string
+	cacheService *CacheService
+	mu           sync.Mutex
+	analyzing    map[string]bool
+	onReady      func(AudioAnalysis)
+}
+
+const (
+	// The audio is decoded at this rate for the waveform
+	waveformSampleRate = 4000
+	// Enough points for a seek bar on a wide screen
+	maxWaveformPoints = 4000
+	maxLoudnessPoints = 4000
+	// ebur128 measures every 100 ms
+	loudnessFrameInterval = 0.1
+	// Silence floor for the loudness curve
+	loudnessFloor = -70.0
+	// Playback target, close to what streaming platforms normalise to
+	targetPlaybackLoudness = -16.0
+	maxPlaybackPeak        = -1.0
+)
+
+var (
+	loudnessFrameLine = regexp.MustCompile(`t:\s*([\d.]+)\s.*?\sS:\s*(-?[\d.]+|-?inf)`)
+	integratedLine    = regexp.MustCompile(`I:\s+(-?[\d.]+|-?inf) LUFS`)
+	loudnessRangeLine = regexp.MustCompile(`LRA:\s+([\d.]+) LU`)
+	truePeakLine      = regexp.MustCompile(`Peak:\s+(-?[\d.]+|-?inf) dBFS`)
+)
+
+// NewAudioAnalysisService creates a new audio analysis service
+func NewAudioAnalysisService(appDataDir string, cacheService *CacheService) *AudioAnalysisService {
+	return &AudioAnalysisService{
+		analysisDir:  filepath.Join(appDataDir, analysisDirName),
+		cacheService: cacheService,
+		analyzing:    make(map[string]bool),
+	}
+}
+
+// SetReadyHandler sets a function called when a background analysis finishes
+func (s *AudioAnalysisService) SetReadyHandler(onReady func(AudioAnalysis)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onReady = onReady
+}
+
+// GetAudioAnalysis returns the audio analysis of a video. If the video hasn't
+// been analysed yet, the analysis is started in the background and the result
+// has Analyzing set; the ready handler receives it when it's done.
+func (s *AudioAnalysisService) GetAudioAnalysis(videoPath string) (AudioAnalysis, error) {
+	analysisPath, err := s.analysisPath(videoPath)
+	if err != nil {
+		return AudioAnalysis{}, err
+	}
+	var analysis AudioAnalysis
+	if err := readJSONFile(analysisPath, &analysis); err != nil {
+		return AudioAnalysis{}, fmt.Errorf("failed to read audio analysis: %v", err)
+	}
+	if !analysis.AnalyzedAt.IsZero() {
+		analysis.VideoPath = videoPath
+		analysis.Analyzed = true
+		return analysis, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := AudioAnalysis{VideoPath: videoPath, Waveform: []float64{}, Loudness: []float64{}, Analyzing: true}
+	if s.analyzing[videoPath] {
+		return pending, nil
+	}
+	s.analyzing[videoPath] = true
+
+	go func() {
+		result, err := s.AnalyzeAudio(videoPath)
+
+		s.mu.Lock()
+		delete(s.analyzing, videoPath)
+		onReady := s.onReady
+		s.mu.Unlock()
+
+		if err != nil {
+			fmt.Printf("Failed to analyse audio of %s: %v\n", videoPath, err)
+			return
+		}
+		if onReady != nil {
+			onReady(result)
+		}
+	}()
+	return pending, nil
+}
+
+// AnalyzeAudio decodes the first audio track of a video once, building the
+// waveform from the samples while ebur128 measures the loudness, and caches
+// the result
+func (s *AudioAnalysisService) AnalyzeAudio(videoPath string) (AudioAnalysis, error) {
+	analysisPath, err := s.analysisPath(videoPath)
+	if err != nil {
+		return AudioAnalysis{}, err
+	}
+	media, err := s.cacheService.GetMediaInfo(videoPath)
+	if err != nil {
+		return AudioAnalysis{}, fmt.Errorf("failed to read video: %v", err)
+	}
+	if !media.HasAudio() {
+		return AudioAnalysis{}, errors.New("video has no audio")
+	}
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return AudioAnalysis{}, errors.New("ffmpeg not found")
+	}
+
+	analysis := AudioAnalysis{
+		VideoPath:        videoPath,
+		Duration:         media.Duration,
+		WaveformInterval: math.Max(media.Duration/maxWaveformPoints, 0.5),
+		LoudnessInterval: math.Max(media.Duration/maxLoudnessPoints, 1),
+	}
+
+	graph := &filterGraph{}
+	outputs := graph.chainOutputs("[0:a:0]", "asplit=2", 2)
+	samples := graph.chain(outputs[0], fmt.Sprintf("aresample=%d,aformat=sample_fmts=s16:channel_layouts=mono", waveformSampleRate))
+	meter := graph.chain(outputs[1], "ebur128=framelog=info:peak=true")
+
+	cmd := exec.Command("ffmpeg",
+		"-hide_banner", "-nostats",
+		"-vn", "-i", videoPath,
+		"-filter_complex", graph.String(),
+		"-map", samples, "-f", "s16le", "pipe:1",
+		"-map", meter, "-f", "null", "-",
+	)
+	var log bytes.Buffer
+	cmd.Stderr = &log
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return AudioAnalysis{}, err
+	}
+	if err := cmd.Start(); err != nil {
+		return AudioAnalysis{}, err
+	}
+	waveform, readErr := readWaveform(stdout, int(analysis.WaveformInterval*waveformSampleRate))
+	if readErr != nil {
+		// Let ffmpeg finish writing so it can exit
+		io.Copy(io.Discard, stdout)
+	}
+	if err := cmd.Wait(); err != nil {
+		return AudioAnalysis{}, fmt.Errorf("FFmpeg error: %v\nOutput: %s", err, log.String())
+	}
+	if readErr != nil {
+		return AudioAnalysis{}, fmt.Errorf("failed to read audio samples: %v", readErr)
+	}
+	analysis.Waveform = waveform
+	parseLoudnessLog(log.String(), &analysis)
+	analysis.SuggestedGain = suggestedPlaybackGain(analysis.IntegratedLoudness, analysis.TruePeak)
+	analysis.AnalyzedAt = time.Now()
+
+	if err := os.MkdirAll(s.analysisDir, 0755); err != nil {
+		return AudioAnalysis{}, err
+	}
+	if err := writeJSONFile(analysisPath, analysis); err != nil {
+		return AudioAnalysis{}, fmt.Errorf("failed to save audio analysis: %v", err)
+	}
+	analysis.Analyzed = true
+	return analysis, nil
+}
+
+// analysisPath returns where the audio analysis of a video is cached
+func (s *AudioAnalysisService) analysisPath(videoPath string) (string, error) {
+	hash, err := s.cacheService.GetFileHash(videoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash video: %v", err)
+	}
+	return filepath.Join(s.analysisDir, hash+"_audio.json"), nil
+}
+
+// readWaveform reads 16-bit mono samples and returns the peak level of every
+// bucketSize samples
+func readWaveform(r io.Reader, bucketSize int) ([]float64, error) {
+	reader := bufio.NewReaderSize(r, 64*1024)
+	waveform := []float64{}
+	peak, count := 0.0, 0
+	var sample [2]byte
+	for {
+		if _, err := io.ReadFull(reader, sample[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			return waveform, err
+		}
+		level := math.Abs(float64(int16(binary.LittleEndian.Uint16(sample[:])))) / 32768
+		peak = math.Max(peak, level)
+		count++
+		if count == bucketSize {
+			waveform = append(waveform, roundLevel(peak))
+			peak, count = 0, 0
+		}
+	}
+	if count > 0 {
+		waveform = append(waveform, roundLevel(peak))
+	}
+	return waveform, nil
+}
+
+// parseLoudnessLog reads the short-term loudness ebur128 logs every 100 ms
+// into the loudness curve, and the integrated loudness, loudness range and
+// true peak from its summary
+func parseLoudnessLog(output string, analysis *AudioAnalysis) {
+	summary := ""
+	if index := strings.LastIndex(output, "Summary:"); index >= 0 {
+		output, summary = output[:index], output[index:]
+	}
+
+	// Each point is the average of the frames in its interval. The interval is
+	// a whole number of frames, so the curve stays in step with the video.
+	framesPerPoint := int(math.Round(analysis.LoudnessInterval / loudnessFrameInterval))
+	if framesPerPoint < 1 {
+		framesPerPoint = 1
+	}
+	analysis.LoudnessInterval = float64(framesPerPoint) * loudnessFrameInterval
+	analysis.Loudness = []float64{}
+	sum, count := 0.0, 0
+	for _, line := range strings.Split(output, "\n") {
+		match := loudnessFrameLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		sum += parseLoudness(match[2])
+		count++
+		if count == framesPerPoint {
+			analysis.Loudness = append(analysis.Loudness, roundLevel(sum/float64(count)))
+			sum, count = 0, 0
+		}
+	}
+	if count > 0 {
+		analysis.Loudness = append(analysis.Loudness, roundLevel(sum/float64(count)))
+	}
+
+	analysis.IntegratedLoudness = loudnessFloor
+	if match := integratedLine.FindStringSubmatch(summary); match != nil {
+		analysis.IntegratedLoudness = parseLoudness(match[1])
+	}
+	if match := loudnessRangeLine.FindStringSubmatch(summary); match != nil {
+		analysis.LoudnessRange = parseProbeFloat(match[1])
+	}
+	analysis.TruePeak = loudnessFloor
+	if match := truePeakLine.FindStringSubmatch(summary); match != nil {
+		analysis.TruePeak = parseLoudness(match[1])
+	}
+}
+
+// parseLoudness parses an ebur128 value, clamping -inf and very quiet values to the floor
+func parseLoudness(value string) float64 {
+	if strings.HasSuffix(value, "inf") {
+		return loudnessFloor
+	}
+	return math.Max(parseProbeFloat(value), loudnessFloor)
+}
+
+// suggestedPlaybackGain returns the gain that brings a video to the playback
+// target without pushing its true peak over the limit. Silent videos get none.
+func suggestedPlaybackGain(integratedLoudness float64, truePeak float64) float64 {
+	if integratedLoudness <= loudnessFloor {
+		return 0
+	}
+	gain := math.Min(targetPlaybackLoudness-integratedLoudness, maxPlaybackPeak-truePeak)
+	return math.Round(gain*10) / 10
+}
+
+// roundLevel keeps cached levels short
+func roundLevel(level float64) float64 {
+	return math.Round(level*1000) / 1000
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bookmark is a named point or range in a video with an optional note
+type Bookmark struct {
+	ID        string    `json:"id"`
+	Time      float64   `json:"time"`
+	EndTime   float64   `json:"endTime,omitempty"` // Set for ranges, 0 for a point
+	Name      string    `json:"name"`
+	Note      string    `json:"note,omitempty"`
+	Color     string    `json:"color,omitempty"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// VideoBookmarks are the bookmarks of one video
+type VideoBookmarks struct {
+	VideoHash string     `json:"videoHash"`
+	VideoPath string     `json:"videoPath"` // Last known location, empty if the video can't be found
+	Bookmarks []Bookmark `json:"bookmarks"`
+}
+
+// BookmarkService stores bookmarks keyed by the video's content hash, so they
+// stay attached to a video that is renamed or moved
+type BookmarkService struct {
+	appDataDir   string
+	cacheService *CacheService
+	mu           sync.Mutex
+}
+
+const bookmarksFileName = "bookmarks.json"
+
+// NewBookmarkService creates a new bookmark service
+func NewBookmarkService(appDataDir string, cacheService *CacheService) *BookmarkService {
+	return &BookmarkService{
+		appDataDir:   appDataDir,
+		cacheService: cacheService,
+	}
+}
+
+// GetBookmarks returns the bookmarks of a video sorted by time
+func (s *BookmarkService) GetBookmarks(videoPath string) ([]Bookmark, error) {
+	hash, err := s.cacheService.GetFileHash(videoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash video: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	store, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	if entry, exists := store[hash]; exists {
+		return entry.Bookmarks, nil
+	}
+	return []Bookmark{}, nil
+}
+
+// AddBookmark adds a bookmark to a video and returns it with its ID set
+func (s *BookmarkService) AddBookmark(videoPath string, bookmark Bookmark) (Bookmark, error) {
+	if err := validateBookmark(&bookmark); err != nil {
+		return Bookmark{}, err
+	}
+	id, err := newRecordID()
+	if err != nil {
+		return Bookmark{}, err
+	}
+	bookmark.ID = id
+	bookmark.CreatedAt = time.Now()
+	bookmark.UpdatedAt = bookmark.CreatedAt
+
+	err = s.updateVideo(videoPath, func(entry *VideoBookmarks) error {
+		entry.Bookmarks = append(entry.Bookmarks, bookmark)
+		return nil
+	})
+	return bookmark, err
+}
+
+// UpdateBookmark replaces the bookmark with the same ID
+func (s *BookmarkService) UpdateBookmark(videoPath string, bookmark Bookmark) (Bookmark, error) {
+	if err := validateBookmark(&bookmark); err != nil {
+		return Bookmark{}, err
+	}
+
+	err := s.updateVideo(videoPath, func(entry *VideoBookmarks) error {
+		for i := range entry.Bookmarks {
+			if entry.Bookmarks[i].ID == bookmark.ID {
+				bookmark.CreatedAt = entry.Bookmarks[i].CreatedAt
+				bookmark.UpdatedAt = time.Now()
+				entry.Bookmarks[i] = bookmark
+				return nil
+			}
+		}
+		return errors.New("bookmark not found")
+	})
+	return bookmark, err
+}
+
+// DeleteBookmark removes a bookmark from a video
+func (s *BookmarkService) DeleteBookmark(videoPath string, id string) error {
+	return s.updateVideo(videoPath, func(entry *VideoBookmarks) error {
+		for i := range entry.Bookmarks {
+			if entry.Bookmarks[i].ID == id {
+				entry.Bookmarks = append(entry.Bookmarks[:i], entry.Bookmarks[i+1:]...)
+				return nil
+			}
+		}
+		return errors.New("bookmark not found")
+	})
+}
+
+// GetAllBookmarks returns the bookmarks of every video in the library. Videos
+// that were moved are found again by their hash.
+func (s *BookmarkService) GetAllBookmarks() ([]VideoBookmarks, error) {
+	s.mu.Lock()
+	store, err := s.load()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]VideoBookmarks, 0, len(store))
+	for _, entry := range store {
+		if len(entry.Bookmarks) == 0 {
+			continue
+		}
+		if _, err := os.Stat(entry.VideoPath); err != nil {
+			entry.VideoPath = s.cacheService.FindPathByHash(entry.VideoHash)
+		}
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(filepath.Base(result[i].VideoPath)) < strings.ToLower(filepath.Base(result[j].VideoPath))
+	})
+	return result, nil
+}
+
+// ExportBookmarksMarkdown formats a video's bookmarks as a Markdown list.
+// Timestamps link to the video file with a #t= media fragment, so clicking
+// one opens the video at that moment.
+func (s *BookmarkService) ExportBookmarksMarkdown(videoPath string) (string, error) {
+	bookmarks, err := s.GetBookmarks(videoPath)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# %s\n\n", filepath.Base(videoPath)))
+	if len(bookmarks) == 0 {
+		sb.WriteString("No bookmarks.\n")
+		return sb.String(), nil
+	}
+
+	videoURL := fileURL(videoPath)
+	for _, bookmark := range bookmarks {
+		timestamp := fmt.Sprintf("[%s](<%s#t=%d>)", formatTimeText(bookmark.Time), videoURL, int(bookmark.Time))
+		if bookmark.EndTime > bookmark.Time {
+			timestamp += fmt.Sprintf("–[%s](<%s#t=%d>)", formatTimeText(bookmark.EndTime), videoURL, int(bookmark.EndTime))
+		}
+
+		sb.WriteString(fmt.Sprintf("- %s **%s**", timestamp, markdownEscape(bookmark.Name)))
+		if len(bookmark.Tags) > 0 {
+			sb.WriteString(" `" + strings.Join(bookmark.Tags, "` `") + "`")
+		}
+		sb.WriteString("\n")
+		if note := strings.TrimSpace(bookmark.Note); note != "" {
+			for _, line := range strings.Split(note, "\n") {
+				sb.WriteString("  > " + markdownEscape(strings.TrimRight(line, "\r")) + "\n")
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+// BookmarkMarkers converts bookmarks to markers for editor export
+func BookmarkMarkers(bookmarks []Bookmark) []EditorMarker {
+	markers := make([]EditorMarker, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		duration := 0.0
+		if bookmark.EndTime > bookmark.Time {
+			duration = bookmark.EndTime - bookmark.Time
+		}
+		markers = append(markers, EditorMarker{
+			Time:     bookmark.Time,
+			Duration: duration,
+			Name:     bookmark.Name,
+			Note:     bookmark.Note,
+			Color:    bookmark.Color,
+			Kind:     MarkerKindMarker,
+		})
+	}
+	return markers
+}
+
+// updateVideo applies a change to a video's bookmarks and saves the store
+func (s *BookmarkService) updateVideo(videoPath string, update func(entry *VideoBookmarks) error) error {
+	hash, err := s.cacheService.GetFileHash(videoPath)
+	if err != nil {
+		return fmt.Errorf("failed to hash video: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	store, err := s.load()
+	if err != nil {
+		return err
+	}
+	entry, exists := store[hash]
+	if !exists {
+		entry = VideoBookmarks{VideoHash: hash, Bookmarks: []Bookmark{}}
+	}
+	entry.VideoPath = videoPath
+
+	if err := update(&entry); err != nil {
+		return err
+	}
+	sort.SliceStable(entry.Bookmarks, func(i, j int) bool {
+		return entry.Bookmarks[i].Time < entry.Bookmarks[j].Time
+	})
+
+	if len(entry.Bookmarks) == 0 {
+		delete(store, hash)
+	} else {
+		store[hash] = entry
+	}
+	return s.save(store)
+}
+
+// load reads the bookmark store. Callers must hold mu.
+func (s *BookmarkService) load() (map[string]VideoBookmarks, error) {
+	store := make(map[string]VideoBookmarks)
+	// Unlike a cache, bookmarks can't be rebuilt, so don't overwrite a file we can't read
+	if err := readJSONFile(filepath.Join(s.appDataDir, bookmarksFileName), &store); err != nil {
+		return nil, fmt.Errorf("failed to read bookmarks: %v", err)
+	}
+	return store, nil
+}
+
+// save writes the bookmark store. Callers must hold mu.
+func (s *BookmarkService) save(store map[string]VideoBookmarks) error {
+	return writeJSONFile(filepath.Join(s.appDataDir, bookmarksFileName), store)
+}
+
+// validateBookmark checks the times and tidies the name and tags
+func validateBookmark(bookmark *Bookmark) error {
+	if bookmark.Time < 0 {
+		return errors.New("bookmark time can't be negative")
+	}
+	if bookmark.EndTime != 0 && bookmark.EndTime <= bookmark.Time {
+		return errors.New("bookmark end must be after its start")
+	}
+	bookmark.Name = strings.TrimSpace(bookmark.Name)
+	if bookmark.Name == "" {
+		bookmark.Name = formatTimeText(bookmark.Time)
+	}
+	bookmark.Tags = normalizeTags(bookmark.Tags)
+	return nil
+}
+
+// markdownEscape escapes characters that Markdown would treat as formatting
+func markdownEscape(text string) string {
+	replacer := strings.NewReplacer(
+		"\\", "\\\\",
+		"*", "\\*",
+		"_", "\\_",
+		"`", "\\`",
+		"[", "\\[",
+		"]", "\\]",
+		"<", "\\<",
+		"#", "\\#",
+	)
+	return replacer.Replace(text)
+}
@@ -2,18 +2,21 @@ package services
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
 // VideoMetadata represents cached information about a video file
 type VideoMetadata struct {
-	Path         string    `json:"path"`
-	Hash         string    `json:"hash"`
-	Duration     float64   `json:"duration"`
-	LastModified time.Time `json:"lastModified"`
-	FileSize     int64     `json:"fileSize"`
+	Path         string     `json:"path"`
+	Hash         string     `json:"hash"`
+	Duration     float64    `json:"duration"`
+	LastModified time.Time  `json:"lastModified"`
+	FileSize     int64      `json:"fileSize"`
+	Media        *MediaInfo `json:"media,omitempty"`
 }
 
 // VideoCache represents the cache of video metadata
@@ -25,6 +28,8 @@ type VideoCache struct {
 // CacheService handles caching of video metadata
 type CacheService struct {
 	cacheDir string
+	hashMu   sync.Mutex
+	mediaMu  sync.Mutex
 }
 
 // NewCacheService creates a new cache service
@@ -87,3 +92,199 @@ func (s *CacheService) SaveVideoCache(cacheType string, cache VideoCache) error
 
 	return os.WriteFile(cachePath, data, 0644)
 }
+
+// GetFileHash returns the content fingerprint of a file, reusing the cached
+// value while the file's size and modification time are unchanged
+func (s *CacheService) GetFileHash(path string) (string, error) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	s.hashMu.Lock()
+	defer s.hashMu.Unlock()
+
+	cache, err := s.LoadVideoCache("hash")
+	if err != nil {
+		return "", err
+	}
+
+	if cached, exists := cache.Videos[path]; exists &&
+		cached.Hash != "" &&
+		cached.LastModified.Equal(fileInfo.ModTime()) &&
+		cached.FileSize == fileInfo.Size() {
+		return cached.Hash, nil
+	}
+
+	hash, err := ComputeFileHash(path)
+	if err != nil {
+		return "", err
+	}
+
+	metadata := cache.Videos[path]
+	metadata.Path = path
+	metadata.Hash = hash
+	metadata.LastModified = fileInfo.ModTime()
+	metadata.FileSize = fileInfo.Size()
+	cache.Videos[path] = metadata
+
+	// The hash is still valid even if it could not be cached
+	if err := s.SaveVideoCache("hash", cache); err != nil {
+		fmt.Printf("Failed to save hash cache: %v\n", err)
+	}
+
+	return hash, nil
+}
+
+// GetFileHashes returns the fingerprints of several files from one read of
+// the hash cache. Files that can't be read are left out.
+func (s *CacheService) GetFileHashes(paths []string) map[string]string {
+	hashes := make(map[string]string, len(paths))
+
+	s.hashMu.Lock()
+	defer s.hashMu.Unlock()
+
+	cache, err := s.LoadVideoCache("hash")
+	if err != nil {
+		return hashes
+	}
+
+	changed := false
+	for _, path := range paths {
+		fileInfo, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if cached, exists := cache.Videos[path]; exists &&
+			cached.Hash != "" &&
+			cached.LastModified.Equal(fileInfo.ModTime()) &&
+			cached.FileSize == fileInfo.Size() {
+			hashes[path] = cached.Hash
+			continue
+		}
+
+		hash, err := ComputeFileHash(path)
+		if err != nil {
+			continue
+		}
+		metadata := cache.Videos[path]
+		metadata.Path = path
+		metadata.Hash = hash
+		metadata.LastModified = fileInfo.ModTime()
+		metadata.FileSize = fileInfo.Size()
+		cache.Videos[path] = metadata
+		hashes[path] = hash
+		changed = true
+	}
+
+	// The hashes are still valid even if they could not be cached
+	if changed {
+		if err := s.SaveVideoCache("hash", cache); err != nil {
+			fmt.Printf("Failed to save hash cache: %v\n", err)
+		}
+	}
+	return hashes
+}
+
+// KeepFileHash keeps a file's fingerprint after it was rewritten without
+// changing its media, such as a remux, so records keyed by the hash still
+// find it
+func (s *CacheService) KeepFileHash(path string, hash string) error {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	s.hashMu.Lock()
+	defer s.hashMu.Unlock()
+
+	cache, err := s.LoadVideoCache("hash")
+	if err != nil {
+		return err
+	}
+	metadata := cache.Videos[path]
+	metadata.Path = path
+	metadata.Hash = hash
+	metadata.LastModified = fileInfo.ModTime()
+	metadata.FileSize = fileInfo.Size()
+	cache.Videos[path] = metadata
+	return s.SaveVideoCache("hash", cache)
+}
+
+// FindPathByHash returns an existing file whose cached fingerprint matches
+// hash, or an empty string if none is known. This lets records keyed by hash
+// follow a video that was renamed or moved.
+func (s *CacheService) FindPathByHash(hash string) string {
+	s.hashMu.Lock()
+	cache, err := s.LoadVideoCache("hash")
+	s.hashMu.Unlock()
+	if err != nil {
+		return ""
+	}
+
+	for path, metadata := range cache.Videos {
+		if metadata.Hash != hash {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// GetMediaInfo returns the ffprobe details of a media file, reusing the
+// cached result while the file's size and modification time are unchanged
+func (s *CacheService) GetMediaInfo(path string) (MediaInfo, error) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return MediaInfo{}, err
+	}
+
+	s.mediaMu.Lock()
+	defer s.mediaMu.Unlock()
+
+	cache, err := s.LoadVideoCache("media")
+	if err != nil {
+		return MediaInfo{}, err
+	}
+
+	if cached, exists := cache.Videos[path]; exists &&
+		cached.Media != nil &&
+		cached.LastModified.Equal(fileInfo.ModTime()) &&
+		cached.FileSize == fileInfo.Size() {
+		rememberMediaProbe(path, *cached.Media, fileInfo)
+		return *cached.Media, nil
+	}
+
+	info, err := probeMedia(path)
+	if err != nil {
+		return MediaInfo{}, err
+	}
+
+	cache.Videos[path] = VideoMetadata{
+		Path:         path,
+		Duration:     info.Duration,
+		LastModified: fileInfo.ModTime(),
+		FileSize:     fileInfo.Size(),
+		Media:        &info,
+	}
+	// The probe result is still valid even if it could not be cached
+	if err := s.SaveVideoCache("media", cache); err != nil {
+		fmt.Printf("Failed to save media cache: %v\n", err)
+	}
+
+	return info, nil
+}
+
+// GetVideoDuration returns the duration of a video in seconds from its cached media info
+func (s *CacheService) GetVideoDuration(path string) (float64, error) {
+	info, err := s.GetMediaInfo(path)
+	if err != nil {
+		return 0, err
+	}
+	if info.Duration <= 0 {
+		return 0, fmt.Errorf("no duration found for %s", path)
+	}
+	return info.Duration, nil
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"FanslyArchivePlayer/backend/models"
+)
+
+// ChatOverlayOptions controls how chat messages are burned into a clip
+type ChatOverlayOptions struct {
+	Enabled           bool    `json:"enabled"`
+	Position          string  `json:"position"`          // top-left, top-right, bottom-left or bottom-right
+	Width             float64 `json:"width"`             // Fraction of the video width (0-1)
+	FontSize          int     `json:"fontSize"`          // Font size in video pixels, 0 scales with the video height
+	BackgroundOpacity float64 `json:"backgroundOpacity"` // 0 is fully transparent, 1 is fully opaque
+	HighlightTips     bool    `json:"highlightTips"`
+	MaxMessages       int     `json:"maxMessages"` // Number of messages visible at once
+}
+
+const (
+	defaultOverlayPosition = "bottom-right"
+	defaultOverlayWidth    = 0.3
+	defaultOverlayOpacity  = 0.5
+	defaultOverlayMessages = 8
+	overlayMargin          = 20
+	tipHighlightColor      = "#FFD700"
+)
+
+// withDefaults fills in unset overlay options
+func (o ChatOverlayOptions) withDefaults(videoHeight int) ChatOverlayOptions {
+	if o.Position == "" {
+		o.Position = defaultOverlayPosition
+	}
+	if o.Width <= 0 || o.Width > 1 {
+		o.Width = defaultOverlayWidth
+	}
+	if o.FontSize <= 0 {
+		o.FontSize = videoHeight / 36
+		if o.FontSize < 12 {
+			o.FontSize = 12
+		}
+	}
+	if o.BackgroundOpacity < 0 || o.BackgroundOpacity > 1 {
+		o.BackgroundOpacity = defaultOverlayOpacity
+	}
+	if o.MaxMessages <= 0 {
+		o.MaxMessages = defaultOverlayMessages
+	}
+	return o
+}
+
+// selectClipMessages returns copies of the messages inside the clip range
+// with their times rebased so the clip starts at zero
+func selectClipMessages(messages []models.ChatMessage, startTime float64, duration float64) []models.ChatMessage {
+	clipMessages := []models.ChatMessage{}
+	endTime := startTime + duration
+
+	for _, msg := range messages {
+		if msg.TimeInSeconds < startTime || msg.TimeInSeconds > endTime {
+			continue
+		}
+		msg.TimeInSeconds -= startTime
+		msg.TimeText = formatTimeText(msg.TimeInSeconds)
+		clipMessages = append(clipMessages, msg)
+	}
+
+	return clipMessages
+}
+
+// buildChatOverlayASS renders rebased chat messages as an ASS subtitle script.
+// Every message starts a new event showing it together with the messages
+// before it, so the box scrolls like the chat panel in the player.
+func buildChatOverlayASS(messages []models.ChatMessage, duration float64, videoWidth int, videoHeight int, options ChatOverlayOptions) string {
+	options = options.withDefaults(videoHeight)
+
+	// Work out the box alignment and horizontal margins
+	boxWidth := int(float64(videoWidth) * options.Width)
+	alignment := 3 // bottom-right
+	marginL := videoWidth - boxWidth - overlayMargin
+	marginR := overlayMargin
+	switch options.Position {
+	case "top-left":
+		alignment = 7
+		marginL, marginR = overlayMargin, videoWidth-boxWidth-overlayMargin
+	case "top-right":
+		alignment = 9
+	case "bottom-left":
+		alignment = 1
+		marginL, marginR = overlayMargin, videoWidth-boxWidth-overlayMargin
+	}
+	if marginL < 0 {
+		marginL = 0
+	}
+	if marginR < 0 {
+		marginR = 0
+	}
+
+	backAlpha := int(math.Round((1 - options.BackgroundOpacity) * 255))
+	boxColor := assColor("#000000", backAlpha)
+
+	var sb strings.Builder
+	sb.WriteString("[Script Info]\n")
+	sb.WriteString("ScriptType: v4.00+\n")
+	sb.WriteString(fmt.Sprintf("PlayResX: %d\n", videoWidth))
+	sb.WriteString(fmt.Sprintf("PlayResY: %d\n", videoHeight))
+	sb.WriteString("WrapStyle: 0\n")
+	sb.WriteString("ScaledBorderAndShadow: yes\n\n")
+
+	sb.WriteString("[V4+ Styles]\n")
+	sb.WriteString("Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding\n")
+	// BorderStyle 3 draws an opaque box behind each line using the outline colour
+	sb.WriteString(fmt.Sprintf("Style: Chat,Arial,%d,&H00FFFFFF,&H00FFFFFF,%s,%s,0,0,0,0,100,100,0,0,3,%d,0,%d,%d,%d,%d,1\n\n",
+		options.FontSize, boxColor, boxColor, options.FontSize/4, alignment, marginL, marginR, overlayMargin))
+
+	sb.WriteString("[Events]\n")
+	sb.WriteString("Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n")
+
+	for i, msg := range messages {
+		start := msg.TimeInSeconds
+		end := duration
+		if i+1 < len(messages) {
+			end = messages[i+1].TimeInSeconds
+		}
+		if end <= start {
+			continue
+		}
+
+		first := i - options.MaxMessages + 1
+		if first < 0 {
+			first = 0
+		}
+		lines := make([]string, 0, i-first+1)
+		for _, visible := range messages[first : i+1] {
+			lines = append(lines, formatOverlayLine(visible, options.HighlightTips))
+		}
+
+		sb.WriteString(fmt.Sprintf("Dialogue: 0,%s,%s,Chat,,0,0,0,,%s\n",
+			formatASSTime(start), formatASSTime(end), strings.Join(lines, "\\N")))
+	}
+
+	return sb.String()
+}
+
+// formatOverlayLine formats a single chat message with ASS override tags
+func formatOverlayLine(msg models.ChatMessage, highlightTips bool) string {
+	nameColor := "#FFFFFF"
+	if msg.Author.TierInfo != nil && msg.Author.TierInfo.TierColor != "" {
+		nameColor = msg.Author.TierInfo.TierColor
+	}
+	textColor := "#FFFFFF"
+
+	prefix := ""
+	if msg.TipAmount > 0 && highlightTips {
+		nameColor = tipHighlightColor
+		textColor = tipHighlightColor
+		// Tip amounts are stored in thousandths of a dollar
+		prefix = fmt.Sprintf("[$%.2f] ", float64(msg.TipAmount)/1000)
+	}
+
+	return fmt.Sprintf("{\\b1\\c%s}%s%s{\\b0\\c%s}: %s",
+		assColor(nameColor, 0), escapeASSText(prefix), escapeASSText(msg.Author.Name),
+		assColor(textColor, 0), escapeASSText(msg.Message))
+}
+
+// escapeASSText keeps user text from being interpreted as ASS override tags
+func escapeASSText(text string) string {
+	replacer := strings.NewReplacer(
+		"{", "(",
+		"}", ")",
+		"\\", "\\\u200b", // a zero-width space stops "\N" and friends
+		"\r\n", " ",
+		"\n", " ",
+	)
+	return replacer.Replace(text)
+}
+
+// assColor converts a #RRGGBB colour and alpha (0 opaque, 255 transparent) to &HAABBGGRR
+func assColor(hex string, alpha int) string {
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) != 6 {
+		hex = "FFFFFF"
+	}
+	return fmt.Sprintf("&H%02X%s%s%s", alpha, strings.ToUpper(hex[4:6]), strings.ToUpper(hex[2:4]), strings.ToUpper(hex[0:2]))
+}
+
+// formatASSTime formats seconds as H:MM:SS.cc
+func formatASSTime(seconds float64) string {
+	if seconds < 0 {
+		seconds = 0
+	}
+	centiseconds := int(math.Round(seconds * 100))
+	hours := centiseconds / 360000
+	minutes := (centiseconds % 360000) / 6000
+	secs := (centiseconds % 6000) / 100
+	return fmt.Sprintf("%d:%02d:%02d.%02d", hours, minutes, secs, centiseconds%100)
+}
+
+// formatTimeText formats seconds the same way chat files do (H:MM:SS or M:SS)
+func formatTimeText(seconds float64) string {
+	total := int(seconds)
+	hours := total / 3600
+	minutes := (total % 3600) / 60
+	secs := total % 60
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, secs)
+	}
+	return fmt.Sprintf("%d:%02d", minutes, secs)
+}
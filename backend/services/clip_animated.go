@@ -0,0 +1,157 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// AnimatedFormat is the image format used for animated clip exports
+type AnimatedFormat string
+
+const (
+	// AnimatedGIF exports a GIF using a generated palette
+	AnimatedGIF AnimatedFormat = "gif"
+	// AnimatedWebP exports an animated WebP
+	AnimatedWebP AnimatedFormat = "webp"
+	// AnimatedAPNG exports an animated PNG
+	AnimatedAPNG AnimatedFormat = "apng"
+)
+
+// AnimatedClipOptions controls how an animated clip is exported
+type AnimatedClipOptions struct {
+	Format    AnimatedFormat `json:"format"`
+	Width     int            `json:"width"`     // Output width in pixels, the height keeps the aspect ratio
+	FPS       int            `json:"fps"`       // Output frame rate
+	Crossfade float64        `json:"crossfade"` // Seconds of the end blended into the start for a seamless loop, 0 disables
+}
+
+const (
+	// maxAnimatedClipDuration keeps animated exports to short loops
+	maxAnimatedClipDuration = 30.0
+	defaultAnimatedWidth    = 480
+	defaultAnimatedFPS      = 15
+)
+
+// CreateAnimatedClip exports a short range of the source video as an animated GIF, WebP or APNG
+func (s *ClipService) CreateAnimatedClip(source ClipSource, startTime float64, duration float64, title string, options AnimatedClipOptions) ClipResult {
+	// Validate inputs
+	if source.Path == "" {
+		return ClipResult{Success: false, ErrorMessage: "No source video provided"}
+	}
+	if duration <= 0 {
+		return ClipResult{Success: false, ErrorMessage: "Duration must be a positive number"}
+	}
+	if duration > maxAnimatedClipDuration {
+		return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Animated clips are limited to %.0f seconds", maxAnimatedClipDuration)}
+	}
+
+	if options.Format == "" {
+		options.Format = AnimatedGIF
+	}
+	if options.Width <= 0 {
+		options.Width = defaultAnimatedWidth
+	}
+	if options.FPS <= 0 {
+		options.FPS = defaultAnimatedFPS
+	}
+
+	var ext string
+	var codecArgs []string
+	switch options.Format {
+	case AnimatedGIF:
+		ext = ".gif"
+		codecArgs = []string{"-loop", "0"}
+	case AnimatedWebP:
+		ext = ".webp"
+		codecArgs = []string{"-c:v", "libwebp", "-lossless", "0", "-q:v", "75", "-loop", "0"}
+	case AnimatedAPNG:
+		ext = ".apng"
+		codecArgs = []string{"-plays", "0", "-f", "apng"}
+	default:
+		return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Unsupported animated format: %s", options.Format)}
+	}
+
+	// Like a frame burst, a loop is read from one file, so it stops at the
+	// end of a recording part
+	input, errorMessage := frameInput(source, startTime, duration)
+	if errorMessage != "" {
+		return ClipResult{Success: false, ErrorMessage: errorMessage}
+	}
+	duration = input.duration
+
+	// The crossfade needs at least as much untouched footage as it blends
+	if options.Crossfade < 0 || options.Crossfade*2 >= duration {
+		options.Crossfade = 0
+	}
+
+	outputPath, err := s.clipOutputPath(clipName{
+		model:     source.Model,
+		videoPath: input.path,
+		startTime: startTime,
+		duration:  duration,
+		title:     title,
+		preset:    string(options.Format),
+	}, ext)
+	if err != nil {
+		return ClipResult{Success: false, ErrorMessage: err.Error()}
+	}
+
+	args := []string{
+		"-ss", formatFFmpegTime(input.start),
+		"-t", formatFFmpegTime(duration),
+		"-i", input.path,
+		"-filter_complex", buildAnimatedFilter(options, duration),
+		"-map", "[out]",
+		"-an",
+	}
+	args = append(args, codecArgs...)
+	args = append(args, "-y", outputPath)
+
+	cmd := exec.Command("ffmpeg", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		os.Remove(outputPath)
+		return ClipResult{
+			Success:      false,
+			ErrorMessage: fmt.Sprintf("FFmpeg error: %v\nOutput: %s", err, string(output)),
+		}
+	}
+
+	s.recordClip(outputPath, source, startTime, duration, title, string(options.Format))
+
+	return ClipResult{
+		Success:  true,
+		FilePath: outputPath,
+	}
+}
+
+// buildAnimatedFilter builds the filter graph for an animated export. The
+// graph always ends in the [out] label.
+func buildAnimatedFilter(options AnimatedClipOptions, duration float64) string {
+	graph := fmt.Sprintf("[0:v]fps=%d,scale=%d:-2:flags=lanczos[scaled];", options.FPS, options.Width)
+
+	if options.Crossfade > 0 {
+		// Fade the first few seconds in over the last few seconds so the last
+		// frame leads straight back into the first one
+		fade := options.Crossfade
+		graph += "[scaled]split[body][head];"
+		graph += fmt.Sprintf("[head]trim=duration=%.3f,format=yuva420p,fade=t=in:st=0:d=%.3f:alpha=1,setpts=PTS-STARTPTS+(%.3f/TB)[fadein];",
+			fade, fade, duration-2*fade)
+		graph += fmt.Sprintf("[body]trim=start=%.3f,setpts=PTS-STARTPTS[main];", fade)
+		graph += "[main][fadein]overlay[looped];"
+	} else {
+		graph += "[scaled]null[looped];"
+	}
+
+	if options.Format == AnimatedGIF {
+		// Generate a palette from the clip itself for much better GIF colours
+		graph += "[looped]split[palin][gifin];"
+		graph += "[palin]palettegen=stats_mode=diff[palette];"
+		graph += "[gifin][palette]paletteuse=dither=bayer:bayer_scale=5:diff_mode=rectangle[out]"
+	} else {
+		graph += "[looped]null[out]"
+	}
+
+	return graph
+}
@@ -0,0 +1,353 @@
+package services
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BatchClipRow is one clip to create from a marker file or chapter list
+type BatchClipRow struct {
+	Row       int     `json:"row"` // Row in the file, EDL event or chapter number, for showing errors
+	StartTime float64 `json:"startTime"`
+	EndTime   float64 `json:"endTime"`
+	Title     string  `json:"title"`
+	Problem   string  `json:"problem,omitempty"` // Set when the row can't be clipped
+}
+
+// BatchClipPreview is the dry-run result for a marker file
+type BatchClipPreview struct {
+	VideoPath     string         `json:"videoPath"`
+	VideoDuration float64        `json:"videoDuration"`
+	Rows          []BatchClipRow `json:"rows"`
+	ValidRows     int            `json:"validRows"`
+}
+
+// BatchClipProgress reports how far a batch has got
+type BatchClipProgress struct {
+	Row       int    `json:"row"` // 1-based position in the batch
+	TotalRows int    `json:"totalRows"`
+	Message   string `json:"message"`
+}
+
+const (
+	defaultEDLFrameRate  = 30.0
+	minBatchClipDuration = 1.0
+)
+
+var (
+	// A CMX3600 event: number, reel, track, transition, source in/out, record in/out
+	edlEventLine = regexp.MustCompile(`^(\d+)\s+\S+\s+\S+\s+\S+(?:\s+\d+)?\s+(\d{2}[:;]\d{2}[:;]\d{2}[:;]\d{2})\s+(\d{2}[:;]\d{2}[:;]\d{2}[:;]\d{2})\s+\S+\s+\S+`)
+	// Marker names written by Resolve, e.g. "|C:ResolveColorBlue |M:Great moment |D:1"
+	edlMarkerName = regexp.MustCompile(`\|M:(.*?)(?:\s+\|[A-Z]:|$)`)
+)
+
+// ParseMarkerFile reads clip ranges from a CSV/TSV or CMX3600 EDL file. EDL
+// timecodes are converted to seconds at the frame rate of videoPath.
+func ParseMarkerFile(path string, videoPath string) ([]BatchClipRow, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	content := strings.TrimPrefix(string(data), "\ufeff")
+
+	if strings.EqualFold(filepath.Ext(path), ".edl") {
+		frameRate := defaultEDLFrameRate
+		if media, err := probeMedia(videoPath); err == nil {
+			if rate, err := media.FrameRate(); err == nil {
+				frameRate = rate
+			}
+		}
+		return parseEDL(content, frameRate)
+	}
+	return parseMarkerTable(content, strings.EqualFold(filepath.Ext(path), ".tsv"))
+}
+
+// parseMarkerTable reads CSV or TSV rows of start, end or duration, and title.
+// With a header row the columns are matched by name (start, end, duration,
+// title). Without one the columns are start, end, title, and a second value
+// starting with "+" is a duration. Times may be seconds, MM:SS or HH:MM:SS.
+func parseMarkerTable(content string, tabSeparated bool) ([]BatchClipRow, error) {
+	firstLine, _, _ := strings.Cut(content, "\n")
+	reader := csv.NewReader(strings.NewReader(content))
+	switch {
+	case tabSeparated || strings.Contains(firstLine, "\t"):
+		reader.Comma = '\t'
+	case strings.Contains(firstLine, ";") && !strings.Contains(firstLine, ","):
+		// Spreadsheets in some locales export with semicolons
+		reader.Comma = ';'
+	}
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read marker file: %v", err)
+	}
+
+	startColumn, endColumn, durationColumn, titleColumn := 0, 1, -1, 2
+	var rows []BatchClipRow
+	for i, record := range records {
+		if len(record) == 0 || (len(record) == 1 && strings.TrimSpace(record[0]) == "") {
+			continue
+		}
+		if i == 0 {
+			if _, err := parseMarkerTime(record[0]); err != nil {
+				startColumn, endColumn, durationColumn, titleColumn = markerColumns(record)
+				if startColumn < 0 {
+					return nil, errors.New("marker file has no start column")
+				}
+				continue
+			}
+		}
+		row := BatchClipRow{Row: i + 1, Title: markerField(record, titleColumn)}
+		start, err := parseMarkerTime(markerField(record, startColumn))
+		if err != nil {
+			row.Problem = "Invalid start time"
+			rows = append(rows, row)
+			continue
+		}
+		row.StartTime = start
+
+		if value := markerField(record, durationColumn); value != "" {
+			duration, err := parseMarkerTime(value)
+			if err != nil {
+				row.Problem = "Invalid duration"
+			}
+			row.EndTime = start + duration
+		} else if value := markerField(record, endColumn); strings.HasPrefix(value, "+") {
+			duration, err := parseMarkerTime(strings.TrimPrefix(value, "+"))
+			if err != nil {
+				row.Problem = "Invalid duration"
+			}
+			row.EndTime = start + duration
+		} else {
+			end, err := parseMarkerTime(value)
+			if err != nil {
+				row.Problem = "Invalid end time"
+			}
+			row.EndTime = end
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("marker file has no rows")
+	}
+	return rows, nil
+}
+
+// markerColumns finds the start, end, duration and title columns in a header
+// row. Missing columns are -1.
+func markerColumns(header []string) (int, int, int, int) {
+	start, end, duration, title := -1, -1, -1, -1
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "start", "start time", "in", "from":
+			start = i
+		case "end", "end time", "out", "to":
+			end = i
+		case "duration", "length":
+			duration = i
+		case "title", "name", "label":
+			title = i
+		case "description", "note", "notes":
+			// Only used when there's no name column
+			if title < 0 {
+				title = i
+			}
+		}
+	}
+	return start, end, duration, title
+}
+
+// markerField returns the trimmed field at index, or "" if there is none
+func markerField(record []string, index int) string {
+	if index < 0 || index >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[index])
+}
+
+// parseMarkerTime parses seconds, MM:SS or HH:MM:SS with optional fractions
+func parseMarkerTime(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, errors.New("empty time")
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid time: %s", value)
+	}
+	seconds := 0.0
+	for _, part := range parts {
+		number, err := strconv.ParseFloat(strings.Replace(part, ",", ".", 1), 64)
+		if err != nil || number < 0 {
+			return 0, fmt.Errorf("invalid time: %s", value)
+		}
+		seconds = seconds*60 + number
+	}
+	return seconds, nil
+}
+
+// parseEDL reads the source in/out points of each event in a CMX3600 EDL.
+// Titles come from Resolve marker names or clip name comments.
+func parseEDL(content string, frameRate float64) ([]BatchClipRow, error) {
+	if frameRate <= 0 {
+		frameRate = defaultEDLFrameRate
+	}
+
+	var rows []BatchClipRow
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if match := edlEventLine.FindStringSubmatch(line); match != nil {
+			number, _ := strconv.Atoi(match[1])
+			rows = append(rows, BatchClipRow{
+				Row:       number,
+				StartTime: parseTimecode(match[2], frameRate),
+				EndTime:   parseTimecode(match[3], frameRate),
+			})
+			continue
+		}
+		if len(rows) == 0 {
+			continue
+		}
+
+		// Comment lines describe the event above them
+		row := &rows[len(rows)-1]
+		if match := edlMarkerName.FindStringSubmatch(line); match != nil {
+			row.Title = strings.TrimSpace(match[1])
+		} else if name, ok := strings.CutPrefix(line, "* FROM CLIP NAME:"); ok && row.Title == "" {
+			row.Title = strings.TrimSpace(name)
+		} else if comment, ok := strings.CutPrefix(line, "* COMMENT:"); ok {
+			row.Title = strings.TrimSpace(comment)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("EDL has no events")
+	}
+	return rows, nil
+}
+
+// parseTimecode converts HH:MM:SS:FF (or drop-frame HH:MM:SS;FF) to seconds.
+// Timecode counts frames at the whole number rate, e.g. 30 for 29.97 fps, so
+// the frame count is divided by the real rate.
+func parseTimecode(timecode string, frameRate float64) float64 {
+	fields := strings.FieldsFunc(timecode, func(r rune) bool { return r == ':' || r == ';' })
+	values := make([]int, len(fields))
+	for i, field := range fields {
+		values[i], _ = strconv.Atoi(field)
+	}
+	base := int(math.Round(frameRate))
+	frames := (values[0]*3600+values[1]*60+values[2])*base + values[3]
+
+	// Drop-frame timecode skips the first frame numbers of every minute
+	// except each tenth, 2 at 29.97 fps and 4 at 59.94 fps
+	if strings.Contains(timecode, ";") {
+		dropped := int(math.Round(frameRate * 0.066666))
+		minutes := values[0]*60 + values[1]
+		frames -= dropped * (minutes - minutes/10)
+	}
+	return float64(frames) / frameRate
+}
+
+// VideoChapters returns the chapters embedded in a video as clip rows
+func VideoChapters(videoPath string) ([]BatchClipRow, error) {
+	media, err := probeMedia(videoPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(media.Chapters) == 0 {
+		return nil, errors.New("video has no chapters")
+	}
+
+	rows := make([]BatchClipRow, 0, len(media.Chapters))
+	for i, chapter := range media.Chapters {
+		rows = append(rows, BatchClipRow{
+			Row:       i + 1,
+			StartTime: chapter.Start,
+			EndTime:   chapter.End,
+			Title:     chapter.Title,
+		})
+	}
+	return rows, nil
+}
+
+// PreviewBatchClips checks the rows against the video without creating any
+// clips, flagging ranges that are empty or outside the video's duration.
+// duration is the length of the timeline the rows are on, such as a
+// multi-part recording's; 0 reads it from the video.
+func PreviewBatchClips(videoPath string, duration float64, rows []BatchClipRow) (BatchClipPreview, error) {
+	if duration <= 0 {
+		media, err := probeMedia(videoPath)
+		if err != nil {
+			return BatchClipPreview{}, fmt.Errorf("failed to read video duration: %v", err)
+		}
+		duration = media.Duration
+	}
+
+	preview := BatchClipPreview{VideoPath: videoPath, VideoDuration: duration, Rows: rows}
+	for i := range preview.Rows {
+		row := &preview.Rows[i]
+		switch {
+		case row.Problem != "":
+		case row.EndTime <= row.StartTime:
+			row.Problem = "End is not after start"
+		case row.EndTime-row.StartTime < minBatchClipDuration:
+			// Usually a point marker, e.g. a one frame Resolve marker
+			row.Problem = "Shorter than one second"
+		case row.StartTime >= duration:
+			row.Problem = fmt.Sprintf("Starts after the video ends (%s)", formatTimeText(duration))
+		case row.EndTime > duration:
+			row.Problem = fmt.Sprintf("Ends after the video ends (%s)", formatTimeText(duration))
+		}
+		if row.Problem == "" {
+			preview.ValidRows++
+		}
+	}
+	return preview, nil
+}
+
+// CreateBatchClips creates one clip per row, returning a result for each row
+// in order. Rows with a problem are skipped. progress may be nil.
+func (s *ClipService) CreateBatchClips(source ClipSource, rows []BatchClipRow, options ClipOptions, progress func(BatchClipProgress)) []ClipResult {
+	if progress == nil {
+		progress = func(BatchClipProgress) {}
+	}
+
+	results := make([]ClipResult, 0, len(rows))
+	for i, row := range rows {
+		progress(BatchClipProgress{
+			Row:       i + 1,
+			TotalRows: len(rows),
+			Message:   fmt.Sprintf("Creating clip %d of %d", i+1, len(rows)),
+		})
+
+		if row.Problem != "" {
+			results = append(results, ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Row %d: %s", row.Row, row.Problem)})
+			continue
+		}
+		result := s.CreateClipWithOptions(source, row.StartTime, row.EndTime-row.StartTime, row.Title, options)
+		if !result.Success {
+			result.ErrorMessage = fmt.Sprintf("Row %d: %s", row.Row, result.ErrorMessage)
+		}
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CompilationSegment is one source range of a highlight reel
+type CompilationSegment struct {
+	SourcePath string  `json:"sourcePath"`
+	StartTime  float64 `json:"startTime"`
+	EndTime    float64 `json:"endTime"`
+	Title      string  `json:"title,omitempty"` // Shown on the title card before the segment
+}
+
+// CompilationOptions controls how segments are joined into a highlight reel
+type CompilationOptions struct {
+	Width             int     `json:"width"`             // Output width, segments are scaled and padded to fit
+	Height            int     `json:"height"`            // Output height
+	FPS               int     `json:"fps"`               // Output frame rate
+	Crossfade         float64 `json:"crossfade"`         // Seconds of crossfade between parts, 0 for hard cuts
+	TitleCards        bool    `json:"titleCards"`        // Insert a card with the segment title before each titled segment
+	TitleCardDuration float64 `json:"titleCardDuration"` // Seconds each title card is shown
+	TrimDeadAir       bool    `json:"trimDeadAir"`       // Shrink segments that start or end in analysed dead air, for automatically picked ranges
+}
+
+// CompilationProgress reports how far a compilation has got
+type CompilationProgress struct {
+	Stage         string `json:"stage"`   // "segment", "joining" or "done"
+	Segment       int    `json:"segment"` // 1-based index of the segment being rendered
+	TotalSegments int    `json:"totalSegments"`
+	Message       string `json:"message"`
+}
+
+const (
+	defaultCompilationWidth     = 1920
+	defaultCompilationHeight    = 1080
+	defaultCompilationFPS       = 30
+	defaultTitleCardDuration    = 2.0
+	compilationAudioSampleRate  = "48000"
+	compilationAudioChannelSpec = "stereo"
+)
+
+// compilationPart is a rendered piece of the compilation, either a segment or a title card
+type compilationPart struct {
+	path     string
+	duration float64
+}
+
+// CreateCompilation renders the segments, which may come from different
+// videos, into a single video. Each segment is normalised to the same
+// resolution and frame rate first so they can be joined. progress may be nil.
+func (s *ClipService) CreateCompilation(segments []CompilationSegment, title string, options CompilationOptions, progress func(CompilationProgress)) ClipResult {
+	if progress == nil {
+		progress = func(CompilationProgress) {}
+	}
+
+	// Validate inputs
+	if len(segments) == 0 {
+		return ClipResult{Success: false, ErrorMessage: "No segments provided"}
+	}
+	// ffmpeg runs in the work directory, so relative source paths must be resolved first
+	segments = append([]CompilationSegment{}, segments...)
+	for i, segment := range segments {
+		sourcePath, err := filepath.Abs(segment.SourcePath)
+		if err != nil {
+			return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Segment %d: invalid source path: %v", i+1, err)}
+		}
+		segments[i].SourcePath = sourcePath
+		if _, err := os.Stat(sourcePath); err != nil {
+			return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Segment %d: source video not found", i+1)}
+		}
+		if segment.EndTime <= segment.StartTime {
+			return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Segment %d: end time must be after start time", i+1)}
+		}
+	}
+
+	if options.Width <= 0 || options.Height <= 0 {
+		options.Width, options.Height = defaultCompilationWidth, defaultCompilationHeight
+	}
+	// libx264 needs even dimensions
+	options.Width -= options.Width % 2
+	options.Height -= options.Height % 2
+	if options.FPS <= 0 {
+		options.FPS = defaultCompilationFPS
+	}
+	if options.TitleCardDuration <= 0 {
+		options.TitleCardDuration = defaultTitleCardDuration
+	}
+
+	if title == "" {
+		title = fmt.Sprintf("compilation_%s", time.Now().Format("20060102_150405"))
+	}
+	workDir, err := os.MkdirTemp("", "archive-player-compilation-*")
+	if err != nil {
+		return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Failed to create work directory: %v", err)}
+	}
+	defer os.RemoveAll(workDir)
+
+	// Render every segment (and its title card) to an intermediate file with identical encoding settings
+	var parts []compilationPart
+	for i, segment := range segments {
+		progress(CompilationProgress{
+			Stage:         "segment",
+			Segment:       i + 1,
+			TotalSegments: len(segments),
+			Message:       fmt.Sprintf("Rendering segment %d of %d", i+1, len(segments)),
+		})
+
+		if options.TitleCards && strings.TrimSpace(segment.Title) != "" {
+			card, err := renderTitleCard(workDir, i, segment.Title, options)
+			if err != nil {
+				return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Segment %d: %v", i+1, err)}
+			}
+			parts = append(parts, card)
+		}
+
+		part, err := renderCompilationSegment(workDir, i, segment, options)
+		if err != nil {
+			return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Segment %d: %v", i+1, err)}
+		}
+		parts = append(parts, part)
+	}
+
+	progress(CompilationProgress{
+		Stage:         "joining",
+		Segment:       len(segments),
+		TotalSegments: len(segments),
+		Message:       "Joining segments",
+	})
+
+	// Crossfades can't be longer than the shortest part they join
+	crossfade := options.Crossfade
+	for _, part := range parts {
+		if crossfade*2 >= part.duration {
+			crossfade = 0
+		}
+	}
+
+	segmentsDuration := 0.0
+	for _, segment := range segments {
+		segmentsDuration += segment.EndTime - segment.StartTime
+	}
+	outputPath, err := s.clipOutputPath(clipName{
+		videoPath: segments[0].SourcePath,
+		startTime: segments[0].StartTime,
+		duration:  segmentsDuration,
+		title:     title,
+	}, ".mp4")
+	if err != nil {
+		return ClipResult{Success: false, ErrorMessage: err.Error()}
+	}
+
+	if crossfade > 0 && len(parts) > 1 {
+		err = joinPartsWithCrossfade(workDir, parts, crossfade, outputPath)
+	} else {
+		err = joinPartsWithConcat(workDir, parts, outputPath)
+	}
+	if err != nil {
+		os.Remove(outputPath)
+		return ClipResult{Success: false, ErrorMessage: err.Error()}
+	}
+
+	totalDuration := 0.0
+	for _, part := range parts {
+		totalDuration += part.duration
+	}
+	if crossfade > 0 {
+		totalDuration -= crossfade * float64(len(parts)-1)
+	}
+
+	s.saveClipRecord(outputPath, ClipMetadata{
+		Version:    clipMetadataVersion,
+		SourcePath: segments[0].SourcePath,
+		Duration:   totalDuration,
+		Title:      title,
+		CreatedAt:  time.Now(),
+		Tags:       []string{},
+		Segments:   segments,
+	})
+
+	progress(CompilationProgress{
+		Stage:         "done",
+		Segment:       len(segments),
+		TotalSegments: len(segments),
+		Message:       "Compilation finished",
+	})
+
+	return ClipResult{
+		Success:  true,
+		FilePath: outputPath,
+	}
+}
+
+// renderCompilationSegment cuts and normalises one segment
+func renderCompilationSegment(workDir string, index int, segment CompilationSegment, options CompilationOptions) (compilationPart, error) {
+	duration := segment.EndTime - segment.StartTime
+	partPath := filepath.Join(workDir, fmt.Sprintf("segment_%03d.mp4", index))
+
+	args := []string{
+		"-ss", formatFFmpegTime(segment.StartTime),
+		"-t", formatFFmpegTime(duration),
+		"-i", segment.SourcePath,
+	}
+
+	// Sources without audio get silence so every part has the same streams
+	audioInput := "[0:a]"
+	if media, err := probeMedia(segment.SourcePath); err != nil || !media.HasAudio() {
+		args = append(args, "-f", "lavfi", "-t", formatFFmpegTime(duration),
+			"-i", "anullsrc=r="+compilationAudioSampleRate+":cl="+compilationAudioChannelSpec)
+		audioInput = "[1:a]"
+	}
+
+	filter := normalizeVideoFilter("[0:v]", options) + "[v];" +
+		audioInput + "aresample=" + compilationAudioSampleRate + ",aformat=channel_layouts=" + compilationAudioChannelSpec + "[a]"
+	args = append(args, "-filter_complex", filter, "-map", "[v]", "-map", "[a]")
+	args = append(args, compilationEncodeArgs()...)
+	args = append(args, "-y", partPath)
+
+	if err := runFFmpeg(workDir, args); err != nil {
+		return compilationPart{}, err
+	}
+	return compilationPart{path: partPath, duration: duration}, nil
+}
+
+// renderTitleCard renders a black card showing a segment title
+func renderTitleCard(workDir string, index int, title string, options CompilationOptions) (compilationPart, error) {
+	partPath := filepath.Join(workDir, fmt.Sprintf("title_%03d.mp4", index))
+
+	// The title is read from a file with expansion off, so quotes, % and \ are drawn as typed
+	textFile := fmt.Sprintf("title_%03d.txt", index)
+	if err := os.WriteFile(filepath.Join(workDir, textFile), []byte(title), 0644); err != nil {
+		return compilationPart{}, err
+	}
+
+	duration := formatFFmpegTime(options.TitleCardDuration)
+	args := []string{
+		"-f", "lavfi", "-t", duration,
+		"-i", fmt.Sprintf("color=c=black:s=%dx%d:r=%d", options.Width, options.Height, options.FPS),
+		"-f", "lavfi", "-t", duration,
+		"-i", "anullsrc=r=" + compilationAudioSampleRate + ":cl=" + compilationAudioChannelSpec,
+		"-vf", fmt.Sprintf("drawtext=%stextfile=%s:expansion=none:fontcolor=white:fontsize=%d:x=(w-text_w)/2:y=(h-text_h)/2,format=yuv420p",
+			drawtextFontOption(), textFile, options.Height/12),
+		"-map", "0:v", "-map", "1:a",
+	}
+	args = append(args, compilationEncodeArgs()...)
+	args = append(args, "-y", partPath)
+
+	if err := runFFmpeg(workDir, args); err != nil {
+		return compilationPart{}, err
+	}
+	return compilationPart{path: partPath, duration: options.TitleCardDuration}, nil
+}
+
+// joinPartsWithConcat joins parts back to back. They share encoding settings
+// so the streams are copied without re-encoding.
+func joinPartsWithConcat(workDir string, parts []compilationPart, outputPath string) error {
+	var list strings.Builder
+	for _, part := range parts {
+		list.WriteString("file '" + strings.ReplaceAll(filepath.ToSlash(part.path), "'", `'\''`) + "'\n")
+	}
+	if err := os.WriteFile(filepath.Join(workDir, "parts.txt"), []byte(list.String()), 0644); err != nil {
+		return err
+	}
+
+	return runFFmpeg(workDir, []string{
+		"-f", "concat", "-safe", "0",
+		"-i", "parts.txt",
+		"-c", "copy",
+		"-movflags", "+faststart",
+		"-y", outputPath,
+	})
+}
+
+// joinPartsWithCrossfade joins parts with xfade/acrossfade transitions
+func joinPartsWithCrossfade(workDir string, parts []compilationPart, crossfade float64, outputPath string) error {
+	var args []string
+	for _, part := range parts {
+		args = append(args, "-i", part.path)
+	}
+
+	var graph strings.Builder
+	videoLabel, audioLabel := "[0:v]", "[0:a]"
+	offset := 0.0
+	for i := 1; i < len(parts); i++ {
+		// Each transition starts crossfade seconds before the joined video so far ends
+		offset += parts[i-1].duration - crossfade
+		nextVideo, nextAudio := fmt.Sprintf("[v%d]", i), fmt.Sprintf("[a%d]", i)
+		graph.WriteString(fmt.Sprintf("%s[%d:v]xfade=transition=fade:duration=%.3f:offset=%.3f%s;",
+			videoLabel, i, crossfade, offset, nextVideo))
+		graph.WriteString(fmt.Sprintf("%s[%d:a]acrossfade=d=%.3f%s;", audioLabel, i, crossfade, nextAudio))
+		videoLabel, audioLabel = nextVideo, nextAudio
+	}
+
+	args = append(args,
+		"-filter_complex", strings.TrimSuffix(graph.String(), ";"),
+		"-map", videoLabel, "-map", audioLabel,
+	)
+	args = append(args, compilationEncodeArgs()...)
+	args = append(args, "-movflags", "+faststart", "-y", outputPath)
+
+	return runFFmpeg(workDir, args)
+}
+
+// normalizeVideoFilter scales and pads a video stream to the compilation format
+func normalizeVideoFilter(input string, options CompilationOptions) string {
+	return fmt.Sprintf("%sscale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=%d,format=yuv420p",
+		input, options.Width, options.Height, options.Width, options.Height, options.FPS)
+}
+
+// compilationEncodeArgs are the encoder settings shared by every compilation part
+func compilationEncodeArgs() []string {
+	return []string{
+		"-c:v", "libx264",
+		"-preset", "veryfast",
+		"-crf", "18",
+		"-c:a", "aac",
+		"-b:a", "192k",
+		"-ar", compilationAudioSampleRate,
+	}
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"FanslyArchivePlayer/backend/models"
+)
+
+// FrameFormat is the image format used for frame grabs
+type FrameFormat string
+
+const (
+	// FramePNG saves lossless PNG images
+	FramePNG FrameFormat = "png"
+	// FrameJPEG saves high quality JPEG images
+	FrameJPEG FrameFormat = "jpeg"
+	// FrameWebP saves high quality WebP images
+	FrameWebP FrameFormat = "webp"
+)
+
+// FrameGrabOptions controls how a frame is saved
+type FrameGrabOptions struct {
+	Format      FrameFormat        `json:"format"`
+	ChatOverlay ChatOverlayOptions `json:"chatOverlay"` // Burns in the chat as it looked at that moment
+}
+
+// FrameBurstOptions controls a burst of frames grabbed over a range
+type FrameBurstOptions struct {
+	Format      FrameFormat        `json:"format"`
+	ChatOverlay ChatOverlayOptions `json:"chatOverlay"`
+	Interval    float64            `json:"interval"`   // Seconds between frames
+	Columns     int                `json:"columns"`    // Tiles per row of the grid image
+	TileWidth   int                `json:"tileWidth"`  // Width of each grid tile in pixels
+	SaveFrames  bool               `json:"saveFrames"` // Also save every frame at full resolution
+}
+
+// FrameBurstResult is the outcome of a frame burst
+type FrameBurstResult struct {
+	Success      bool     `json:"success"`
+	GridPath     string   `json:"gridPath"`
+	FramePaths   []string `json:"framePaths"`
+	ErrorMessage string   `json:"errorMessage,omitempty"`
+}
+
+const (
+	defaultBurstInterval  = 10.0
+	defaultBurstColumns   = 4
+	defaultBurstTileWidth = 480
+	// framesFolderName is the folder in the clips directory frame grabs are saved in
+	framesFolderName = "frames"
+	// maxBurstFrames keeps the grid image to a sensible size
+	maxBurstFrames = 100
+	// frameOverlayDuration is how long the chat overlay script lasts for a single frame
+	frameOverlayDuration = 1.0
+)
+
+// frameCodecArgs returns the file extension and ffmpeg encoder arguments for a frame format
+func frameCodecArgs(format FrameFormat) (string, []string, error) {
+	switch format {
+	case FramePNG, "":
+		return ".png", []string{"-c:v", "png"}, nil
+	case FrameJPEG:
+		return ".jpg", []string{"-c:v", "mjpeg", "-q:v", "2"}, nil
+	case FrameWebP:
+		return ".webp", []string{"-c:v", "libwebp", "-quality", "90"}, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported image format: %s", format)
+	}
+}
+
+// GrabFrame saves the frame at time as a full resolution image in the frames
+// folder of the clips directory, and adds it to the clip library
+func (s *ClipService) GrabFrame(source ClipSource, time float64, title string, options FrameGrabOptions) ClipResult {
+	if source.Path == "" {
+		return ClipResult{Success: false, ErrorMessage: "No source video provided"}
+	}
+	if time < 0 {
+		return ClipResult{Success: false, ErrorMessage: "Time can't be negative"}
+	}
+	ext, codecArgs, err := frameCodecArgs(options.Format)
+	if err != nil {
+		return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Unsupported image format: %s", options.Format)}
+	}
+
+	input, errorMessage := frameInput(source, time, frameOverlayDuration)
+	if errorMessage != "" {
+		return ClipResult{Success: false, ErrorMessage: errorMessage}
+	}
+
+	workDir, err := os.MkdirTemp("", "archive-player-frame-*")
+	if err != nil {
+		return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Failed to create work directory: %v", err)}
+	}
+	defer os.RemoveAll(workDir)
+
+	graph := &filterGraph{}
+	video, err := addFrameChatOverlay(graph, "[0:v]", input.path, workDir, source.ChatMessages, time, frameOverlayDuration, options.ChatOverlay)
+	if err != nil {
+		return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Failed to add chat overlay: %v", err)}
+	}
+
+	outputPath, err := s.frameOutputPath(clipName{
+		model:     source.Model,
+		videoPath: input.path,
+		startTime: time,
+		title:     title,
+		preset:    "frame",
+	}, ext)
+	if err != nil {
+		return ClipResult{Success: false, ErrorMessage: err.Error()}
+	}
+
+	// Seeking on the input decodes up to the exact frame, not just the nearest keyframe
+	args := []string{"-ss", formatFFmpegTime(input.start), "-i", input.path}
+	if !graph.empty() {
+		args = append(args, "-filter_complex", graph.String(), "-map", video)
+	}
+	args = append(args, "-frames:v", "1", "-update", "1")
+	args = append(args, codecArgs...)
+	args = append(args, "-y", outputPath)
+
+	if err := runFFmpeg(workDir, args); err != nil {
+		os.Remove(outputPath)
+		return ClipResult{Success: false, ErrorMessage: err.Error()}
+	}
+
+	s.recordClip(outputPath, source, time, 0, title, "frame")
+
+	return ClipResult{
+		Success:  true,
+		FilePath: outputPath,
+	}
+}
+
+// GrabFrameBurst grabs a frame every interval seconds between startTime and
+// endTime and saves them as one grid image, plus the single frames if asked
+func (s *ClipService) GrabFrameBurst(source ClipSource, startTime float64, endTime float64, title string, options FrameBurstOptions) FrameBurstResult {
+	if source.Path == "" {
+		return FrameBurstResult{Success: false, ErrorMessage: "No source video provided"}
+	}
+	if startTime < 0 || endTime <= startTime {
+		return FrameBurstResult{Success: false, ErrorMessage: "End time must be after the start time"}
+	}
+	ext, codecArgs, err := frameCodecArgs(options.Format)
+	if err != nil {
+		return FrameBurstResult{Success: false, ErrorMessage: fmt.Sprintf("Unsupported image format: %s", options.Format)}
+	}
+
+	if options.Interval <= 0 {
+		options.Interval = defaultBurstInterval
+	}
+	if options.Columns <= 0 {
+		options.Columns = defaultBurstColumns
+	}
+	if options.TileWidth <= 0 {
+		options.TileWidth = defaultBurstTileWidth
+	}
+	duration := endTime - startTime
+	count := int(math.Floor(duration/options.Interval)) + 1
+	if count > maxBurstFrames {
+		return FrameBurstResult{Success: false, ErrorMessage: fmt.Sprintf("A burst is limited to %d frames, use a longer interval", maxBurstFrames)}
+	}
+	columns := options.Columns
+	if count < columns {
+		columns = count
+	}
+	rows := (count + columns - 1) / columns
+
+	input, errorMessage := frameInput(source, startTime, duration)
+	if errorMessage != "" {
+		return FrameBurstResult{Success: false, ErrorMessage: errorMessage}
+	}
+	// A burst is read from one file, so it stops at the end of a recording part
+	duration = input.duration
+
+	workDir, err := os.MkdirTemp("", "archive-player-frame-*")
+	if err != nil {
+		return FrameBurstResult{Success: false, ErrorMessage: fmt.Sprintf("Failed to create work directory: %v", err)}
+	}
+	defer os.RemoveAll(workDir)
+
+	graph := &filterGraph{}
+	video, err := addFrameChatOverlay(graph, "[0:v]", input.path, workDir, source.ChatMessages, startTime, duration, options.ChatOverlay)
+	if err != nil {
+		return FrameBurstResult{Success: false, ErrorMessage: fmt.Sprintf("Failed to add chat overlay: %v", err)}
+	}
+	video = graph.chain(video, fmt.Sprintf("fps=1/%.3f", options.Interval))
+	grid := video
+	frames := ""
+	if options.SaveFrames {
+		outputs := graph.split(video, 2)
+		grid, frames = outputs[0], outputs[1]
+	}
+	// A partly filled last row is left blank
+	grid = graph.chain(grid, fmt.Sprintf("scale=%d:-2,tile=%dx%d:padding=4:margin=4", options.TileWidth, columns, rows))
+
+	gridPath, err := s.frameOutputPath(clipName{
+		model:     source.Model,
+		videoPath: input.path,
+		startTime: startTime,
+		duration:  duration,
+		title:     title,
+		preset:    "burst",
+	}, ext)
+	if err != nil {
+		return FrameBurstResult{Success: false, ErrorMessage: err.Error()}
+	}
+
+	args := []string{
+		"-ss", formatFFmpegTime(input.start),
+		"-t", formatFFmpegTime(duration),
+		"-i", input.path,
+		"-filter_complex", graph.String(),
+		"-map", grid, "-frames:v", "1", "-update", "1",
+	}
+	args = append(args, codecArgs...)
+	args = append(args, "-y", gridPath)
+	// The single frames go in a folder next to the grid image
+	framesDir := strings.TrimSuffix(gridPath, ext) + "_frames"
+	if frames != "" {
+		if err := os.MkdirAll(framesDir, 0755); err != nil {
+			os.Remove(gridPath)
+			return FrameBurstResult{Success: false, ErrorMessage: fmt.Sprintf("Failed to create frames folder: %v", err)}
+		}
+		pattern := strings.ReplaceAll(framesDir, "%", "%%") + string(filepath.Separator) + "frame_%04d" + ext
+		args = append(args, "-map", frames)
+		args = append(args, codecArgs...)
+		args = append(args, "-y", pattern)
+	}
+
+	if err := runFFmpeg(workDir, args); err != nil {
+		os.Remove(gridPath)
+		if frames != "" {
+			os.RemoveAll(framesDir)
+		}
+		return FrameBurstResult{Success: false, ErrorMessage: err.Error()}
+	}
+
+	s.recordClip(gridPath, source, startTime, duration, title, "burst")
+
+	result := FrameBurstResult{Success: true, GridPath: gridPath, FramePaths: []string{}}
+	if frames != "" {
+		framePaths, err := nameBurstFrames(framesDir, ext, startTime, options.Interval)
+		if err != nil {
+			return FrameBurstResult{Success: false, GridPath: gridPath, ErrorMessage: fmt.Sprintf("Failed to save frames: %v", err)}
+		}
+		result.FramePaths = framePaths
+	}
+	return result
+}
+
+// frameInput finds the file and local time to read frames from, or returns
+// an error message. For a multi-part recording that's the part time falls in.
+func frameInput(source ClipSource, time float64, duration float64) (clipInputRange, string) {
+	sourcePath, err := filepath.Abs(source.Path)
+	if err != nil {
+		return clipInputRange{}, fmt.Sprintf("Invalid source path: %v", err)
+	}
+	ranges := source.inputRanges(sourcePath, time, duration)
+	if len(ranges) == 0 {
+		return clipInputRange{}, "Time is outside the recording"
+	}
+	return ranges[0], ""
+}
+
+// addFrameChatOverlay burns in the chat as it looked from time onwards.
+// Messages sent before time are shown from the first frame, like in the
+// player's chat panel.
+func addFrameChatOverlay(graph *filterGraph, video string, videoPath string, workDir string, messages []models.ChatMessage, time float64, duration float64, options ChatOverlayOptions) (string, error) {
+	if !options.Enabled {
+		return video, nil
+	}
+
+	overlayMessages := []models.ChatMessage{}
+	for _, msg := range messages {
+		if msg.TimeInSeconds > time+duration {
+			continue
+		}
+		msg.TimeInSeconds = math.Max(msg.TimeInSeconds-time, 0)
+		overlayMessages = append(overlayMessages, msg)
+	}
+	if len(overlayMessages) == 0 {
+		return video, nil
+	}
+
+	media, err := probeMedia(videoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read video: %v", err)
+	}
+	frameWidth, frameHeight, err := media.VideoSize()
+	if err != nil {
+		return "", fmt.Errorf("failed to read video size: %v", err)
+	}
+	script := buildChatOverlayASS(overlayMessages, duration, frameWidth, frameHeight, options)
+	if err := os.WriteFile(filepath.Join(workDir, "chat.ass"), []byte(script), 0644); err != nil {
+		return "", fmt.Errorf("failed to write chat overlay script: %v", err)
+	}
+	return graph.chain(video, "subtitles=chat.ass"), nil
+}
+
+// nameBurstFrames renames the numbered frames of a burst after the time
+// each frame was taken
+func nameBurstFrames(framesDir string, ext string, startTime float64, interval float64) ([]string, error) {
+	framePaths := []string{}
+	for i := 1; ; i++ {
+		framePath := filepath.Join(framesDir, fmt.Sprintf("frame_%04d%s", i, ext))
+		if _, err := os.Stat(framePath); err != nil {
+			break
+		}
+		frameTime := startTime + float64(i-1)*interval
+		target := filepath.Join(framesDir, fmt.Sprintf("%03d_%s%s", i, formatFilenameTime(frameTime), ext))
+		if err := os.Rename(framePath, target); err != nil {
+			return framePaths, err
+		}
+		framePaths = append(framePaths, target)
+	}
+	return framePaths, nil
+}
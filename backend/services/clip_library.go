@@ -0,0 +1,543 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"FanslyArchivePlayer/backend/models"
+)
+
+const (
+	// clipMetadataVersion is written to every sidecar so the format can evolve
+	clipMetadataVersion = 1
+	// clipSidecarSuffix is appended to the clip path to name its metadata file
+	clipSidecarSuffix = ".json"
+	// clipThumbnailSuffix is appended to the clip path to name its thumbnail
+	clipThumbnailSuffix = ".jpg"
+)
+
+// ClipMetadata is the sidecar JSON written next to every clip
+type ClipMetadata struct {
+	Version    int                  `json:"version"`
+	SourcePath string               `json:"sourcePath"`
+	SourceHash string               `json:"sourceHash,omitempty"`
+	Model      string               `json:"model,omitempty"`
+	StartTime  float64              `json:"startTime"`
+	Duration   float64              `json:"duration"`
+	Title      string               `json:"title"`
+	Preset     string               `json:"preset,omitempty"`
+	CreatedAt  time.Time            `json:"createdAt"`
+	Tags       []string             `json:"tags"`
+	Chat       []models.ChatMessage `json:"chat,omitempty"`     // Messages from the clip range, rebased to the clip start
+	Segments   []CompilationSegment `json:"segments,omitempty"` // Source ranges of a compilation, in order
+}
+
+// ClipRecord describes a saved clip in the clip library
+type ClipRecord struct {
+	FilePath      string        `json:"filePath"`
+	FileName      string        `json:"fileName"`
+	FileSize      int64         `json:"fileSize"`
+	ModifiedAt    time.Time     `json:"modifiedAt"`
+	ThumbnailPath string        `json:"thumbnailPath,omitempty"`
+	Metadata      *ClipMetadata `json:"metadata,omitempty"` // Nil for clips made before sidecars existed
+}
+
+// ClipSourceMoment points at the place in the source video a clip was cut from
+type ClipSourceMoment struct {
+	VideoPath string  `json:"videoPath"`
+	VideoURL  string  `json:"videoUrl,omitempty"`
+	ChatPath  string  `json:"chatPath,omitempty"`
+	StartTime float64 `json:"startTime"`
+	Duration  float64 `json:"duration"`
+}
+
+// clipIndex lists clips created by the app so clips outside the scanned
+// directories (e.g. next to their source video) are still found
+type clipIndex struct {
+	Clips []string `json:"clips"`
+}
+
+// recordClip writes the sidecar metadata and thumbnail for a newly created
+// clip and adds it to the library index. Failures are logged rather than
+// returned because the clip itself was created successfully.
+func (s *ClipService) recordClip(clipPath string, source ClipSource, startTime float64, duration float64, title string, preset string) {
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(clipPath), filepath.Ext(clipPath))
+	}
+
+	metadata := ClipMetadata{
+		Version:    clipMetadataVersion,
+		SourcePath: source.Path,
+		Model:      source.Model,
+		StartTime:  startTime,
+		Duration:   duration,
+		Title:      title,
+		Preset:     preset,
+		CreatedAt:  time.Now(),
+		Tags:       []string{},
+		Chat:       selectClipMessages(source.ChatMessages, startTime, duration),
+	}
+	if absPath, err := filepath.Abs(source.Path); err == nil {
+		metadata.SourcePath = absPath
+	}
+	// Clips of a multi-part recording point at the part they start in, and
+	// list every part they cover
+	if ranges := source.inputRanges(metadata.SourcePath, startTime, duration); len(source.Parts) > 0 && len(ranges) > 0 {
+		metadata.SourcePath = ranges[0].path
+		metadata.StartTime = ranges[0].start
+		if len(ranges) > 1 {
+			for _, inputRange := range ranges {
+				metadata.Segments = append(metadata.Segments, CompilationSegment{
+					SourcePath: inputRange.path,
+					StartTime:  inputRange.start,
+					EndTime:    inputRange.start + inputRange.duration,
+				})
+			}
+		}
+	}
+	if s.cacheService != nil {
+		if hash, err := s.cacheService.GetFileHash(metadata.SourcePath); err == nil {
+			metadata.SourceHash = hash
+		}
+	}
+	// Raw event payloads are large and not useful outside the player
+	for i := range metadata.Chat {
+		metadata.Chat[i].RawData = ""
+	}
+
+	s.saveClipRecord(clipPath, metadata)
+}
+
+// saveClipRecord writes the sidecar and thumbnail for a new clip and adds it
+// to the library index
+func (s *ClipService) saveClipRecord(clipPath string, metadata ClipMetadata) {
+	if err := writeClipMetadata(clipPath, metadata); err != nil {
+		fmt.Printf("Failed to write clip metadata: %v\n", err)
+	}
+	if err := generateClipThumbnail(clipPath, &metadata); err != nil {
+		fmt.Printf("Failed to generate clip thumbnail: %v\n", err)
+	}
+	if err := s.updateClipIndex(func(index *clipIndex) {
+		index.Clips = append(index.Clips, clipPath)
+	}); err != nil {
+		fmt.Printf("Failed to update clip library: %v\n", err)
+	}
+}
+
+// GetClipMetadata returns the full sidecar metadata of a clip, including its chat slice
+func (s *ClipService) GetClipMetadata(clipPath string) (ClipMetadata, error) {
+	metadata, err := readClipMetadata(clipPath)
+	if err != nil {
+		return ClipMetadata{}, err
+	}
+	if metadata == nil {
+		return ClipMetadata{}, fmt.Errorf("clip has no metadata")
+	}
+	return *metadata, nil
+}
+
+// RenameClip renames a clip together with its sidecar files and updates its title
+func (s *ClipService) RenameClip(clipPath string, newTitle string) (ClipRecord, error) {
+	if _, err := os.Stat(clipPath); err != nil {
+		return ClipRecord{}, fmt.Errorf("clip not found: %v", err)
+	}
+	newTitle = strings.TrimSpace(newTitle)
+	if newTitle == "" {
+		return ClipRecord{}, fmt.Errorf("title cannot be empty")
+	}
+
+	newPath := filepath.Join(filepath.Dir(clipPath), sanitizeFilename(newTitle)+filepath.Ext(clipPath))
+	if newPath != clipPath {
+		if _, err := os.Stat(newPath); err == nil {
+			return ClipRecord{}, fmt.Errorf("a clip named %s already exists", filepath.Base(newPath))
+		}
+		if err := os.Rename(clipPath, newPath); err != nil {
+			return ClipRecord{}, fmt.Errorf("failed to rename clip: %v", err)
+		}
+		for _, suffix := range []string{clipSidecarSuffix, clipThumbnailSuffix} {
+			if _, err := os.Stat(clipPath + suffix); err == nil {
+				os.Rename(clipPath+suffix, newPath+suffix)
+			}
+		}
+	}
+
+	metadata, err := readClipMetadata(newPath)
+	if err != nil {
+		return ClipRecord{}, err
+	}
+	if metadata == nil {
+		metadata = &ClipMetadata{Version: clipMetadataVersion, Tags: []string{}}
+	}
+	metadata.Title = newTitle
+	if err := writeClipMetadata(newPath, *metadata); err != nil {
+		return ClipRecord{}, err
+	}
+
+	if err := s.updateClipIndex(func(index *clipIndex) {
+		for i, path := range index.Clips {
+			if path == clipPath {
+				index.Clips[i] = newPath
+			}
+		}
+	}); err != nil {
+		fmt.Printf("Failed to update clip library: %v\n", err)
+	}
+
+	return buildClipRecord(newPath)
+}
+
+// DeleteClip moves a clip and its sidecar files to the system trash
+func (s *ClipService) DeleteClip(clipPath string) error {
+	if err := moveToTrash(clipPath); err != nil {
+		return err
+	}
+	for _, suffix := range []string{clipSidecarSuffix, clipThumbnailSuffix} {
+		if _, err := os.Stat(clipPath + suffix); err == nil {
+			if err := moveToTrash(clipPath + suffix); err != nil {
+				fmt.Printf("Failed to move %s to trash: %v\n", clipPath+suffix, err)
+			}
+		}
+	}
+
+	return s.updateClipIndex(func(index *clipIndex) {
+		kept := index.Clips[:0]
+		for _, path := range index.Clips {
+			if path != clipPath {
+				kept = append(kept, path)
+			}
+		}
+		index.Clips = kept
+	})
+}
+
+// SetClipTags replaces the tags of a clip
+func (s *ClipService) SetClipTags(clipPath string, tags []string) (ClipRecord, error) {
+	if _, err := os.Stat(clipPath); err != nil {
+		return ClipRecord{}, fmt.Errorf("clip not found: %v", err)
+	}
+
+	metadata, err := readClipMetadata(clipPath)
+	if err != nil {
+		return ClipRecord{}, err
+	}
+	if metadata == nil {
+		metadata = &ClipMetadata{
+			Version: clipMetadataVersion,
+			Title:   strings.TrimSuffix(filepath.Base(clipPath), filepath.Ext(clipPath)),
+		}
+	}
+
+	metadata.Tags = normalizeTags(tags)
+	if err := writeClipMetadata(clipPath, *metadata); err != nil {
+		return ClipRecord{}, err
+	}
+
+	return buildClipRecord(clipPath)
+}
+
+// GetClipSourceMoment returns the source video and time range a clip was cut
+// from. If the source has been moved, it is looked up by its hash.
+func (s *ClipService) GetClipSourceMoment(clipPath string) (ClipSourceMoment, error) {
+	metadata, err := s.GetClipMetadata(clipPath)
+	if err != nil {
+		return ClipSourceMoment{}, err
+	}
+
+	videoPath := metadata.SourcePath
+	if _, err := os.Stat(videoPath); err != nil {
+		videoPath = ""
+		if metadata.SourceHash != "" && s.cacheService != nil {
+			videoPath = s.cacheService.FindPathByHash(metadata.SourceHash)
+		}
+		if videoPath == "" {
+			return ClipSourceMoment{}, fmt.Errorf("source video not found: %s", metadata.SourcePath)
+		}
+	}
+
+	moment := ClipSourceMoment{
+		VideoPath: videoPath,
+		StartTime: metadata.StartTime,
+		Duration:  metadata.Duration,
+	}
+
+	// Pair the chat file the same way the Fansly integration does
+	chatPath := strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + "_chat.json"
+	if _, err := os.Stat(chatPath); err == nil {
+		moment.ChatPath = chatPath
+	}
+
+	return moment, nil
+}
+
+// buildClipRecords builds library records for clip paths, skipping duplicates
+// and files that no longer exist. Newest clips come first.
+func (s *ClipService) buildClipRecords(clipPaths []string) []ClipRecord {
+	records := []ClipRecord{}
+	seen := make(map[string]bool)
+	var missingThumbnails []string
+
+	for _, clipPath := range clipPaths {
+		key := clipPath
+		if absPath, err := filepath.Abs(clipPath); err == nil {
+			key = absPath
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		record, err := buildClipRecord(key)
+		if err != nil {
+			continue
+		}
+		if record.ThumbnailPath == "" {
+			missingThumbnails = append(missingThumbnails, key)
+		}
+		// Chat slices can be large, fetch them with GetClipMetadata instead
+		if record.Metadata != nil {
+			record.Metadata.Chat = nil
+		}
+		records = append(records, record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ModifiedAt.After(records[j].ModifiedAt)
+	})
+
+	s.queueClipThumbnails(missingThumbnails)
+	return records
+}
+
+// SetThumbnailReadyHandler sets a function called with the updated record
+// when a missing clip thumbnail has been generated in the background
+func (s *ClipService) SetThumbnailReadyHandler(onThumbnail func(ClipRecord)) {
+	s.thumbnailMu.Lock()
+	defer s.thumbnailMu.Unlock()
+	s.onThumbnail = onThumbnail
+}
+
+// queueClipThumbnails queues thumbnail generation for clips that have none,
+// e.g. ones made before thumbnails existed. Clips are processed one at a
+// time in the background so listing a large library doesn't wait for ffmpeg.
+func (s *ClipService) queueClipThumbnails(clipPaths []string) {
+	s.thumbnailMu.Lock()
+	defer s.thumbnailMu.Unlock()
+
+	for _, clipPath := range clipPaths {
+		if s.thumbnailQueued[clipPath] || s.thumbnailFailed[clipPath] {
+			continue
+		}
+		s.thumbnailQueued[clipPath] = true
+		s.thumbnailQueue = append(s.thumbnailQueue, clipPath)
+	}
+
+	if !s.thumbnailsBusy && len(s.thumbnailQueue) > 0 {
+		s.thumbnailsBusy = true
+		go s.processThumbnailQueue()
+	}
+}
+
+// processThumbnailQueue generates thumbnails for queued clips until the queue is empty
+func (s *ClipService) processThumbnailQueue() {
+	for {
+		s.thumbnailMu.Lock()
+		if len(s.thumbnailQueue) == 0 {
+			s.thumbnailsBusy = false
+			s.thumbnailMu.Unlock()
+			return
+		}
+		clipPath := s.thumbnailQueue[0]
+		s.thumbnailQueue = s.thumbnailQueue[1:]
+		s.thumbnailMu.Unlock()
+
+		metadata, _ := readClipMetadata(clipPath)
+		err := generateClipThumbnail(clipPath, metadata)
+
+		s.thumbnailMu.Lock()
+		delete(s.thumbnailQueued, clipPath)
+		if err != nil {
+			s.thumbnailFailed[clipPath] = true
+		}
+		onThumbnail := s.onThumbnail
+		s.thumbnailMu.Unlock()
+
+		if err != nil {
+			fmt.Printf("Failed to generate clip thumbnail for %s: %v\n", clipPath, err)
+			continue
+		}
+		record, err := buildClipRecord(clipPath)
+		if err != nil {
+			continue
+		}
+		// Chat slices can be large, fetch them with GetClipMetadata instead
+		if record.Metadata != nil {
+			record.Metadata.Chat = nil
+		}
+		if onThumbnail != nil {
+			onThumbnail(record)
+		}
+	}
+}
+
+// buildClipRecord builds the library record for a single clip. Older clips
+// may have no thumbnail yet; buildClipRecords queues one for them.
+func buildClipRecord(clipPath string) (ClipRecord, error) {
+	fileInfo, err := os.Stat(clipPath)
+	if err != nil {
+		return ClipRecord{}, err
+	}
+
+	record := ClipRecord{
+		FilePath:   clipPath,
+		FileName:   filepath.Base(clipPath),
+		FileSize:   fileInfo.Size(),
+		ModifiedAt: fileInfo.ModTime(),
+	}
+
+	metadata, err := readClipMetadata(clipPath)
+	if err == nil {
+		record.Metadata = metadata
+	}
+
+	thumbnailPath := clipPath + clipThumbnailSuffix
+	if _, err := os.Stat(thumbnailPath); err == nil {
+		record.ThumbnailPath = thumbnailPath
+	}
+
+	return record, nil
+}
+
+// readClipMetadata reads a clip's sidecar, returning nil if it has none
+func readClipMetadata(clipPath string) (*ClipMetadata, error) {
+	data, err := os.ReadFile(clipPath + clipSidecarSuffix)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var metadata ClipMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse clip metadata: %v", err)
+	}
+	if metadata.Tags == nil {
+		metadata.Tags = []string{}
+	}
+	return &metadata, nil
+}
+
+// writeClipMetadata writes a clip's sidecar
+func writeClipMetadata(clipPath string, metadata ClipMetadata) error {
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(clipPath+clipSidecarSuffix, data, 0644)
+}
+
+// generateClipThumbnail grabs a frame from the middle of the clip (or one
+// second in when the duration is unknown) as a small JPEG. Frame grabs are
+// still images, so their thumbnail is the image itself scaled down.
+func generateClipThumbnail(clipPath string, metadata *ClipMetadata) error {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return fmt.Errorf("ffmpeg not found")
+	}
+
+	args := []string{}
+	if !isFrameGrab(metadata) {
+		seek := 1.0
+		if metadata != nil && metadata.Duration > 0 {
+			seek = metadata.Duration / 2
+		}
+		args = append(args, "-ss", formatFFmpegTime(seek))
+	}
+	args = append(args,
+		"-i", clipPath,
+		"-frames:v", "1",
+		"-vf", "scale=320:-2",
+		"-y",
+		clipPath+clipThumbnailSuffix,
+	)
+	cmd := exec.Command("ffmpeg", args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg error: %v\nOutput: %s", err, string(output))
+	}
+	return nil
+}
+
+// isFrameGrab reports whether a library entry is a still image from GrabFrame
+// or GrabFrameBurst rather than a clip
+func isFrameGrab(metadata *ClipMetadata) bool {
+	return metadata != nil && (metadata.Preset == "frame" || metadata.Preset == "burst")
+}
+
+// indexedClips returns the clip paths recorded in the library index
+func (s *ClipService) indexedClips() []string {
+	s.libraryMu.Lock()
+	defer s.libraryMu.Unlock()
+
+	index, err := s.loadClipIndex()
+	if err != nil {
+		return []string{}
+	}
+	return index.Clips
+}
+
+// updateClipIndex applies a change to the library index and saves it
+func (s *ClipService) updateClipIndex(update func(index *clipIndex)) error {
+	s.libraryMu.Lock()
+	defer s.libraryMu.Unlock()
+
+	index, err := s.loadClipIndex()
+	if err != nil {
+		return err
+	}
+	update(&index)
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.appDataDir, "clip_library.json"), data, 0644)
+}
+
+// loadClipIndex reads the library index, returning an empty index if there is none
+func (s *ClipService) loadClipIndex() (clipIndex, error) {
+	index := clipIndex{Clips: []string{}}
+
+	data, err := os.ReadFile(filepath.Join(s.appDataDir, "clip_library.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return index, nil
+		}
+		return index, err
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		// A corrupted index only loses clips outside the scanned directories
+		return clipIndex{Clips: []string{}}, nil
+	}
+	return index, nil
+}
+
+// normalizeTags trims tags and removes empty and duplicate entries
+func normalizeTags(tags []string) []string {
+	result := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, tag)
+	}
+	return result
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"FanslyArchivePlayer/backend/models"
+)
+
+const (
+	// Chat reacts a little after the moment on stream, so clips start well before the message
+	defaultMessagePreRoll  = 20.0
+	defaultMessagePostRoll = 5.0
+	maxMessageTitleLength  = 60
+)
+
+// CreateClipAroundMessage clips the moment a chat message reacted to. The clip
+// starts preRoll seconds before the message and ends postRoll seconds after it;
+// zero values use the defaults. An empty title uses the message text.
+func (s *ClipService) CreateClipAroundMessage(source ClipSource, message models.ChatMessage, preRoll float64, postRoll float64, title string, options ClipOptions) ClipResult {
+	if preRoll <= 0 {
+		preRoll = defaultMessagePreRoll
+	}
+	if postRoll <= 0 {
+		postRoll = defaultMessagePostRoll
+	}
+
+	startTime := message.TimeInSeconds - preRoll
+	if startTime < 0 {
+		startTime = 0
+	}
+	endTime := message.TimeInSeconds + postRoll
+	// Don't open or close the clip on a "be right back" screen
+	startTime, endTime = TrimToContent(source.SkipSegments, startTime, endTime)
+	duration := endTime - startTime
+
+	if strings.TrimSpace(title) == "" {
+		title = messageClipTitle(message)
+	}
+
+	return s.CreateClipWithOptions(source, startTime, duration, title, options)
+}
+
+// messageClipTitle turns a chat message into a short clip title
+func messageClipTitle(message models.ChatMessage) string {
+	title := strings.Join(strings.Fields(message.Message), " ")
+	if title == "" {
+		// Tips can come without a message
+		return message.Author.Name
+	}
+	if utf8.RuneCountInString(title) > maxMessageTitleLength {
+		title = strings.TrimSpace(string([]rune(title)[:maxMessageTitleLength])) + "…"
+	}
+	return title
+}
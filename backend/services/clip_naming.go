@@ -0,0 +1,215 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ClipNamingOptions controls how clip files are named and organised
+type ClipNamingOptions struct {
+	// Template for clip file names. Supported tokens: {model}, {stream_date},
+	// {start}, {end}, {duration}, {title} and {preset}.
+	Template string `json:"template"`
+	// Save clips in a subfolder per model inside the clips directory
+	ModelSubfolders bool `json:"modelSubfolders"`
+}
+
+const (
+	// DefaultClipNameTemplate names clips after their title
+	DefaultClipNameTemplate = "{title}"
+	// untitledClipName is used for {title} when a clip has no title
+	untitledClipName = "{model}_{stream_date}_{start}"
+)
+
+// clipName holds the values available to naming templates
+type clipName struct {
+	model     string
+	videoPath string
+	startTime float64
+	duration  float64
+	title     string
+	preset    string
+}
+
+var (
+	clipTokenPattern         = regexp.MustCompile(`\{[a-z_]+\}`)
+	emptyTokenWithSeparators = regexp.MustCompile("[ _.-]*\x00[ _.\x00-]*")
+	streamDateInFilename     = regexp.MustCompile(`(20\d{2})[-_.]?(\d{2})[-_.]?(\d{2})`)
+	defaultClipNaming        = ClipNamingOptions{Template: DefaultClipNameTemplate}
+	clipSettingsFileName     = "clip_settings.json"
+	clipNameTokenExpander    = map[string]func(clipName) string{
+		"{model}": func(name clipName) string {
+			return name.model
+		},
+		"{stream_date}": func(name clipName) string {
+			return streamDate(name.videoPath)
+		},
+		"{start}": func(name clipName) string {
+			return formatFilenameTime(name.startTime)
+		},
+		"{end}": func(name clipName) string {
+			return formatFilenameTime(name.startTime + name.duration)
+		},
+		"{duration}": func(name clipName) string {
+			return formatFilenameDuration(name.duration)
+		},
+		"{preset}": func(name clipName) string {
+			return name.preset
+		},
+	}
+)
+
+// SetNamingOptions sets and saves the clip naming options
+func (s *ClipService) SetNamingOptions(options ClipNamingOptions) error {
+	if strings.TrimSpace(options.Template) == "" {
+		options.Template = DefaultClipNameTemplate
+	}
+	for _, token := range clipTokenPattern.FindAllString(options.Template, -1) {
+		if _, ok := clipNameTokenExpander[token]; !ok && token != "{title}" {
+			return fmt.Errorf("unknown naming token: %s", token)
+		}
+	}
+
+	data, err := json.MarshalIndent(options, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(s.appDataDir, clipSettingsFileName), data, 0644); err != nil {
+		return err
+	}
+
+	s.naming = options
+	return nil
+}
+
+// GetNamingOptions returns the current clip naming options
+func (s *ClipService) GetNamingOptions() ClipNamingOptions {
+	return s.naming
+}
+
+// loadNamingOptions reads the saved naming options, falling back to the defaults
+func loadNamingOptions(appDataDir string) ClipNamingOptions {
+	options := defaultClipNaming
+	data, err := os.ReadFile(filepath.Join(appDataDir, clipSettingsFileName))
+	if err != nil {
+		return options
+	}
+	if err := json.Unmarshal(data, &options); err != nil || strings.TrimSpace(options.Template) == "" {
+		return defaultClipNaming
+	}
+	return options
+}
+
+// renderClipName expands the naming template into a file name without extension
+func (s *ClipService) renderClipName(name clipName) string {
+	titleValue := sanitizeFilename(name.title)
+	if strings.TrimSpace(name.title) == "" {
+		titleValue = strings.Trim(expandClipTokens(untitledClipName, name), " _.-")
+	}
+
+	filename := strings.Trim(expandClipTokens(s.naming.Template, name), " _.-")
+	filename = strings.ReplaceAll(filename, "{title}", titleValue)
+	if strings.TrimSpace(filename) == "" {
+		filename = fmt.Sprintf("clip_%s", time.Now().Format("20060102_150405"))
+	}
+
+	return sanitizeFilename(filename)
+}
+
+// expandClipTokens replaces every token except {title}. Tokens without a value
+// are dropped together with the separators around them.
+func expandClipTokens(template string, name clipName) string {
+	const emptyToken = "\x00"
+	expanded := clipTokenPattern.ReplaceAllStringFunc(template, func(token string) string {
+		expand, ok := clipNameTokenExpander[token]
+		if !ok {
+			return token
+		}
+		value := strings.TrimSpace(expand(name))
+		if value == "" {
+			return emptyToken
+		}
+		return sanitizeFilename(value)
+	})
+
+	return emptyTokenWithSeparators.ReplaceAllStringFunc(expanded, func(match string) string {
+		// Keep a single separator so the neighbouring parts stay apart
+		trimmed := strings.Trim(match, emptyToken)
+		if trimmed == "" {
+			return ""
+		}
+		return trimmed[:1]
+	})
+}
+
+// reserveClipPath finds a free file name in dir, adding " (2)", " (3)" and so
+// on when the name is taken. The file is created empty so a concurrent clip
+// can't claim the same name; ffmpeg then overwrites the placeholder.
+func reserveClipPath(dir string, baseName string, ext string) (string, error) {
+	for i := 1; i < 1000; i++ {
+		name := baseName
+		if i > 1 {
+			name = fmt.Sprintf("%s (%d)", baseName, i)
+		}
+		path := filepath.Join(dir, name+ext)
+
+		// Don't reuse a name whose sidecar is still around
+		if _, err := os.Stat(path + clipSidecarSuffix); err == nil {
+			continue
+		}
+
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			if os.IsExist(err) {
+				continue
+			}
+			return "", err
+		}
+		file.Close()
+		return path, nil
+	}
+	return "", fmt.Errorf("could not find a free file name for %s", baseName)
+}
+
+// streamDate returns the date of a stream, taken from its file name if it
+// contains one and from the file's modification time otherwise
+func streamDate(videoPath string) string {
+	if videoPath == "" {
+		return ""
+	}
+	if match := streamDateInFilename.FindStringSubmatch(filepath.Base(videoPath)); match != nil {
+		if date, err := time.Parse("20060102", match[1]+match[2]+match[3]); err == nil {
+			return date.Format("2006-01-02")
+		}
+	}
+	if fileInfo, err := os.Stat(videoPath); err == nil {
+		return fileInfo.ModTime().Format("2006-01-02")
+	}
+	return ""
+}
+
+// formatFilenameTime formats seconds as HH-MM-SS, which is safe in file names
+func formatFilenameTime(seconds float64) string {
+	total := int(seconds)
+	return fmt.Sprintf("%02d-%02d-%02d", total/3600, (total%3600)/60, total%60)
+}
+
+// formatFilenameDuration formats seconds compactly, e.g. 1m30s
+func formatFilenameDuration(seconds float64) string {
+	total := int(math.Round(seconds))
+	hours, minutes, secs := total/3600, (total%3600)/60, total%60
+	switch {
+	case hours > 0:
+		return fmt.Sprintf("%dh%02dm%02ds", hours, minutes, secs)
+	case minutes > 0:
+		return fmt.Sprintf("%dm%02ds", minutes, secs)
+	default:
+		return fmt.Sprintf("%ds", secs)
+	}
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PolishOptions holds optional audio and visual post-processing for a clip
+type PolishOptions struct {
+	// EBU R128 loudness normalization
+	NormalizeLoudness bool    `json:"normalizeLoudness"`
+	TargetLoudness    float64 `json:"targetLoudness"` // Integrated loudness in LUFS, 0 uses -16
+
+	// Fade durations in seconds, 0 disables the fade
+	AudioFadeIn  float64 `json:"audioFadeIn"`
+	AudioFadeOut float64 `json:"audioFadeOut"`
+	VideoFadeIn  float64 `json:"videoFadeIn"`
+	VideoFadeOut float64 `json:"videoFadeOut"`
+
+	Watermark WatermarkOptions `json:"watermark"`
+
+	// Bumper videos played before and after the clip, scaled to the clip's frame
+	IntroPath string `json:"introPath,omitempty"`
+	OutroPath string `json:"outroPath,omitempty"`
+}
+
+// WatermarkOptions describes a text or image watermark
+type WatermarkOptions struct {
+	Text      string  `json:"text,omitempty"`
+	ImagePath string  `json:"imagePath,omitempty"` // Used instead of Text when set
+	Position  string  `json:"position"`            // top-left, top-right, bottom-left, bottom-right or center
+	Opacity   float64 `json:"opacity"`             // 0-1, 0 uses 0.7
+	Scale     float64 `json:"scale"`               // Image width or text height as a fraction of the frame, 0 uses a default
+}
+
+const (
+	defaultTargetLoudness   = -16.0
+	defaultWatermarkOpacity = 0.7
+	defaultWatermarkScale   = 0.15
+	defaultWatermarkText    = 0.04
+	watermarkMargin         = 20
+	polishAudioSampleRate   = "48000"
+	polishAudioChannelSpec  = "stereo"
+)
+
+// hasAudioFilters reports whether the options change the clip's audio
+func (p PolishOptions) hasAudioFilters() bool {
+	return p.NormalizeLoudness || p.AudioFadeIn > 0 || p.AudioFadeOut > 0
+}
+
+// hasBumpers reports whether an intro or outro is set
+func (p PolishOptions) hasBumpers() bool {
+	return p.IntroPath != "" || p.OutroPath != ""
+}
+
+// hasWatermark reports whether a watermark is set
+func (w WatermarkOptions) hasWatermark() bool {
+	return strings.TrimSpace(w.Text) != "" || w.ImagePath != ""
+}
+
+// addWatermarkFilter draws the watermark over the video and returns the new label
+func addWatermarkFilter(graph *filterGraph, video string, watermark WatermarkOptions, frameWidth int, frameHeight int, workDir string) (string, error) {
+	opacity := watermark.Opacity
+	if opacity <= 0 || opacity > 1 {
+		opacity = defaultWatermarkOpacity
+	}
+
+	if watermark.ImagePath != "" {
+		// ffmpeg runs in the work directory, so a relative path must be resolved first
+		imagePath, err := filepath.Abs(watermark.ImagePath)
+		if err != nil {
+			return "", fmt.Errorf("invalid watermark image path: %v", err)
+		}
+		if _, err := os.Stat(imagePath); err != nil {
+			return "", fmt.Errorf("watermark image not found: %v", err)
+		}
+		scale := watermark.Scale
+		if scale <= 0 || scale > 1 {
+			scale = defaultWatermarkScale
+		}
+		input := graph.addInput("-i", imagePath)
+		logo := graph.chain(fmt.Sprintf("[%d:v]", input), fmt.Sprintf("scale=%d:-1,format=rgba,colorchannelmixer=aa=%.2f",
+			evenDimension(int(float64(frameWidth)*scale)), opacity))
+		x, y := overlayPosition(watermark.Position, "W", "H", "w", "h")
+		return graph.chain(video+logo, fmt.Sprintf("overlay=%s:%s", x, y)), nil
+	}
+
+	// The text is read from a file with expansion off, so quotes, % and \ are drawn as typed
+	if err := os.WriteFile(filepath.Join(workDir, "watermark.txt"), []byte(watermark.Text), 0644); err != nil {
+		return "", err
+	}
+	scale := watermark.Scale
+	if scale <= 0 || scale > 1 {
+		scale = defaultWatermarkText
+	}
+	fontSize := int(float64(frameHeight) * scale)
+	if fontSize < 10 {
+		fontSize = 10
+	}
+	x, y := overlayPosition(watermark.Position, "w", "h", "text_w", "text_h")
+	return graph.chain(video, fmt.Sprintf("drawtext=%stextfile=watermark.txt:expansion=none:fontcolor=white@%.2f:fontsize=%d:borderw=2:bordercolor=black@%.2f:x=%s:y=%s",
+		drawtextFontOption(), opacity, fontSize, opacity/2, x, y)), nil
+}
+
+// addVideoFades adds fade in/out to the video and returns the new label
+func addVideoFades(graph *filterGraph, video string, polish PolishOptions, duration float64) string {
+	var fades []string
+	if polish.VideoFadeIn > 0 {
+		fades = append(fades, fmt.Sprintf("fade=t=in:st=0:d=%.3f", minFloat(polish.VideoFadeIn, duration)))
+	}
+	if polish.VideoFadeOut > 0 {
+		fadeOut := minFloat(polish.VideoFadeOut, duration)
+		fades = append(fades, fmt.Sprintf("fade=t=out:st=%.3f:d=%.3f", duration-fadeOut, fadeOut))
+	}
+	if len(fades) == 0 {
+		return video
+	}
+	return graph.chain(video, strings.Join(fades, ","))
+}
+
+// addAudioPolish adds loudness normalization and fades to the audio and returns the new label
+func addAudioPolish(graph *filterGraph, audio string, polish PolishOptions, duration float64) string {
+	var filters []string
+	if polish.NormalizeLoudness {
+		target := polish.TargetLoudness
+		if target >= 0 || target < -70 {
+			target = defaultTargetLoudness
+		}
+		// loudnorm resamples to 192 kHz internally, so set the rate back afterwards
+		filters = append(filters, fmt.Sprintf("loudnorm=I=%.1f:TP=-1.5:LRA=11", target), "aresample="+polishAudioSampleRate)
+	}
+	if polish.AudioFadeIn > 0 {
+		filters = append(filters, fmt.Sprintf("afade=t=in:st=0:d=%.3f", minFloat(polish.AudioFadeIn, duration)))
+	}
+	if polish.AudioFadeOut > 0 {
+		fadeOut := minFloat(polish.AudioFadeOut, duration)
+		filters = append(filters, fmt.Sprintf("afade=t=out:st=%.3f:d=%.3f", duration-fadeOut, fadeOut))
+	}
+	if len(filters) == 0 {
+		return audio
+	}
+	return graph.chain(audio, strings.Join(filters, ","))
+}
+
+// addBumpers joins the intro and outro around the clip. Bumpers are scaled and
+// padded to the clip's frame and given silent audio if they have none.
+func addBumpers(graph *filterGraph, video string, audio string, polish PolishOptions, frameWidth int, frameHeight int) (string, string, error) {
+	var videoParts, audioParts []string
+
+	addBumper := func(path string) error {
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("invalid bumper path: %v", err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("bumper not found: %s", path)
+		}
+		input := graph.addInput("-i", path)
+		videoParts = append(videoParts, graph.chain(fmt.Sprintf("[%d:v]", input),
+			fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,format=yuv420p",
+				frameWidth, frameHeight, frameWidth, frameHeight)))
+
+		media, err := probeMedia(path)
+		if err != nil {
+			return fmt.Errorf("failed to read bumper: %v", err)
+		}
+		if media.HasAudio() {
+			audioParts = append(audioParts, graph.chain(fmt.Sprintf("[%d:a:0]", input), normalizedAudioFilter()))
+			return nil
+		}
+		silence := graph.addInput("-f", "lavfi", "-t", formatFFmpegTime(media.Duration),
+			"-i", "anullsrc=r="+polishAudioSampleRate+":cl="+polishAudioChannelSpec)
+		audioParts = append(audioParts, fmt.Sprintf("[%d:a]", silence))
+		return nil
+	}
+
+	if polish.IntroPath != "" {
+		if err := addBumper(polish.IntroPath); err != nil {
+			return "", "", err
+		}
+	}
+
+	videoParts = append(videoParts, graph.chain(video, "setsar=1,format=yuv420p"))
+	audioParts = append(audioParts, graph.chain(audio, normalizedAudioFilter()))
+
+	if polish.OutroPath != "" {
+		if err := addBumper(polish.OutroPath); err != nil {
+			return "", "", err
+		}
+	}
+
+	// concat expects the streams of each segment interleaved: v0 a0 v1 a1 ...
+	var inputs strings.Builder
+	for i := range videoParts {
+		inputs.WriteString(videoParts[i] + audioParts[i])
+	}
+	joined := graph.chainOutputs(inputs.String(), fmt.Sprintf("concat=n=%d:v=1:a=1", len(videoParts)), 2)
+
+	return joined[0], joined[1], nil
+}
+
+// normalizedAudioFilter converts audio to the format shared by every concat segment
+func normalizedAudioFilter() string {
+	return "aresample=" + polishAudioSampleRate + ",aformat=sample_fmts=fltp:channel_layouts=" + polishAudioChannelSpec
+}
+
+// overlayPosition returns x and y expressions for a corner position. The
+// frame and item sizes are passed as expression names because overlay and
+// drawtext use different ones.
+func overlayPosition(position string, frameWidth string, frameHeight string, itemWidth string, itemHeight string) (string, string) {
+	margin := fmt.Sprint(watermarkMargin)
+	left, right := margin, frameWidth+"-"+itemWidth+"-"+margin
+	top, bottom := margin, frameHeight+"-"+itemHeight+"-"+margin
+
+	switch position {
+	case "top-left":
+		return left, top
+	case "top-right":
+		return right, top
+	case "bottom-left":
+		return left, bottom
+	case "center":
+		return "(" + frameWidth + "-" + itemWidth + ")/2", "(" + frameHeight + "-" + itemHeight + ")/2"
+	default:
+		return right, bottom
+	}
+}
+
+// minFloat returns the smaller of a and b
+func minFloat(a float64, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ClipPreset is a named set of clip options, including the polish filters
+type ClipPreset struct {
+	Name    string      `json:"name"`
+	Options ClipOptions `json:"options"`
+}
+
+const clipPresetsFileName = "clip_presets.json"
+
+// GetClipPresets returns the saved clip presets sorted by name
+func (s *ClipService) GetClipPresets() []ClipPreset {
+	s.libraryMu.Lock()
+	defer s.libraryMu.Unlock()
+
+	presets := s.loadClipPresets()
+	sort.Slice(presets, func(i, j int) bool {
+		return strings.ToLower(presets[i].Name) < strings.ToLower(presets[j].Name)
+	})
+	return presets
+}
+
+// SaveClipPreset saves a preset, replacing any preset with the same name
+func (s *ClipService) SaveClipPreset(preset ClipPreset) error {
+	preset.Name = strings.TrimSpace(preset.Name)
+	if preset.Name == "" {
+		return errors.New("preset name is required")
+	}
+	preset.Options.Preset = preset.Name
+
+	s.libraryMu.Lock()
+	defer s.libraryMu.Unlock()
+
+	presets := s.loadClipPresets()
+	replaced := false
+	for i := range presets {
+		if strings.EqualFold(presets[i].Name, preset.Name) {
+			presets[i] = preset
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		presets = append(presets, preset)
+	}
+	return s.saveClipPresets(presets)
+}
+
+// DeleteClipPreset removes the preset with the given name
+func (s *ClipService) DeleteClipPreset(name string) error {
+	s.libraryMu.Lock()
+	defer s.libraryMu.Unlock()
+
+	presets := s.loadClipPresets()
+	kept := presets[:0]
+	for _, preset := range presets {
+		if !strings.EqualFold(preset.Name, name) {
+			kept = append(kept, preset)
+		}
+	}
+	if len(kept) == len(presets) {
+		return errors.New("preset not found")
+	}
+	return s.saveClipPresets(kept)
+}
+
+// loadClipPresets reads the saved presets. Callers must hold libraryMu.
+func (s *ClipService) loadClipPresets() []ClipPreset {
+	presets := []ClipPreset{}
+	data, err := os.ReadFile(filepath.Join(s.appDataDir, clipPresetsFileName))
+	if err != nil {
+		return presets
+	}
+	if err := json.Unmarshal(data, &presets); err != nil {
+		return []ClipPreset{}
+	}
+	return presets
+}
+
+// saveClipPresets writes the presets. Callers must hold libraryMu.
+func (s *ClipService) saveClipPresets(presets []ClipPreset) error {
+	data, err := json.MarshalIndent(presets, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.appDataDir, clipPresetsFileName), data, 0644)
+}
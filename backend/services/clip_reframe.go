@@ -0,0 +1,229 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// CropMode selects how a clip is reframed
+type CropMode string
+
+const (
+	// CropNone keeps the original frame
+	CropNone CropMode = ""
+	// CropRect crops a custom rectangle of the source
+	CropRect CropMode = "rect"
+	// CropAspect crops a preset aspect ratio around a movable focus point
+	CropAspect CropMode = "aspect"
+)
+
+// CropOptions controls cropping and reframing of a clip, e.g. for vertical 9:16 exports
+type CropOptions struct {
+	Mode CropMode `json:"mode"`
+
+	// Custom rectangle in source pixels, used by CropRect
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// Aspect ratio such as "9:16", "1:1" or "4:5", used by CropAspect
+	Aspect string `json:"aspect"`
+	// Centre of the crop area as a fraction of the source frame (0-1), 0.5 is centred
+	FocusX float64 `json:"focusX"`
+	FocusY float64 `json:"focusY"`
+
+	// Fit the whole crop area (or the whole frame for CropAspect) over a blurred copy instead of cutting it off
+	BlurFill bool `json:"blurFill"`
+	// Place the chat in a panel underneath the video instead of over it
+	StackChat bool `json:"stackChat"`
+	// Fraction of the output height used by the stacked chat panel
+	ChatHeight float64 `json:"chatHeight"`
+
+	// Output width in pixels, 0 keeps the source resolution
+	OutputWidth int `json:"outputWidth"`
+}
+
+const (
+	defaultStackedChatHeight = 0.35
+	reframeBlurStrength      = "20:5"
+)
+
+// reframeLayout is the geometry worked out for a reframed clip
+type reframeLayout struct {
+	outputWidth  int
+	outputHeight int
+	// Area the video is drawn into, at the top of the output
+	videoWidth  int
+	videoHeight int
+	// Crop rectangle in source pixels
+	cropX      int
+	cropY      int
+	cropWidth  int
+	cropHeight int
+}
+
+// computeReframeLayout validates the crop options and works out the output geometry
+func computeReframeLayout(crop CropOptions, sourceWidth int, sourceHeight int) (reframeLayout, error) {
+	var layout reframeLayout
+
+	// Aspect ratio of the whole output frame
+	var aspect float64
+	switch crop.Mode {
+	case CropRect:
+		if crop.Width <= 0 || crop.Height <= 0 {
+			return layout, fmt.Errorf("crop rectangle must have a positive size")
+		}
+		if crop.X < 0 || crop.Y < 0 || crop.X+crop.Width > sourceWidth || crop.Y+crop.Height > sourceHeight {
+			return layout, fmt.Errorf("crop rectangle is outside the %dx%d video", sourceWidth, sourceHeight)
+		}
+		aspect = float64(crop.Width) / float64(crop.Height)
+	case CropAspect:
+		parsed, err := parseAspectRatio(crop.Aspect)
+		if err != nil {
+			return layout, err
+		}
+		aspect = parsed
+	default:
+		return layout, fmt.Errorf("unknown crop mode: %s", crop.Mode)
+	}
+
+	// Output size, defaulting to the source height for preset ratios and the
+	// rectangle's own size for custom crops
+	if crop.OutputWidth > 0 {
+		layout.outputWidth = crop.OutputWidth
+		layout.outputHeight = int(math.Round(float64(crop.OutputWidth) / aspect))
+	} else if crop.Mode == CropRect {
+		layout.outputWidth, layout.outputHeight = crop.Width, crop.Height
+	} else {
+		layout.outputHeight = sourceHeight
+		layout.outputWidth = int(math.Round(float64(sourceHeight) * aspect))
+	}
+	layout.outputWidth = evenDimension(layout.outputWidth)
+	layout.outputHeight = evenDimension(layout.outputHeight)
+
+	// The stacked chat panel takes the bottom of the frame
+	layout.videoWidth, layout.videoHeight = layout.outputWidth, layout.outputHeight
+	if crop.StackChat {
+		chatHeight := crop.ChatHeight
+		if chatHeight <= 0 || chatHeight >= 1 {
+			chatHeight = defaultStackedChatHeight
+		}
+		layout.videoHeight = evenDimension(int(float64(layout.outputHeight) * (1 - chatHeight)))
+	}
+
+	if crop.Mode == CropRect {
+		layout.cropX, layout.cropY = crop.X, crop.Y
+		layout.cropWidth, layout.cropHeight = crop.Width, crop.Height
+		return layout, nil
+	}
+
+	// Largest rectangle with the video area's aspect ratio that fits the source
+	areaAspect := float64(layout.videoWidth) / float64(layout.videoHeight)
+	if float64(sourceWidth)/float64(sourceHeight) > areaAspect {
+		layout.cropHeight = sourceHeight
+		layout.cropWidth = int(float64(sourceHeight) * areaAspect)
+	} else {
+		layout.cropWidth = sourceWidth
+		layout.cropHeight = int(float64(sourceWidth) / areaAspect)
+	}
+	layout.cropWidth = evenDimension(layout.cropWidth)
+	layout.cropHeight = evenDimension(layout.cropHeight)
+
+	// Centre it on the focus point without leaving the frame
+	focusX, focusY := crop.FocusX, crop.FocusY
+	if focusX <= 0 || focusX > 1 {
+		focusX = 0.5
+	}
+	if focusY <= 0 || focusY > 1 {
+		focusY = 0.5
+	}
+	layout.cropX = clampInt(int(focusX*float64(sourceWidth))-layout.cropWidth/2, 0, sourceWidth-layout.cropWidth)
+	layout.cropY = clampInt(int(focusY*float64(sourceHeight))-layout.cropHeight/2, 0, sourceHeight-layout.cropHeight)
+
+	return layout, nil
+}
+
+// addReframeFilters adds the crop, blur fill and stacking filters to graph and
+// returns the label of the reframed video
+func addReframeFilters(graph *filterGraph, input string, crop CropOptions, layout reframeLayout) string {
+	var video string
+
+	if crop.BlurFill {
+		// The foreground keeps everything the user asked for and is fitted inside
+		// the frame, the blurred copy fills the bars around it
+		copies := graph.split(input, 2)
+		foreground, background := copies[0], copies[1]
+
+		foregroundFilters := ""
+		if crop.Mode == CropRect {
+			foregroundFilters = fmt.Sprintf("crop=%d:%d:%d:%d,", layout.cropWidth, layout.cropHeight, layout.cropX, layout.cropY)
+		}
+		foregroundFilters += fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease", layout.videoWidth, layout.videoHeight)
+		fitted := graph.chain(foreground, foregroundFilters)
+
+		blurred := graph.chain(background, fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=increase,crop=%d:%d,boxblur=%s",
+			layout.videoWidth, layout.videoHeight, layout.videoWidth, layout.videoHeight, reframeBlurStrength))
+		video = graph.chain(blurred+fitted, "overlay=(W-w)/2:(H-h)/2,setsar=1")
+	} else {
+		video = graph.chain(input, fmt.Sprintf("crop=%d:%d:%d:%d,scale=%d:%d,setsar=1",
+			layout.cropWidth, layout.cropHeight, layout.cropX, layout.cropY, layout.videoWidth, layout.videoHeight))
+	}
+
+	if layout.videoHeight < layout.outputHeight {
+		video = graph.chain(video, fmt.Sprintf("pad=%d:%d:0:0:color=black", layout.outputWidth, layout.outputHeight))
+	}
+
+	return video
+}
+
+// stackedChatOptions adapts the chat overlay settings to the panel under a stacked video
+func stackedChatOptions(options ChatOverlayOptions, layout reframeLayout) ChatOverlayOptions {
+	options = options.withDefaults(layout.outputHeight)
+	options.Position = "bottom-left"
+	options.Width = 1
+	panelHeight := layout.outputHeight - layout.videoHeight - 2*overlayMargin
+	// Roughly 1.5 lines of text per message including the box padding
+	if fit := panelHeight * 2 / (options.FontSize * 3); fit > 0 && fit < options.MaxMessages {
+		options.MaxMessages = fit
+	}
+	return options
+}
+
+// parseAspectRatio parses ratios like "9:16" or "16/9"
+func parseAspectRatio(aspect string) (float64, error) {
+	parts := strings.FieldsFunc(aspect, func(r rune) bool { return r == ':' || r == '/' || r == 'x' })
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid aspect ratio: %q", aspect)
+	}
+	width, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil || width <= 0 {
+		return 0, fmt.Errorf("invalid aspect ratio: %q", aspect)
+	}
+	height, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil || height <= 0 {
+		return 0, fmt.Errorf("invalid aspect ratio: %q", aspect)
+	}
+	return width / height, nil
+}
+
+// evenDimension rounds a dimension down to an even number as required by yuv420p
+func evenDimension(value int) int {
+	if value < 2 {
+		return 2
+	}
+	return value - value%2
+}
+
+// clampInt limits value to the range [min, max]
+func clampInt(value int, min int, max int) int {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
@@ -6,7 +6,10 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
+
+	"FanslyArchivePlayer/backend/models"
 )
 
 // ClipStorageOption defines where clips should be stored
@@ -26,10 +29,19 @@ type ClipService struct {
 	appDataDir      string
 	defaultOption   ClipStorageOption
 	customOutputDir string
+	cacheService    *CacheService
+	naming          ClipNamingOptions
+	libraryMu       sync.Mutex
+	thumbnailMu     sync.Mutex
+	thumbnailQueue  []string
+	thumbnailQueued map[string]bool
+	thumbnailFailed map[string]bool // Clips ffmpeg couldn't make a thumbnail of, not retried
+	thumbnailsBusy  bool
+	onThumbnail     func(ClipRecord)
 }
 
 // NewClipService creates a new clip service
-func NewClipService(appDataDir string) *ClipService {
+func NewClipService(appDataDir string, cacheService *CacheService) *ClipService {
 	// Create default clips directory in app data as fallback
 	clipsDir := filepath.Join(appDataDir, "clips")
 	if _, err := os.Stat(clipsDir); os.IsNotExist(err) {
@@ -37,8 +49,12 @@ func NewClipService(appDataDir string) *ClipService {
 	}
 
 	return &ClipService{
-		appDataDir:    appDataDir,
-		defaultOption: StoreInVideosDir, // Default to user's Videos directory
+		appDataDir:      appDataDir,
+		defaultOption:   StoreInVideosDir, // Default to user's Videos directory
+		cacheService:    cacheService,
+		naming:          loadNamingOptions(appDataDir),
+		thumbnailQueued: make(map[string]bool),
+		thumbnailFailed: make(map[string]bool),
 	}
 }
 
@@ -57,8 +73,33 @@ type ClipResult struct {
 	ErrorMessage string `json:"errorMessage,omitempty"`
 }
 
+// ClipSource describes the video a clip is cut from
+type ClipSource struct {
+	Path         string
+	Model        string
+	ChatMessages []models.ChatMessage // Chat loaded for the video, used for overlays and clip metadata
+	Parts        []RecordingPart      // Set for a multi-part recording, clip times are then on its timeline
+	SkipSegments []SkipSegment        // Dead air found in the video, kept out of clips picked automatically
+}
+
+// ClipOptions holds optional settings applied when creating a clip
+type ClipOptions struct {
+	Preset      string             `json:"preset,omitempty"` // Name of the preset the options came from
+	ChatOverlay ChatOverlayOptions `json:"chatOverlay"`
+	Crop        CropOptions        `json:"crop"`
+	Polish      PolishOptions      `json:"polish"`
+	Tracks      TrackSelection     `json:"tracks"` // Audio tracks to export, the default track if none are selected
+}
+
 // CreateClip creates a video clip from the source video
 func (s *ClipService) CreateClip(sourceVideoPath string, startTime float64, duration float64, title string) ClipResult {
+	return s.CreateClipWithOptions(ClipSource{Path: sourceVideoPath}, startTime, duration, title, ClipOptions{})
+}
+
+// CreateClipWithOptions creates a video clip from the source video, applying the given options
+func (s *ClipService) CreateClipWithOptions(source ClipSource, startTime float64, duration float64, title string, options ClipOptions) ClipResult {
+	sourceVideoPath := source.Path
+
 	// Validate inputs
 	if sourceVideoPath == "" {
 		return ClipResult{Success: false, ErrorMessage: "No source video provided"}
@@ -67,56 +108,239 @@ func (s *ClipService) CreateClip(sourceVideoPath string, startTime float64, dura
 		return ClipResult{Success: false, ErrorMessage: "Duration must be a positive number"}
 	}
 
-	// Create a filename based on title or timestamp if title is empty
-	filename := title
-	if filename == "" {
-		filename = fmt.Sprintf("clip_%s", time.Now().Format("20060102_150405"))
+	sourceVideoPath, err := filepath.Abs(sourceVideoPath)
+	if err != nil {
+		return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Invalid source path: %v", err)}
 	}
 
-	// Sanitize filename
-	filename = sanitizeFilename(filename)
+	// A clip of a multi-part recording may span several part files
+	ranges := source.inputRanges(sourceVideoPath, startTime, duration)
+	if len(ranges) == 0 {
+		return ClipResult{Success: false, ErrorMessage: "Clip range is outside the recording"}
+	}
+	sourceVideoPath = ranges[0].path
+	media, err := probeMedia(sourceVideoPath)
+	if err != nil {
+		return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Failed to read source video: %v", err)}
+	}
+	if err := options.Tracks.validate(media); err != nil {
+		return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Invalid track selection: %v", err)}
+	}
 
-	// Determine output directory based on storage option
-	outputDir, err := s.getOutputDirectory(sourceVideoPath)
+	// Scratch directory for generated filter inputs such as the chat overlay.
+	// ffmpeg runs inside it so filters can refer to those files by name.
+	workDir, err := os.MkdirTemp("", "archive-player-clip-*")
 	if err != nil {
-		return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Failed to create output directory: %v", err)}
+		return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Failed to create work directory: %v", err)}
+	}
+	defer os.RemoveAll(workDir)
+
+	graph := &filterGraph{sourceInputs: len(ranges)}
+	video := "[0:v]"
+	audio := ""
+	if len(ranges) > 1 {
+		// Parts of one recording share their video encoding, so they are
+		// joined before any other filter. Their audio may differ, e.g. when
+		// a part was recorded with the microphone off.
+		parts := []MediaInfo{media}
+		for _, inputRange := range ranges[1:] {
+			partMedia, err := probeMedia(inputRange.path)
+			if err != nil {
+				return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Failed to read recording part: %v", err)}
+			}
+			parts = append(parts, partMedia)
+		}
+		video, audio = joinClipInputs(graph, ranges, parts, options.Tracks)
+	} else if options.Tracks.selected() {
+		audio = selectClipAudio(graph, 0, options.Tracks)
 	}
 
-	outputPath := filepath.Join(outputDir, filename+".mp4")
+	// The output frame size is needed to lay out the chat overlay, watermark and bumpers
+	polish := options.Polish
+	needsFrameSize := options.Crop.Mode != CropNone || options.ChatOverlay.Enabled ||
+		polish.Watermark.hasWatermark() || polish.hasBumpers()
+	frameWidth, frameHeight := 0, 0
+	if needsFrameSize {
+		frameWidth, frameHeight, err = media.VideoSize()
+		if err != nil {
+			return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Failed to read video size: %v", err)}
+		}
+	}
+
+	// Crop / reframe, e.g. to a vertical 9:16 frame
+	chatOptions := options.ChatOverlay
+	if options.Crop.Mode != CropNone {
+		layout, err := computeReframeLayout(options.Crop, frameWidth, frameHeight)
+		if err != nil {
+			return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Invalid crop: %v", err)}
+		}
+		video = addReframeFilters(graph, video, options.Crop, layout)
+		frameWidth, frameHeight = layout.outputWidth, layout.outputHeight
 
-	// Format start time for ffmpeg (convert seconds to HH:MM:SS.mmm format)
-	startTimeStr := formatFFmpegTime(startTime)
+		// The stacked layout always shows the chat, in the panel under the video
+		if options.Crop.StackChat {
+			chatOptions = stackedChatOptions(chatOptions, layout)
+			chatOptions.Enabled = true
+		}
+	}
+
+	// Render the chat for the clip range as burned-in subtitles
+	if chatOptions.Enabled {
+		clipMessages := selectClipMessages(source.ChatMessages, startTime, duration)
+		if len(clipMessages) > 0 {
+			script := buildChatOverlayASS(clipMessages, duration, frameWidth, frameHeight, chatOptions)
+			if err := os.WriteFile(filepath.Join(workDir, "chat.ass"), []byte(script), 0644); err != nil {
+				return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Failed to write chat overlay: %v", err)}
+			}
+			video = graph.chain(video, "subtitles=chat.ass")
+		}
+	}
+
+	if polish.Watermark.hasWatermark() {
+		video, err = addWatermarkFilter(graph, video, polish.Watermark, frameWidth, frameHeight, workDir)
+		if err != nil {
+			return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Failed to add watermark: %v", err)}
+		}
+	}
+	video = addVideoFades(graph, video, polish, duration)
+
+	// Format times for ffmpeg (convert seconds to HH:MM:SS.mmm format). The
+	// range is set on the input so it doesn't apply to watermark or bumper inputs.
 	durationStr := formatFFmpegTime(duration)
+	var args []string
+	for _, inputRange := range ranges {
+		args = append(args,
+			"-ss", formatFFmpegTime(inputRange.start),
+			"-t", formatFFmpegTime(inputRange.duration),
+			"-i", inputRange.path,
+		)
+	}
 
-	// Create the clip using ffmpeg
-	cmd := exec.Command(
-		"ffmpeg",
-		"-ss", startTimeStr,
-		"-i", sourceVideoPath,
-		"-t", durationStr,
+	if polish.hasAudioFilters() || polish.hasBumpers() {
+		if audio == "" && len(ranges) == 1 && media.HasAudio() {
+			audio = "[0:a:0]"
+		}
+		if audio == "" && polish.hasBumpers() {
+			// Bumpers are joined with concat, which needs audio in every part
+			silence := graph.addInput("-f", "lavfi", "-t", durationStr,
+				"-i", "anullsrc=r="+polishAudioSampleRate+":cl="+polishAudioChannelSpec)
+			audio = fmt.Sprintf("[%d:a]", silence)
+		}
+		if audio != "" {
+			audio = addAudioPolish(graph, audio, polish, duration)
+		}
+		if polish.hasBumpers() {
+			video, audio, err = addBumpers(graph, video, audio, polish, frameWidth, frameHeight)
+			if err != nil {
+				return ClipResult{Success: false, ErrorMessage: fmt.Sprintf("Failed to add bumpers: %v", err)}
+			}
+		}
+	}
+
+	if !graph.empty() {
+		args = append(args, graph.inputArgs()...)
+		args = append(args, "-filter_complex", graph.String())
+	}
+	if !graph.empty() || audio != "" {
+		args = append(args, "-map", streamMapArg(video))
+		if audio != "" {
+			args = append(args, "-map", streamMapArg(audio))
+		} else {
+			args = append(args, "-map", "0:a:0?")
+		}
+	}
+
+	outputPath, err := s.clipOutputPath(clipName{
+		model:     source.Model,
+		videoPath: sourceVideoPath,
+		startTime: startTime,
+		duration:  duration,
+		title:     title,
+		preset:    options.Preset,
+	}, ".mp4")
+	if err != nil {
+		return ClipResult{Success: false, ErrorMessage: err.Error()}
+	}
+
+	args = append(args,
 		"-c:v", "libx264",
 		"-c:a", "aac",
 		"-strict", "experimental",
 		"-b:a", "128k",
-		"-y", // Overwrite output file if it exists
+		"-y", // Only overwrites the placeholder reserved for this clip
 		outputPath,
 	)
 
+	// Create the clip using ffmpeg
+	cmd := exec.Command("ffmpeg", args...)
+	cmd.Dir = workDir
+
 	// Run the command
 	output, err := cmd.CombinedOutput()
 	if err != nil {
+		os.Remove(outputPath)
 		return ClipResult{
 			Success:      false,
 			ErrorMessage: fmt.Sprintf("FFmpeg error: %v\nOutput: %s", err, string(output)),
 		}
 	}
 
+	s.recordClip(outputPath, source, startTime, duration, title, options.Preset)
+
 	return ClipResult{
 		Success:  true,
 		FilePath: outputPath,
 	}
 }
 
+// clipOutputPath reserves the absolute output path for a new clip, named with
+// the naming template and placed in the configured clips directory. The
+// reserved file is an empty placeholder that ffmpeg overwrites, so callers
+// must remove it if the clip can't be created.
+func (s *ClipService) clipOutputPath(name clipName, ext string) (string, error) {
+	outputDir, err := s.clipOutputDir(name)
+	if err != nil {
+		return "", err
+	}
+	return reserveClipPath(outputDir, s.renderClipName(name), ext)
+}
+
+// frameOutputPath reserves the absolute output path for a frame grab. Grabs
+// go in a frames folder inside the clips directory so the directory scan
+// doesn't take still images for clips.
+func (s *ClipService) frameOutputPath(name clipName, ext string) (string, error) {
+	outputDir, err := s.clipOutputDir(name)
+	if err != nil {
+		return "", err
+	}
+	outputDir = filepath.Join(outputDir, framesFolderName)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create frames folder: %v", err)
+	}
+	return reserveClipPath(outputDir, s.renderClipName(name), ext)
+}
+
+// clipOutputDir returns the absolute directory a new clip is saved in
+func (s *ClipService) clipOutputDir(name clipName) (string, error) {
+	// Determine output directory based on storage option
+	outputDir, err := s.getOutputDirectory(name.videoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create output directory: %v", err)
+	}
+	if s.naming.ModelSubfolders && strings.TrimSpace(name.model) != "" {
+		outputDir = filepath.Join(outputDir, sanitizeFilename(name.model))
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create model folder: %v", err)
+		}
+	}
+
+	outputDir, err = filepath.Abs(outputDir)
+	if err != nil {
+		return "", fmt.Errorf("invalid output path: %v", err)
+	}
+	return outputDir, nil
+}
+
 // getOutputDirectory determines where to save the clip based on the storage option
 func (s *ClipService) getOutputDirectory(sourceVideoPath string) (string, error) {
 	var outputDir string
@@ -175,8 +399,8 @@ func (s *ClipService) getOutputDirectory(sourceVideoPath string) (string, error)
 	return outputDir, nil
 }
 
-// GetClips returns a list of all saved clips
-func (s *ClipService) GetClips() []string {
+// GetClips returns all saved clips with their sidecar metadata
+func (s *ClipService) GetClips() []ClipRecord {
 	var allClips []string
 
 	// Get clips from all possible locations
@@ -208,7 +432,10 @@ func (s *ClipService) GetClips() []string {
 		allClips = append(allClips, customClips...)
 	}
 
-	return allClips
+	// 4. Clips recorded in the library index, e.g. ones saved next to their source video
+	allClips = append(allClips, s.indexedClips()...)
+
+	return s.buildClipRecords(allClips)
 }
 
 // getClipsFromDir gets all video clips from a specific directory
@@ -227,7 +454,8 @@ func (s *ClipService) getClipsFromDir(dir string) []string {
 	for _, file := range files {
 		if !file.IsDir() {
 			ext := strings.ToLower(filepath.Ext(file.Name()))
-			if ext == ".mp4" || ext == ".webm" || ext == ".mov" {
+			if ext == ".mp4" || ext == ".webm" || ext == ".mov" ||
+				ext == ".gif" || ext == ".webp" || ext == ".apng" {
 				clips = append(clips, filepath.Join(dir, file.Name()))
 			}
 		}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ContactSheetLocation is where a generated contact sheet is saved
+type ContactSheetLocation string
+
+const (
+	// ContactSheetNextToVideo saves <video>_contact_sheet.jpg next to the video,
+	// where fansly-scraper puts its contact sheets
+	ContactSheetNextToVideo ContactSheetLocation = "video"
+	// ContactSheetInCache saves the sheet in the app cache, keyed by the video hash
+	ContactSheetInCache ContactSheetLocation = "cache"
+)
+
+// ContactSheetOptions controls the layout of a generated contact sheet
+type ContactSheetOptions struct {
+	Columns   int                  `json:"columns"`
+	Rows      int                  `json:"rows"`
+	TileWidth int                  `json:"tileWidth"` // Width of each tile in pixels
+	Location  ContactSheetLocation `json:"location"`
+}
+
+// ContactSheetProgress reports the progress of a library contact sheet pass
+type ContactSheetProgress struct {
+	Index     int    `json:"index"` // 1-based position in the pass
+	Total     int    `json:"total"`
+	VideoPath string `json:"videoPath"`
+	SheetPath string `json:"sheetPath,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Done      bool   `json:"done"` // Set on the last event of the pass
+}
+
+// ContactSheetService generates contact sheets for videos that don't have one
+type ContactSheetService struct {
+	cacheService *CacheService
+	mu           sync.Mutex
+	running      bool
+}
+
+const (
+	contactSheetSuffix      = "_contact_sheet.jpg"
+	contactSheetsDirName    = "contact_sheets"
+	defaultSheetColumns     = 4
+	defaultSheetRows        = 4
+	defaultSheetTileWidth   = 320
+	contactSheetBackground  = "0x1a1a1a"
+	contactSheetTilePadding = 6
+)
+
+// NewContactSheetService creates a new contact sheet service
+func NewContactSheetService(cacheService *CacheService) *ContactSheetService {
+	return &ContactSheetService{cacheService: cacheService}
+}
+
+// FindContactSheet returns the contact sheet of a video, next to the video or
+// in the app cache, or an empty string if it has none
+func FindContactSheet(cacheService *CacheService, videoPath string) string {
+	sheetPath := strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + contactSheetSuffix
+	if _, err := os.Stat(sheetPath); err == nil {
+		return sheetPath
+	}
+
+	// Only hash the video if sheets have been generated into the cache at all
+	cacheDir := filepath.Join(cacheService.cacheDir, contactSheetsDirName)
+	if _, err := os.Stat(cacheDir); err != nil {
+		return ""
+	}
+	hash, err := cacheService.GetFileHash(videoPath)
+	if err != nil {
+		return ""
+	}
+	sheetPath = filepath.Join(cacheDir, hash+".jpg")
+	if _, err := os.Stat(sheetPath); err == nil {
+		return sheetPath
+	}
+	return ""
+}
+
+// GenerateContactSheet draws a grid of frames spread over the video, each
+// with its timestamp, under a header with the file name, duration and
+// resolution. It returns the path of the sheet.
+func (s *ContactSheetService) GenerateContactSheet(videoPath string, options ContactSheetOptions) (string, error) {
+	if options.Columns <= 0 {
+		options.Columns = defaultSheetColumns
+	}
+	if options.Rows <= 0 {
+		options.Rows = defaultSheetRows
+	}
+	if options.TileWidth <= 0 {
+		options.TileWidth = defaultSheetTileWidth
+	}
+
+	sheetPath, err := s.contactSheetPath(videoPath, options.Location)
+	if err != nil {
+		return "", err
+	}
+	media, err := s.cacheService.GetMediaInfo(videoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read video: %v", err)
+	}
+	duration := media.Duration
+	width, height, err := media.VideoSize()
+	if err != nil {
+		return "", fmt.Errorf("failed to read video size: %v", err)
+	}
+	fileInfo, err := os.Stat(videoPath)
+	if err != nil {
+		return "", err
+	}
+
+	workDir, err := os.MkdirTemp("", "archive-player-sheet-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create work directory: %v", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	header := fmt.Sprintf("%s\nDuration %s   Resolution %dx%d   Size %.2f GB",
+		filepath.Base(videoPath), formatTimeText(duration), width, height, float64(fileInfo.Size())/(1<<30))
+	if err := os.WriteFile(filepath.Join(workDir, "header.txt"), []byte(header), 0644); err != nil {
+		return "", fmt.Errorf("failed to write header text: %v", err)
+	}
+
+	// Frames are taken from the middle of equal sections of the video, and
+	// only keyframes are decoded so long streams are quick to sample
+	interval := duration / float64(options.Columns*options.Rows)
+	sheetWidth := options.Columns*(options.TileWidth+contactSheetTilePadding) + contactSheetTilePadding
+	headerFontSize := max(16, sheetWidth/60)
+	headerHeight := headerFontSize*3 + contactSheetTilePadding
+	tileFontSize := max(12, options.TileWidth/16)
+	font := drawtextFontOption()
+
+	filters := []string{
+		fmt.Sprintf("select='isnan(prev_selected_t)+gte(t-prev_selected_t,%.3f)'", interval*0.98),
+		fmt.Sprintf("scale=%d:-2", options.TileWidth),
+		fmt.Sprintf("drawtext=%stext='%%{pts\\:hms}':x=w-tw-8:y=h-th-8:fontsize=%d:fontcolor=white:box=1:boxcolor=black@0.6:boxborderw=4",
+			font, tileFontSize),
+		fmt.Sprintf("tile=%dx%d:padding=%d:margin=%d:color=%s",
+			options.Columns, options.Rows, contactSheetTilePadding, contactSheetTilePadding, contactSheetBackground),
+		fmt.Sprintf("pad=iw:ih+%d:0:%d:color=%s", headerHeight, headerHeight, contactSheetBackground),
+		fmt.Sprintf("drawtext=%stextfile=header.txt:expansion=none:x=%d:y=%d:fontsize=%d:fontcolor=white:line_spacing=%d",
+			font, contactSheetTilePadding*2, contactSheetTilePadding*2, headerFontSize, headerFontSize/2),
+	}
+
+	// Written under a temporary name so a failed run doesn't leave a broken sheet behind
+	tempPath := strings.TrimSuffix(sheetPath, ".jpg") + ".tmp.jpg"
+	err = runFFmpeg(workDir, []string{
+		"-skip_frame", "nokey",
+		"-ss", formatFFmpegTime(interval / 2),
+		"-copyts",
+		"-i", videoPath,
+		"-vf", strings.Join(filters, ","),
+		"-an",
+		"-frames:v", "1",
+		"-update", "1",
+		"-q:v", "3",
+		"-y", tempPath,
+	})
+	if err != nil {
+		os.Remove(tempPath)
+		return "", err
+	}
+	if err := os.Rename(tempPath, sheetPath); err != nil {
+		os.Remove(tempPath)
+		return "", err
+	}
+	return sheetPath, nil
+}
+
+// GenerateMissingContactSheets generates a contact sheet for each video that
+// doesn't have one. Only one pass runs at a time.
+func (s *ContactSheetService) GenerateMissingContactSheets(videoPaths []string, options ContactSheetOptions, progress func(ContactSheetProgress)) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return errors.New("contact sheets are already being generated")
+	}
+	s.running = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	if progress == nil {
+		progress = func(ContactSheetProgress) {}
+	}
+
+	var missing []string
+	for _, path := range videoPaths {
+		if FindContactSheet(s.cacheService, path) == "" {
+			missing = append(missing, path)
+		}
+	}
+
+	for i, path := range missing {
+		update := ContactSheetProgress{Index: i + 1, Total: len(missing), VideoPath: path}
+		sheetPath, err := s.GenerateContactSheet(path, options)
+		if err != nil {
+			fmt.Printf("Failed to generate contact sheet for %s: %v\n", path, err)
+			update.Error = err.Error()
+		}
+		update.SheetPath = sheetPath
+		update.Done = i == len(missing)-1
+		progress(update)
+	}
+	if len(missing) == 0 {
+		progress(ContactSheetProgress{Done: true})
+	}
+	return nil
+}
+
+// contactSheetPath returns where the contact sheet of a video is saved
+func (s *ContactSheetService) contactSheetPath(videoPath string, location ContactSheetLocation) (string, error) {
+	if location != ContactSheetInCache {
+		return strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + contactSheetSuffix, nil
+	}
+
+	hash, err := s.cacheService.GetFileHash(videoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash video: %v", err)
+	}
+	cacheDir := filepath.Join(s.cacheService.cacheDir, contactSheetsDirName)
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, hash+".jpg"), nil
+}
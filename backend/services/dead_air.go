@@ -0,0 +1,380 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"FanslyArchivePlayer/backend/models"
+)
+
+// SkipSegment is a stretch of dead air, such as a "starting soon" screen or
+// an AFK break, that the player can offer to skip
+type SkipSegment struct {
+	Start      float64  `json:"start"`
+	End        float64  `json:"end"`
+	Kind       string   `json:"kind"`       // "intro", "break" or "outro"
+	Confidence float64  `json:"confidence"` // 0-1, higher when more signals agree
+	Signals    []string `json:"signals"`    // Signals present for most of the segment: silence, black, freeze, chat
+}
+
+// DeadAirAnalysis is the dead-air detection result of a video
+type DeadAirAnalysis struct {
+	VideoPath string        `json:"videoPath"`
+	Duration  float64       `json:"duration"`
+	Segments  []SkipSegment `json:"segments"`
+	Analyzed  bool          `json:"analyzed"`  // False until the video has been analysed
+	Analyzing bool          `json:"analyzing"` // Set while the analysis runs in the background
+}
+
+// deadAirSignals is what ffmpeg detected in a video, cached per video hash.
+// Chat is combined with it when segments are built, since chat can be
+// loaded separately.
+type deadAirSignals struct {
+	Duration   float64      `json:"duration"`
+	Silence    [][2]float64 `json:"silence"`
+	Black      [][2]float64 `json:"black"`
+	Freeze     [][2]float64 `json:"freeze"`
+	AnalyzedAt time.Time    `json:"analyzedAt"`
+}
+
+// DeadAirService detects dead air with ffmpeg's silencedetect, blackdetect
+// and freezedetect filters combined with chat inactivity
+type DeadAirService struct {
+	analysisDir  string
+	cacheService *CacheService
+	mu           sync.Mutex
+	analyzing    map[string]bool
+	onReady      func(DeadAirAnalysis)
+}
+
+const (
+	analysisDirName = "analysis"
+	// Signals shorter than this aren't reported by ffmpeg
+	minDeadAirSignal = 20
+	// Skip segments shorter than this aren't worth a skip button
+	minSkipSegment = 45.0
+	// Dead pieces closer than this are merged into one segment
+	skipSegmentMergeGap = 10.0
+	// A segment this close to the start or end of the video is the intro or outro
+	skipSegmentEdge = 30.0
+	// Chat counts as inactive after this long without a message
+	chatQuietGap = 90.0
+	// Score a stretch needs to count as dead air
+	deadAirThreshold = 0.5
+)
+
+// How much each signal contributes to a stretch being dead air. A black or
+// frozen picture counts together with silence or a quiet chat; silence alone
+// only counts when chat is quiet as well.
+var deadAirWeights = map[string]float64{
+	"black":   0.45,
+	"freeze":  0.35,
+	"silence": 0.3,
+	"chat":    0.25,
+}
+
+var (
+	silenceStartLine = regexp.MustCompile(`silence_start: (-?[\d.]+)`)
+	silenceEndLine   = regexp.MustCompile(`silence_end: (-?[\d.]+)`)
+	blackLine        = regexp.MustCompile(`black_start:(-?[\d.]+) black_end:(-?[\d.]+)`)
+	freezeStartLine  = regexp.MustCompile(`freeze_start: (-?[\d.]+)`)
+	freezeEndLine    = regexp.MustCompile(`freeze_end: (-?[\d.]+)`)
+)
+
+// NewDeadAirService creates a new dead-air detection service
+func NewDeadAirService(appDataDir string, cacheService *CacheService) *DeadAirService {
+	return &DeadAirService{
+		analysisDir:  filepath.Join(appDataDir, analysisDirName),
+		cacheService: cacheService,
+		analyzing:    make(map[string]bool),
+	}
+}
+
+// SetReadyHandler sets a function called when a background analysis finishes
+func (s *DeadAirService) SetReadyHandler(onReady func(DeadAirAnalysis)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onReady = onReady
+}
+
+// GetSkipSegments returns the skip segments of a video. If the video hasn't
+// been analysed yet, the analysis is started in the background and the result
+// has Analyzing set; the ready handler receives the segments when it's done.
+func (s *DeadAirService) GetSkipSegments(videoPath string, messages []models.ChatMessage) (DeadAirAnalysis, error) {
+	analysis, err := s.CachedSkipSegments(videoPath, messages)
+	if err != nil || analysis.Analyzed {
+		return analysis, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	analysis.Analyzing = true
+	if s.analyzing[videoPath] {
+		return analysis, nil
+	}
+	s.analyzing[videoPath] = true
+
+	go func() {
+		result, err := s.AnalyzeDeadAir(videoPath, messages)
+
+		s.mu.Lock()
+		delete(s.analyzing, videoPath)
+		onReady := s.onReady
+		s.mu.Unlock()
+
+		if err != nil {
+			fmt.Printf("Failed to detect dead air in %s: %v\n", videoPath, err)
+			return
+		}
+		if onReady != nil {
+			onReady(result)
+		}
+	}()
+	return analysis, nil
+}
+
+// CachedSkipSegments returns the skip segments of a video from a previous
+// analysis, without analysing it. Analyzed is false if there is none.
+func (s *DeadAirService) CachedSkipSegments(videoPath string, messages []models.ChatMessage) (DeadAirAnalysis, error) {
+	signalsPath, err := s.signalsPath(videoPath)
+	if err != nil {
+		return DeadAirAnalysis{}, err
+	}
+	var signals deadAirSignals
+	if err := readJSONFile(signalsPath, &signals); err != nil {
+		return DeadAirAnalysis{}, fmt.Errorf("failed to read dead-air analysis: %v", err)
+	}
+	if signals.AnalyzedAt.IsZero() {
+		return DeadAirAnalysis{VideoPath: videoPath, Segments: []SkipSegment{}}, nil
+	}
+	return DeadAirAnalysis{
+		VideoPath: videoPath,
+		Duration:  signals.Duration,
+		Segments:  buildSkipSegments(signals, messages),
+		Analyzed:  true,
+	}, nil
+}
+
+// AnalyzeDeadAir runs the detection filters over a video, caches what they
+// found and returns the skip segments. Only keyframes are decoded for the
+// picture checks, so a long stream takes a fraction of its running time.
+func (s *DeadAirService) AnalyzeDeadAir(videoPath string, messages []models.ChatMessage) (DeadAirAnalysis, error) {
+	signalsPath, err := s.signalsPath(videoPath)
+	if err != nil {
+		return DeadAirAnalysis{}, err
+	}
+	media, err := s.cacheService.GetMediaInfo(videoPath)
+	if err != nil {
+		return DeadAirAnalysis{}, fmt.Errorf("failed to read video: %v", err)
+	}
+
+	workDir, err := os.MkdirTemp("", "archive-player-deadair-*")
+	if err != nil {
+		return DeadAirAnalysis{}, fmt.Errorf("failed to create work directory: %v", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	args := []string{
+		"-hide_banner", "-nostats",
+		"-skip_frame", "nokey",
+		"-i", videoPath,
+		"-vf", fmt.Sprintf("scale=320:-2,blackdetect=d=%d:pix_th=0.10,freezedetect=n=-60dB:d=%d", minDeadAirSignal, minDeadAirSignal),
+	}
+	if media.HasAudio() {
+		args = append(args, "-af", fmt.Sprintf("silencedetect=noise=-50dB:d=%d", minDeadAirSignal))
+	}
+	args = append(args, "-f", "null", "-")
+	output, err := runFFmpegOutput(workDir, args)
+	if err != nil {
+		return DeadAirAnalysis{}, err
+	}
+
+	signals := parseDeadAirSignals(output, media.Duration)
+	signals.AnalyzedAt = time.Now()
+	if err := os.MkdirAll(s.analysisDir, 0755); err != nil {
+		return DeadAirAnalysis{}, err
+	}
+	if err := writeJSONFile(signalsPath, signals); err != nil {
+		return DeadAirAnalysis{}, fmt.Errorf("failed to save dead-air analysis: %v", err)
+	}
+
+	return DeadAirAnalysis{
+		VideoPath: videoPath,
+		Duration:  signals.Duration,
+		Segments:  buildSkipSegments(signals, messages),
+		Analyzed:  true,
+	}, nil
+}
+
+// signalsPath returns where the detected signals of a video are cached
+func (s *DeadAirService) signalsPath(videoPath string) (string, error) {
+	hash, err := s.cacheService.GetFileHash(videoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash video: %v", err)
+	}
+	return filepath.Join(s.analysisDir, hash+"_dead_air.json"), nil
+}
+
+// parseDeadAirSignals reads the ranges the detection filters logged. A range
+// still open when the video ends runs to the end.
+func parseDeadAirSignals(output string, duration float64) deadAirSignals {
+	signals := deadAirSignals{Duration: duration, Silence: [][2]float64{}, Black: [][2]float64{}, Freeze: [][2]float64{}}
+
+	silenceStart, freezeStart := -1.0, -1.0
+	for _, line := range strings.Split(strings.ReplaceAll(output, "\r", "\n"), "\n") {
+		if match := silenceStartLine.FindStringSubmatch(line); match != nil {
+			silenceStart = max(parseProbeFloat(match[1]), 0)
+		} else if match := silenceEndLine.FindStringSubmatch(line); match != nil && silenceStart >= 0 {
+			signals.Silence = append(signals.Silence, [2]float64{silenceStart, parseProbeFloat(match[1])})
+			silenceStart = -1
+		}
+		if match := blackLine.FindStringSubmatch(line); match != nil {
+			signals.Black = append(signals.Black, [2]float64{parseProbeFloat(match[1]), parseProbeFloat(match[2])})
+		}
+		if match := freezeStartLine.FindStringSubmatch(line); match != nil {
+			freezeStart = parseProbeFloat(match[1])
+		} else if match := freezeEndLine.FindStringSubmatch(line); match != nil && freezeStart >= 0 {
+			signals.Freeze = append(signals.Freeze, [2]float64{freezeStart, parseProbeFloat(match[1])})
+			freezeStart = -1
+		}
+	}
+	if silenceStart >= 0 && duration > silenceStart {
+		signals.Silence = append(signals.Silence, [2]float64{silenceStart, duration})
+	}
+	if freezeStart >= 0 && duration > freezeStart {
+		signals.Freeze = append(signals.Freeze, [2]float64{freezeStart, duration})
+	}
+	return signals
+}
+
+// chatQuietRanges returns the stretches without chat messages. Without a
+// chat there's nothing to tell, so no ranges are returned.
+func chatQuietRanges(messages []models.ChatMessage, duration float64) [][2]float64 {
+	ranges := [][2]float64{}
+	if len(messages) == 0 {
+		return ranges
+	}
+	previous := 0.0
+	for _, msg := range messages {
+		if msg.TimeInSeconds-previous >= chatQuietGap {
+			ranges = append(ranges, [2]float64{previous, msg.TimeInSeconds})
+		}
+		previous = max(previous, msg.TimeInSeconds)
+	}
+	if duration-previous >= chatQuietGap {
+		ranges = append(ranges, [2]float64{previous, duration})
+	}
+	return ranges
+}
+
+// buildSkipSegments scores every stretch of the video by the signals present
+// in it, then merges the stretches that score as dead air into segments
+func buildSkipSegments(signals deadAirSignals, messages []models.ChatMessage) []SkipSegment {
+	ranges := map[string][][2]float64{
+		"silence": signals.Silence,
+		"black":   signals.Black,
+		"freeze":  signals.Freeze,
+		"chat":    chatQuietRanges(messages, signals.Duration),
+	}
+
+	// Split the timeline at every range boundary
+	boundaries := []float64{}
+	for _, signalRanges := range ranges {
+		for _, r := range signalRanges {
+			boundaries = append(boundaries, r[0], r[1])
+		}
+	}
+	sort.Float64s(boundaries)
+
+	type deadPiece struct {
+		start, end float64
+		score      float64
+		signals    map[string]bool
+	}
+	var pieces []deadPiece
+	for i := 0; i+1 < len(boundaries); i++ {
+		start, end := boundaries[i], boundaries[i+1]
+		if end <= start {
+			continue
+		}
+		middle := (start + end) / 2
+		piece := deadPiece{start: start, end: end, signals: map[string]bool{}}
+		for signal, signalRanges := range ranges {
+			for _, r := range signalRanges {
+				if middle >= r[0] && middle < r[1] {
+					piece.score += deadAirWeights[signal]
+					piece.signals[signal] = true
+					break
+				}
+			}
+		}
+		if piece.score >= deadAirThreshold {
+			pieces = append(pieces, piece)
+		}
+	}
+
+	segments := []SkipSegment{}
+	for i := 0; i < len(pieces); {
+		// Merge the pieces that follow each other closely
+		j := i + 1
+		for j < len(pieces) && pieces[j].start-pieces[j-1].end <= skipSegmentMergeGap {
+			j++
+		}
+		start, end := pieces[i].start, pieces[j-1].end
+		if end-start >= minSkipSegment {
+			weighted := 0.0
+			signalTime := map[string]float64{}
+			for _, piece := range pieces[i:j] {
+				weighted += min(piece.score, 1) * (piece.end - piece.start)
+				for signal := range piece.signals {
+					signalTime[signal] += piece.end - piece.start
+				}
+			}
+			segment := SkipSegment{
+				Start:      start,
+				End:        end,
+				Kind:       "break",
+				Confidence: weighted / (end - start),
+				Signals:    []string{},
+			}
+			for _, signal := range []string{"silence", "black", "freeze", "chat"} {
+				if signalTime[signal] > (end-start)/2 {
+					segment.Signals = append(segment.Signals, signal)
+				}
+			}
+			if start <= skipSegmentEdge {
+				segment.Kind = "intro"
+			} else if signals.Duration > 0 && end >= signals.Duration-skipSegmentEdge {
+				segment.Kind = "outro"
+			}
+			segments = append(segments, segment)
+		}
+		i = j
+	}
+	return segments
+}
+
+// TrimToContent shrinks a range so it doesn't start or end in dead air. A
+// range that lies entirely inside dead air is returned unchanged, since it
+// was picked on purpose.
+func TrimToContent(segments []SkipSegment, start float64, end float64) (float64, float64) {
+	trimmedStart, trimmedEnd := start, end
+	for _, segment := range segments {
+		if trimmedStart >= segment.Start && trimmedStart < segment.End {
+			trimmedStart = segment.End
+		}
+		if trimmedEnd > segment.Start && trimmedEnd <= segment.End {
+			trimmedEnd = segment.Start
+		}
+	}
+	if trimmedEnd <= trimmedStart {
+		return start, end
+	}
+	return trimmedStart, trimmedEnd
+}
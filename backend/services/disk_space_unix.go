@@ -0,0 +1,14 @@
+//go:build !windows
+
+package services
+
+import "golang.org/x/sys/unix"
+
+// freeDiskSpace returns the bytes available to the user on the volume holding dir
+func freeDiskSpace(dir string) (uint64, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FaststartCandidate is an MP4 whose index sits after its media data
+type FaststartCandidate struct {
+	VideoPath   string `json:"videoPath"`
+	Size        int64  `json:"size"`
+	IndexOffset int64  `json:"indexOffset"` // Bytes the player has to skip to reach the index
+}
+
+// FaststartProgress reports the progress of a faststart pass
+type FaststartProgress struct {
+	Index      int    `json:"index"` // 1-based position in the pass
+	Total      int    `json:"total"`
+	VideoPath  string `json:"videoPath"`
+	BytesDone  int64  `json:"bytesDone"` // Size of the videos processed so far
+	BytesTotal int64  `json:"bytesTotal"`
+	Error      string `json:"error,omitempty"`
+	Done       bool   `json:"done"` // Set on the last event of the pass
+}
+
+// FaststartService moves the index of MP4 files to the front so the player
+// can start them without fetching the end of the file first
+type FaststartService struct {
+	cacheService *CacheService
+	mu           sync.Mutex
+	running      bool
+}
+
+const (
+	// Free space to keep beyond the size of the remuxed copy
+	faststartSpareSpace = 512 << 20
+	// The remuxed copy may differ from the original by a frame's duration
+	faststartDurationTolerance = 0.5
+)
+
+// NewFaststartService creates a new faststart service
+func NewFaststartService(cacheService *CacheService) *FaststartService {
+	return &FaststartService{cacheService: cacheService}
+}
+
+// FindNonFaststartVideos returns the MP4s among videoPaths whose index comes
+// after their media data. Only box headers are read, so scanning a large
+// library is quick. Files without an index are left to the integrity check.
+func (s *FaststartService) FindNonFaststartVideos(videoPaths []string) []FaststartCandidate {
+	candidates := []FaststartCandidate{}
+	for _, path := range videoPaths {
+		if !isMP4File(path) {
+			continue
+		}
+		layout, err := readMP4Layout(path)
+		if err != nil {
+			fmt.Printf("Failed to read %s: %v\n", path, err)
+			continue
+		}
+		if !layout.hasIndex() || layout.faststart() {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		moov, _ := layout.find("moov")
+		candidates = append(candidates, FaststartCandidate{VideoPath: path, Size: info.Size(), IndexOffset: moov.offset})
+	}
+	return candidates
+}
+
+// OptimizeLibrary remuxes the given videos one at a time, reporting each
+// through progress. Only one pass runs at a time.
+func (s *FaststartService) OptimizeLibrary(videoPaths []string, progress func(FaststartProgress)) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return errors.New("videos are already being optimized")
+	}
+	s.running = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	if progress == nil {
+		progress = func(FaststartProgress) {}
+	}
+	sizes := make([]int64, len(videoPaths))
+	bytesTotal := int64(0)
+	for i, path := range videoPaths {
+		if info, err := os.Stat(path); err == nil {
+			sizes[i] = info.Size()
+			bytesTotal += sizes[i]
+		}
+	}
+
+	bytesDone := int64(0)
+	for i, path := range videoPaths {
+		update := FaststartProgress{Index: i + 1, Total: len(videoPaths), VideoPath: path, BytesTotal: bytesTotal}
+		if err := s.OptimizeVideo(path); err != nil {
+			fmt.Printf("Failed to optimize %s: %v\n", path, err)
+			update.Error = err.Error()
+		}
+		bytesDone += sizes[i]
+		update.BytesDone = bytesDone
+		update.Done = i == len(videoPaths)-1
+		progress(update)
+	}
+	if len(videoPaths) == 0 {
+		progress(FaststartProgress{Done: true})
+	}
+	return nil
+}
+
+// OptimizeVideo moves the index of an MP4 to the front. The video is remuxed
+// to a temporary file next to it, which is checked against the original and
+// then renamed over it, so the original is untouched if anything fails. The
+// file keeps its modification time and fingerprint, and with them its
+// bookmarks, watch history and analyses.
+func (s *FaststartService) OptimizeVideo(videoPath string) error {
+	if !isMP4File(videoPath) {
+		return errors.New("only MP4 files can be optimized")
+	}
+	layout, err := readMP4Layout(videoPath)
+	if err != nil {
+		return fmt.Errorf("failed to read video: %v", err)
+	}
+	if !layout.hasIndex() {
+		return errors.New("video has no index, check its integrity instead")
+	}
+	if layout.faststart() {
+		return nil
+	}
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return errors.New("ffmpeg not found")
+	}
+
+	fileInfo, err := os.Stat(videoPath)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(videoPath)
+	free, err := freeDiskSpace(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read free disk space: %v", err)
+	}
+	if needed := uint64(fileInfo.Size()) + faststartSpareSpace; free < needed {
+		return fmt.Errorf("not enough free disk space: %d MB needed, %d MB available", needed>>20, free>>20)
+	}
+
+	hash, err := s.cacheService.GetFileHash(videoPath)
+	if err != nil {
+		return fmt.Errorf("failed to hash video: %v", err)
+	}
+	original, err := runMediaProbe(videoPath)
+	if err != nil {
+		return fmt.Errorf("failed to read video: %v", err)
+	}
+
+	format := "mp4"
+	if strings.EqualFold(filepath.Ext(videoPath), ".mov") {
+		format = "mov"
+	}
+	tempPath := videoPath + ".faststart.tmp"
+	err = runFFmpeg(dir, []string{
+		"-i", videoPath,
+		"-map", "0",
+		"-c", "copy",
+		"-map_metadata", "0",
+		"-movflags", "+faststart",
+		"-f", format,
+		"-y", tempPath,
+	})
+	if err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	if err := verifyFaststartCopy(tempPath, original); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	// Keep the recording date the library sorts by
+	if err := os.Chtimes(tempPath, fileInfo.ModTime(), fileInfo.ModTime()); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	if err := os.Rename(tempPath, videoPath); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to replace video: %v", err)
+	}
+
+	if err := s.cacheService.KeepFileHash(videoPath, hash); err != nil {
+		fmt.Printf("Failed to keep hash of %s: %v\n", videoPath, err)
+	}
+	return nil
+}
+
+// verifyFaststartCopy checks that a remuxed copy has its index in front and
+// the same streams and duration as the original
+func verifyFaststartCopy(path string, original MediaInfo) error {
+	layout, err := readMP4Layout(path)
+	if err != nil {
+		return fmt.Errorf("failed to read optimized copy: %v", err)
+	}
+	if layout.truncated || !layout.faststart() {
+		return errors.New("optimized copy doesn't start with its index")
+	}
+	media, err := runMediaProbe(path)
+	if err != nil {
+		return fmt.Errorf("failed to read optimized copy: %v", err)
+	}
+	if len(media.Streams) != len(original.Streams) {
+		return fmt.Errorf("optimized copy has %d streams instead of %d", len(media.Streams), len(original.Streams))
+	}
+	if math.Abs(media.Duration-original.Duration) > faststartDurationTolerance {
+		return fmt.Errorf("optimized copy is %s long instead of %s", formatTimeText(media.Duration), formatTimeText(original.Duration))
+	}
+	return nil
+}
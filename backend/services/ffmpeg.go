@@ -0,0 +1,129 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// drawtextFontOption returns the fontfile option for ffmpeg's drawtext filter.
+// Windows builds of ffmpeg usually ship without a working fontconfig setup, so
+// Arial is referenced directly there; elsewhere fontconfig picks the font.
+func drawtextFontOption() string {
+	if runtime.GOOS != "windows" {
+		return ""
+	}
+	windir := os.Getenv("WINDIR")
+	if windir == "" {
+		windir = `C:\Windows`
+	}
+	fontPath := filepath.Join(windir, "Fonts", "arial.ttf")
+	if _, err := os.Stat(fontPath); err != nil {
+		return ""
+	}
+	return "fontfile='" + escapeFilterPath(fontPath) + "':"
+}
+
+// escapeFilterPath makes a file path safe to use as a quoted filter option value
+func escapeFilterPath(path string) string {
+	path = filepath.ToSlash(path)
+	path = strings.ReplaceAll(path, `'`, `'\''`)
+	return strings.ReplaceAll(path, ":", `\:`)
+}
+
+// runFFmpeg runs ffmpeg inside workDir and includes its output in the error
+func runFFmpeg(workDir string, args []string) error {
+	_, err := runFFmpegOutput(workDir, args)
+	return err
+}
+
+// runFFmpegOutput runs ffmpeg inside workDir and returns its log output, for
+// analysis filters that report their results there
+func runFFmpegOutput(workDir string, args []string) (string, error) {
+	cmd := exec.Command("ffmpeg", args...)
+	cmd.Dir = workDir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("FFmpeg error: %v\nOutput: %s", err, string(output))
+	}
+	return string(output), nil
+}
+
+// streamMapArg returns the -map argument for a stream label. Labels of
+// unfiltered input streams such as "[0:v]" are mapped as input specifiers.
+func streamMapArg(label string) string {
+	if strings.HasPrefix(label, "[") && strings.Contains(label, ":") {
+		return strings.Trim(label, "[]")
+	}
+	return label
+}
+
+// filterGraph builds an ffmpeg filter_complex graph one chain at a time. It
+// also collects the extra inputs the graph reads from; the source video inputs
+// come first and are added by the caller.
+type filterGraph struct {
+	chains       []string
+	labels       int
+	inputs       []string
+	sourceInputs int // Number of source inputs added by the caller, 1 if unset
+}
+
+// addInput registers an extra ffmpeg input (e.g. "-loop", "1", "-i", "logo.png")
+// and returns its input index
+func (g *filterGraph) addInput(args ...string) int {
+	g.inputs = append(g.inputs, args...)
+	index := g.sourceInputs
+	if index == 0 {
+		index = 1
+	}
+	for _, arg := range g.inputs {
+		if arg == "-i" {
+			index++
+		}
+	}
+	return index - 1
+}
+
+// inputArgs returns the arguments for the extra inputs
+func (g *filterGraph) inputArgs() []string {
+	return g.inputs
+}
+
+// chain appends a filter chain reading from inputs (e.g. "[0:v]") and returns
+// the label of its output
+func (g *filterGraph) chain(inputs string, filters string) string {
+	g.labels++
+	label := fmt.Sprintf("[f%d]", g.labels)
+	g.chains = append(g.chains, inputs+filters+label)
+	return label
+}
+
+// chainOutputs appends a filter chain with several outputs (e.g. split or
+// concat) and returns their labels
+func (g *filterGraph) chainOutputs(inputs string, filters string, count int) []string {
+	outputs := make([]string, count)
+	for i := range outputs {
+		g.labels++
+		outputs[i] = fmt.Sprintf("[f%d]", g.labels)
+	}
+	g.chains = append(g.chains, inputs+filters+strings.Join(outputs, ""))
+	return outputs
+}
+
+// split duplicates a video stream into count outputs and returns their labels
+func (g *filterGraph) split(input string, count int) []string {
+	return g.chainOutputs(input, fmt.Sprintf("split=%d", count), count)
+}
+
+// empty reports whether no chains have been added
+func (g *filterGraph) empty() bool {
+	return len(g.chains) == 0
+}
+
+// String returns the graph in filter_complex syntax
+func (g *filterGraph) String() string {
+	return strings.Join(g.chains, ";")
+}
@@ -0,0 +1,335 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MediaInfo describes a media file as reported by ffprobe
+type MediaInfo struct {
+	Container         string            `json:"container"`         // ffprobe format name, e.g. "mov,mp4,m4a,3gp,3g2,mj2"
+	ContainerLongName string            `json:"containerLongName"` // e.g. "QuickTime / MOV"
+	Duration          float64           `json:"duration"`
+	StartTime         float64           `json:"startTime"`
+	Size              int64             `json:"size"`
+	BitRate           int64             `json:"bitRate"`
+	CreationTime      string            `json:"creationTime,omitempty"`
+	Streams           []MediaStream     `json:"streams"`
+	Chapters          []MediaChapter    `json:"chapters"`
+	Tags              map[string]string `json:"tags"`
+}
+
+// MediaStream is one stream of a media file
+type MediaStream struct {
+	Index         int               `json:"index"`
+	Type          string            `json:"type"`          // video, audio, subtitle, data or attachment
+	Codec         string            `json:"codec"`         // ffprobe codec name, e.g. "h264"
+	CodecLongName string            `json:"codecLongName"` // e.g. "H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10"
+	Profile       string            `json:"profile,omitempty"`
+	BitRate       int64             `json:"bitRate,omitempty"`
+	Width         int               `json:"width,omitempty"`
+	Height        int               `json:"height,omitempty"`
+	FrameRate     string            `json:"frameRate,omitempty"` // Average frame rate as a fraction, e.g. 30000/1001
+	FPS           float64           `json:"fps,omitempty"`
+	PixelFormat   string            `json:"pixelFormat,omitempty"`
+	SampleRate    int               `json:"sampleRate,omitempty"`
+	Channels      int               `json:"channels,omitempty"`
+	ChannelLayout string            `json:"channelLayout,omitempty"`
+	Language      string            `json:"language,omitempty"`
+	Title         string            `json:"title,omitempty"`
+	Default       bool              `json:"default"`
+	Tags          map[string]string `json:"tags"`
+}
+
+// MediaChapter is a chapter of a media file
+type MediaChapter struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Title string  `json:"title"`
+}
+
+// ffprobeOutput is the JSON written by ffprobe -show_format -show_streams -show_chapters
+type ffprobeOutput struct {
+	Format struct {
+		FormatName     string            `json:"format_name"`
+		FormatLongName string            `json:"format_long_name"`
+		StartTime      string            `json:"start_time"`
+		Duration       string            `json:"duration"`
+		Size           string            `json:"size"`
+		BitRate        string            `json:"bit_rate"`
+		Tags           map[string]string `json:"tags"`
+	} `json:"format"`
+	Streams []struct {
+		Index         int    `json:"index"`
+		CodecName     string `json:"codec_name"`
+		CodecLongName string `json:"codec_long_name"`
+		Profile       string `json:"profile"`
+		CodecType     string `json:"codec_type"`
+		Width         int    `json:"width"`
+		Height        int    `json:"height"`
+		PixelFormat   string `json:"pix_fmt"`
+		AvgFrameRate  string `json:"avg_frame_rate"`
+		RealFrameRate string `json:"r_frame_rate"`
+		SampleRate    string `json:"sample_rate"`
+		Channels      int    `json:"channels"`
+		ChannelLayout string `json:"channel_layout"`
+		BitRate       string `json:"bit_rate"`
+		Disposition   struct {
+			Default int `json:"default"`
+		} `json:"disposition"`
+		Tags map[string]string `json:"tags"`
+	} `json:"streams"`
+	Chapters []struct {
+		StartTime string            `json:"start_time"`
+		EndTime   string            `json:"end_time"`
+		Tags      map[string]string `json:"tags"`
+	} `json:"chapters"`
+}
+
+// mediaProbe is a probe result remembered for the running session
+type mediaProbe struct {
+	info         MediaInfo
+	lastModified time.Time
+	fileSize     int64
+}
+
+var (
+	mediaProbesMu sync.Mutex
+	mediaProbes   = make(map[string]mediaProbe)
+)
+
+// probeMedia runs ffprobe once per file and remembers the result while the
+// file's size and modification time are unchanged. CacheService.GetMediaInfo
+// also keeps results between sessions.
+func probeMedia(path string) (MediaInfo, error) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return MediaInfo{}, err
+	}
+
+	mediaProbesMu.Lock()
+	probe, exists := mediaProbes[path]
+	mediaProbesMu.Unlock()
+	if exists && probe.lastModified.Equal(fileInfo.ModTime()) && probe.fileSize == fileInfo.Size() {
+		return probe.info, nil
+	}
+
+	info, err := runMediaProbe(path)
+	if err != nil {
+		return MediaInfo{}, err
+	}
+	rememberMediaProbe(path, info, fileInfo)
+	return info, nil
+}
+
+// rememberMediaProbe stores a probe result for the running session
+func rememberMediaProbe(path string, info MediaInfo, fileInfo os.FileInfo) {
+	mediaProbesMu.Lock()
+	defer mediaProbesMu.Unlock()
+	mediaProbes[path] = mediaProbe{info: info, lastModified: fileInfo.ModTime(), fileSize: fileInfo.Size()}
+}
+
+// runMediaProbe reads the format, streams and chapters of a file with ffprobe
+func runMediaProbe(path string) (MediaInfo, error) {
+	// Check if ffprobe is available
+	if _, err := exec.LookPath("ffprobe"); err != nil {
+		return MediaInfo{}, errors.New("ffprobe not found")
+	}
+
+	cmd := exec.Command(
+		"ffprobe",
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		"-show_chapters",
+		path,
+	)
+	output, err := cmd.Output()
+	if err != nil {
+		return MediaInfo{}, err
+	}
+
+	var probe ffprobeOutput
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return MediaInfo{}, fmt.Errorf("unexpected ffprobe output: %v", err)
+	}
+
+	info := MediaInfo{
+		Container:         probe.Format.FormatName,
+		ContainerLongName: probe.Format.FormatLongName,
+		Duration:          parseProbeFloat(probe.Format.Duration),
+		StartTime:         parseProbeFloat(probe.Format.StartTime),
+		Size:              parseProbeInt(probe.Format.Size),
+		BitRate:           parseProbeInt(probe.Format.BitRate),
+		CreationTime:      probe.Format.Tags["creation_time"],
+		Streams:           []MediaStream{},
+		Chapters:          []MediaChapter{},
+		Tags:              probe.Format.Tags,
+	}
+	if info.Tags == nil {
+		info.Tags = map[string]string{}
+	}
+
+	for _, stream := range probe.Streams {
+		mediaStream := MediaStream{
+			Index:         stream.Index,
+			Type:          stream.CodecType,
+			Codec:         stream.CodecName,
+			CodecLongName: stream.CodecLongName,
+			Profile:       stream.Profile,
+			BitRate:       parseProbeInt(stream.BitRate),
+			Width:         stream.Width,
+			Height:        stream.Height,
+			PixelFormat:   stream.PixelFormat,
+			SampleRate:    int(parseProbeInt(stream.SampleRate)),
+			Channels:      stream.Channels,
+			ChannelLayout: stream.ChannelLayout,
+			Language:      stream.Tags["language"],
+			Title:         stream.Tags["title"],
+			Default:       stream.Disposition.Default == 1,
+			Tags:          stream.Tags,
+		}
+		if mediaStream.Tags == nil {
+			mediaStream.Tags = map[string]string{}
+		}
+		if stream.CodecType == "video" {
+			rate := stream.AvgFrameRate
+			if numerator, denominator, err := parseFrameRate(rate); err == nil {
+				mediaStream.FrameRate = rate
+				mediaStream.FPS = float64(numerator) / float64(denominator)
+			} else if numerator, denominator, err := parseFrameRate(stream.RealFrameRate); err == nil {
+				mediaStream.FrameRate = stream.RealFrameRate
+				mediaStream.FPS = float64(numerator) / float64(denominator)
+			}
+		}
+		info.Streams = append(info.Streams, mediaStream)
+	}
+
+	for _, chapter := range probe.Chapters {
+		info.Chapters = append(info.Chapters, MediaChapter{
+			Start: parseProbeFloat(chapter.StartTime),
+			End:   parseProbeFloat(chapter.EndTime),
+			Title: chapter.Tags["title"],
+		})
+	}
+
+	// Some recorders only tag the video stream with its creation time
+	if info.CreationTime == "" {
+		for _, stream := range info.Streams {
+			if created := stream.Tags["creation_time"]; created != "" {
+				info.CreationTime = created
+				break
+			}
+		}
+	}
+
+	return info, nil
+}
+
+// VideoStream returns the first video stream
+func (m MediaInfo) VideoStream() (MediaStream, bool) {
+	return m.firstStream("video")
+}
+
+// AudioStreams returns the audio streams in file order
+func (m MediaInfo) AudioStreams() []MediaStream {
+	return m.streamsOfType("audio")
+}
+
+// HasAudio reports whether the file has at least one audio stream
+func (m MediaInfo) HasAudio() bool {
+	_, found := m.firstStream("audio")
+	return found
+}
+
+// VideoSize returns the width and height of the first video stream
+func (m MediaInfo) VideoSize() (int, int, error) {
+	video, found := m.VideoStream()
+	if !found || video.Width <= 0 || video.Height <= 0 {
+		return 0, 0, errors.New("no video stream found")
+	}
+	return video.Width, video.Height, nil
+}
+
+// FrameRateFraction returns the average frame rate of the first video stream
+// as a fraction, e.g. 30000/1001, for formats that need exact rates
+func (m MediaInfo) FrameRateFraction() (int, int, error) {
+	video, found := m.VideoStream()
+	if !found {
+		return 0, 0, errors.New("no video stream found")
+	}
+	return parseFrameRate(video.FrameRate)
+}
+
+// FrameRate returns the average frame rate of the first video stream
+func (m MediaInfo) FrameRate() (float64, error) {
+	numerator, denominator, err := m.FrameRateFraction()
+	if err != nil {
+		return 0, err
+	}
+	return float64(numerator) / float64(denominator), nil
+}
+
+// firstStream returns the first stream of a type
+func (m MediaInfo) firstStream(streamType string) (MediaStream, bool) {
+	for _, stream := range m.Streams {
+		if stream.Type == streamType {
+			return stream, true
+		}
+	}
+	return MediaStream{}, false
+}
+
+// streamsOfType returns the streams of a type in file order
+func (m MediaInfo) streamsOfType(streamType string) []MediaStream {
+	streams := []MediaStream{}
+	for _, stream := range m.Streams {
+		if stream.Type == streamType {
+			streams = append(streams, stream)
+		}
+	}
+	return streams
+}
+
+// parseFrameRate parses an ffprobe frame rate such as "30000/1001" or "30"
+func parseFrameRate(rate string) (int, int, error) {
+	numeratorText, denominatorText, found := strings.Cut(rate, "/")
+	if !found {
+		denominatorText = "1"
+	}
+	numerator, err := strconv.Atoi(numeratorText)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unexpected frame rate: %s", rate)
+	}
+	denominator, err := strconv.Atoi(denominatorText)
+	if err != nil || numerator <= 0 || denominator <= 0 {
+		return 0, 0, fmt.Errorf("unexpected frame rate: %s", rate)
+	}
+	return numerator, denominator, nil
+}
+
+// parseProbeFloat parses a number ffprobe writes as a string, 0 if it's missing
+func parseProbeFloat(value string) float64 {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return number
+}
+
+// parseProbeInt parses an integer ffprobe writes as a string, 0 if it's missing
+func parseProbeInt(value string) int64 {
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return number
+}
@@ -2,6 +2,10 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
@@ -47,3 +51,34 @@ func (s *FileDialogService) OpenChatFile() (string, error) {
 	}
 	return runtime.OpenFileDialog(s.ctx, options)
 }
+
+// OpenMarkerFile opens a dialog to select a marker list (CSV, TSV or EDL)
+func (s *FileDialogService) OpenMarkerFile() (string, error) {
+	options := runtime.OpenDialogOptions{
+		Title: "Select Marker File",
+		Filters: []runtime.FileFilter{
+			{
+				DisplayName: "Marker Files (*.csv;*.tsv;*.txt;*.edl)",
+				Pattern:     "*.csv;*.tsv;*.txt;*.edl",
+			},
+		},
+	}
+	return runtime.OpenFileDialog(s.ctx, options)
+}
+
+// SaveExportFile opens a dialog to choose where to save an exported file. The
+// filter matches the extension of defaultFilename.
+func (s *FileDialogService) SaveExportFile(title string, defaultFilename string) (string, error) {
+	ext := filepath.Ext(defaultFilename)
+	options := runtime.SaveDialogOptions{
+		Title:           title,
+		DefaultFilename: defaultFilename,
+		Filters: []runtime.FileFilter{
+			{
+				DisplayName: fmt.Sprintf("%s Files (*%s)", strings.ToUpper(strings.TrimPrefix(ext, ".")), ext),
+				Pattern:     "*" + ext,
+			},
+		},
+	}
+	return runtime.SaveFileDialog(s.ctx, options)
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// hashChunkSize is how much of each sampled region is read when fingerprinting
+const hashChunkSize = 1 << 20
+
+// ComputeFileHash returns a content fingerprint for a video file. Hashing a
+// multi-gigabyte VOD in full is too slow, so the size and three 1 MiB samples
+// (start, middle and end) are hashed instead. The fingerprint stays the same
+// when a file is renamed or moved.
+func ComputeFileHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	size := info.Size()
+
+	hasher := sha256.New()
+	fmt.Fprintf(hasher, "%d:", size)
+
+	offsets := []int64{0}
+	if size > 2*hashChunkSize {
+		offsets = append(offsets, size/2-hashChunkSize/2, size-hashChunkSize)
+	}
+
+	buf := make([]byte, hashChunkSize)
+	for _, offset := range offsets {
+		n, err := file.ReadAt(buf, offset)
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		hasher.Write(buf[:n])
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
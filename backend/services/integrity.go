@@ -0,0 +1,525 @@
+package services
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Integrity problems found in a video
+const (
+	IssueMissingIndex  = "missing_index" // An MP4 without its moov box, usually from a killed recorder
+	IssueTruncated     = "truncated"     // The file ends in the middle of the media data
+	IssueCorruptData   = "corrupt_data"  // The demuxer hit corrupt packets, common in TS recordings
+	IssueDecodeErrors  = "decode_errors" // Frames that fail to decode
+	IssueNotFaststart  = "not_faststart" // The moov box is at the end, so playback starts slowly
+	IssueUnreadable    = "unreadable"    // ffprobe can't read the file at all
+	IssueNoVideoStream = "no_video_stream"
+)
+
+// Repairs that can be proposed for a video. Repairs write a new file next to
+// the original, which is never modified.
+const (
+	RepairFaststart      = "faststart"       // Remux with the index moved to the front
+	RepairRemuxClean     = "remux_clean"     // Remux dropping corrupt packets; needs a readable index
+	RepairSalvage        = "salvage"         // Keep only the media up to the first corruption
+	RepairReferenceIndex = "reference_index" // Rebuild a lost moov box with untrunc from a healthy recording made with the same settings
+)
+
+// Integrity statuses
+const (
+	IntegrityOK      = "ok"
+	IntegrityWarning = "warning" // Plays, but with glitches or slowly
+	IntegrityBroken  = "broken"  // Won't play
+)
+
+// IntegrityIssue is a problem found by an integrity check
+type IntegrityIssue struct {
+	Kind   string  `json:"kind"`
+	Detail string  `json:"detail"`
+	Time   float64 `json:"time,omitempty"` // Where the problem starts in the video, when known
+}
+
+// IntegrityResult is the integrity check of a video
+type IntegrityResult struct {
+	VideoPath      string           `json:"videoPath"`
+	Status         string           `json:"status"`
+	Issues         []IntegrityIssue `json:"issues"`
+	ErrorCount     int              `json:"errorCount"` // Errors logged while reading the video
+	FirstErrorTime float64          `json:"firstErrorTime"`
+	Duration       float64          `json:"duration"`
+	Repairs        []string         `json:"repairs"` // Suggested repairs, best first
+	Deep           bool             `json:"deep"`    // Whether every frame was decoded
+	CheckedAt      time.Time        `json:"checkedAt"`
+}
+
+// IntegrityProgress reports the progress of a library integrity pass
+type IntegrityProgress struct {
+	Index  int             `json:"index"` // 1-based position in the pass
+	Total  int             `json:"total"`
+	Result IntegrityResult `json:"result"`
+	Error  string          `json:"error,omitempty"`
+	Done   bool            `json:"done"` // Set on the last event of the pass
+}
+
+// RepairResult is the outcome of a repair
+type RepairResult struct {
+	Success      bool    `json:"success"`
+	Action       string  `json:"action"`
+	OutputPath   string  `json:"outputPath"`
+	Duration     float64 `json:"duration"` // Duration of the repaired file
+	ErrorMessage string  `json:"errorMessage,omitempty"`
+}
+
+// IntegrityService checks videos for damage left by interrupted recordings
+// and repairs them. Results are cached per video hash with the other analyses.
+type IntegrityService struct {
+	analysisDir  string
+	cacheService *CacheService
+	mu           sync.Mutex
+	running      bool
+}
+
+// maxIntegrityErrors bounds how many error lines are kept as issue details
+const maxIntegrityErrors = 5
+
+// NewIntegrityService creates a new integrity service
+func NewIntegrityService(appDataDir string, cacheService *CacheService) *IntegrityService {
+	return &IntegrityService{
+		analysisDir:  filepath.Join(appDataDir, analysisDirName),
+		cacheService: cacheService,
+	}
+}
+
+// CachedIntegrity returns the last integrity check of a video, or false if it
+// hasn't been checked since it last changed
+func (s *IntegrityService) CachedIntegrity(videoPath string) (IntegrityResult, bool) {
+	resultPath, err := s.resultPath(videoPath)
+	if err != nil {
+		return IntegrityResult{}, false
+	}
+	var result IntegrityResult
+	if err := readJSONFile(resultPath, &result); err != nil || result.CheckedAt.IsZero() {
+		return IntegrityResult{}, false
+	}
+	result.VideoPath = videoPath
+	// A faststart remux keeps the video's fingerprint
+	if result.hasIssue(IssueNotFaststart) {
+		if layout, err := readMP4Layout(videoPath); err == nil && layout.faststart() {
+			issues := []IntegrityIssue{}
+			for _, issue := range result.Issues {
+				if issue.Kind != IssueNotFaststart {
+					issues = append(issues, issue)
+				}
+			}
+			result.Issues = issues
+			result.finish()
+		}
+	}
+	return result, true
+}
+
+// CheckIntegrity checks a video for a missing index, truncation and corrupt
+// data. The quick check reads every packet without decoding; a deep check
+// also decodes every frame, which takes about as long as transcoding.
+func (s *IntegrityService) CheckIntegrity(videoPath string, deep bool) (IntegrityResult, error) {
+	if _, err := os.Stat(videoPath); err != nil {
+		return IntegrityResult{}, fmt.Errorf("video file not found: %v", err)
+	}
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return IntegrityResult{}, errors.New("ffmpeg not found")
+	}
+
+	result := IntegrityResult{VideoPath: videoPath, Issues: []IntegrityIssue{}, Repairs: []string{}, Deep: deep}
+	if isMP4File(videoPath) {
+		layout, err := readMP4Layout(videoPath)
+		if err != nil {
+			return IntegrityResult{}, fmt.Errorf("failed to read video: %v", err)
+		}
+		switch {
+		case !layout.hasIndex():
+			result.Issues = append(result.Issues, IntegrityIssue{
+				Kind: IssueMissingIndex,
+				Detail: "The file has no index (moov atom); the recorder was probably stopped before it finished. " +
+					"ffmpeg can't read it, but untrunc can rebuild the index from a healthy recording made with the same settings.",
+			})
+		case !layout.faststart():
+			result.Issues = append(result.Issues, IntegrityIssue{Kind: IssueNotFaststart, Detail: "The index is at the end of the file, so playback has to fetch it first"})
+		}
+		if layout.truncated {
+			result.Issues = append(result.Issues, IntegrityIssue{Kind: IssueTruncated, Detail: "The last box runs past the end of the file"})
+		}
+	}
+
+	// Nothing can read an MP4 without its index
+	if result.hasIssue(IssueMissingIndex) {
+		result.finish()
+		return result, s.save(result)
+	}
+
+	// Use a fresh probe, cached media info may predate the damage
+	media, err := runMediaProbe(videoPath)
+	if err != nil {
+		result.Issues = append(result.Issues, IntegrityIssue{Kind: IssueUnreadable, Detail: "ffprobe can't read the file"})
+		result.finish()
+		return result, s.save(result)
+	}
+	result.Duration = media.Duration
+	if _, hasVideo := media.VideoStream(); !hasVideo {
+		result.Issues = append(result.Issues, IntegrityIssue{Kind: IssueNoVideoStream, Detail: "The file has no video stream"})
+	}
+
+	errorLines, firstErrorTime, lastTime, err := scanStreamErrors(videoPath, deep)
+	if err != nil {
+		return IntegrityResult{}, err
+	}
+	result.ErrorCount = len(errorLines)
+	result.FirstErrorTime = firstErrorTime
+	result.Issues = append(result.Issues, classifyStreamErrors(errorLines, firstErrorTime, deep)...)
+	// Reading stopped well before the end the container claims
+	if media.Duration > 0 && lastTime > 0 && lastTime < media.Duration-5 && !result.hasIssue(IssueTruncated) {
+		result.Issues = append(result.Issues, IntegrityIssue{
+			Kind:   IssueTruncated,
+			Detail: fmt.Sprintf("The media ends at %s but the file claims %s", formatTimeText(lastTime), formatTimeText(media.Duration)),
+			Time:   lastTime,
+		})
+	}
+
+	result.finish()
+	return result, s.save(result)
+}
+
+// CheckLibraryIntegrity checks every video of a library in turn, reporting
+// each result through progress. Only one pass runs at a time.
+func (s *IntegrityService) CheckLibraryIntegrity(videoPaths []string, deep bool, progress func(IntegrityProgress)) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return errors.New("the library is already being checked")
+	}
+	s.running = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	if progress == nil {
+		progress = func(IntegrityProgress) {}
+	}
+	for i, path := range videoPaths {
+		update := IntegrityProgress{Index: i + 1, Total: len(videoPaths), Done: i == len(videoPaths)-1}
+		result, err := s.CheckIntegrity(path, deep)
+		if err != nil {
+			fmt.Printf("Failed to check %s: %v\n", path, err)
+			update.Error = err.Error()
+			result.VideoPath = path
+		}
+		update.Result = result
+		progress(update)
+	}
+	if len(videoPaths) == 0 {
+		progress(IntegrityProgress{Done: true})
+	}
+	return nil
+}
+
+// RepairVideo writes a repaired copy of a video next to it as
+// <name>_repaired.mp4. The copy is written to a temporary file and only
+// renamed into place once it probes as a playable video. The reference
+// index repair needs untrunc and a healthy recording made with the same
+// recorder settings; without referencePath the newest intact MP4 in the
+// video's folder is used.
+func (s *IntegrityService) RepairVideo(videoPath string, action string, referencePath string) RepairResult {
+	result := RepairResult{Action: action}
+	if _, err := os.Stat(videoPath); err != nil {
+		result.ErrorMessage = fmt.Sprintf("Video file not found: %v", err)
+		return result
+	}
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		result.ErrorMessage = "FFmpeg not found"
+		return result
+	}
+
+	dir := filepath.Dir(videoPath)
+	stem := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
+	outputPath := uniqueTrashPath(dir, stem+"_repaired.mp4")
+	// untrunc picks the output format by extension
+	tempPath := strings.TrimSuffix(outputPath, ".mp4") + ".tmp.mp4"
+
+	var err error
+	switch action {
+	case RepairFaststart, RepairRemuxClean, RepairSalvage:
+		args := []string{"-i", videoPath}
+		if action != RepairFaststart {
+			args = []string{"-fflags", "+genpts+discardcorrupt", "-err_detect", "ignore_err", "-i", videoPath}
+		}
+		if action == RepairSalvage {
+			check, checked := s.CachedIntegrity(videoPath)
+			if !checked {
+				result.ErrorMessage = "Check the video before salvaging it"
+				return result
+			}
+			if cut := check.salvagePoint(); cut > 0 {
+				args = append(args, "-t", formatFFmpegTime(cut))
+			}
+		}
+		args = append(args,
+			"-map", "0:v?", "-map", "0:a?",
+			"-c", "copy",
+			"-movflags", "+faststart",
+			"-f", "mp4",
+			"-y", tempPath,
+		)
+		err = runFFmpeg(dir, args)
+	case RepairReferenceIndex:
+		if referencePath == "" {
+			if referencePath = findReferenceRecording(videoPath); referencePath == "" {
+				result.ErrorMessage = "No healthy recording to use as a reference was found next to the video"
+				return result
+			}
+		}
+		err = rebuildIndexFromReference(videoPath, referencePath, tempPath)
+	default:
+		result.ErrorMessage = fmt.Sprintf("Unknown repair: %s", action)
+		return result
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		result.ErrorMessage = fmt.Sprintf("Failed to repair video: %v", err)
+		return result
+	}
+
+	media, err := runMediaProbe(tempPath)
+	if err == nil {
+		_, hasVideo := media.VideoStream()
+		if !hasVideo || media.Duration <= 0 {
+			err = errors.New("no playable video")
+		}
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		result.ErrorMessage = "The repaired file isn't playable"
+		return result
+	}
+	if err := os.Rename(tempPath, outputPath); err != nil {
+		os.Remove(tempPath)
+		result.ErrorMessage = fmt.Sprintf("Failed to save repaired video: %v", err)
+		return result
+	}
+	result.Success = true
+	result.OutputPath = outputPath
+	result.Duration = media.Duration
+	return result
+}
+
+// rebuildIndexFromReference rebuilds the lost moov box of an MP4 with untrunc,
+// which learns the codec layout from the reference recording and then walks
+// the broken file's media data to index its samples
+func rebuildIndexFromReference(videoPath string, referencePath string, outputPath string) error {
+	if _, err := exec.LookPath("untrunc"); err != nil {
+		return errors.New("untrunc not found, install it to rebuild a lost index")
+	}
+	reference, err := readMP4Layout(referencePath)
+	if err != nil {
+		return fmt.Errorf("failed to read reference recording: %v", err)
+	}
+	if !reference.hasIndex() {
+		return errors.New("the reference recording has no index either")
+	}
+
+	cmd := exec.Command("untrunc", "-dst", outputPath, referencePath, videoPath)
+	cmd.Dir = filepath.Dir(videoPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("untrunc error: %v\nOutput: %s", err, string(output))
+	}
+	return nil
+}
+
+// findReferenceRecording returns the newest MP4 next to a video that has an
+// index, which is most likely recorded with the same settings
+func findReferenceRecording(videoPath string) string {
+	entries, err := os.ReadDir(filepath.Dir(videoPath))
+	if err != nil {
+		return ""
+	}
+	reference, newest := "", time.Time{}
+	for _, entry := range entries {
+		path := filepath.Join(filepath.Dir(videoPath), entry.Name())
+		if entry.IsDir() || path == videoPath || !isMP4File(path) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().After(newest) {
+			continue
+		}
+		if layout, err := readMP4Layout(path); err != nil || !layout.hasIndex() || layout.truncated {
+			continue
+		}
+		reference, newest = path, info.ModTime()
+	}
+	return reference
+}
+
+// scanStreamErrors reads a video with ffmpeg, copying packets for a quick
+// check or decoding them for a deep one, and returns the errors logged, the
+// playback time reached before the first error and the last time reached.
+// Progress is written to the same stream as the errors so they stay in order.
+func scanStreamErrors(videoPath string, deep bool) ([]string, float64, float64, error) {
+	args := []string{"-hide_banner", "-nostats", "-v", "error", "-progress", "pipe:2", "-i", videoPath, "-map", "0:v?", "-map", "0:a?"}
+	if !deep {
+		args = append(args, "-c", "copy")
+	}
+	args = append(args, "-f", "null", "-")
+
+	cmd := exec.Command("ffmpeg", args...)
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, 0, 0, err
+	}
+
+	errorLines := []string{}
+	lastTime, firstErrorTime := 0.0, -1.0
+	scanner := bufio.NewScanner(stderr)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if key, value, found := strings.Cut(line, "="); found && !strings.Contains(key, " ") {
+			if key == "out_time_us" {
+				if micros, err := strconv.ParseInt(value, 10, 64); err == nil && micros > 0 {
+					lastTime = float64(micros) / 1e6
+				}
+			}
+			continue
+		}
+		if firstErrorTime < 0 {
+			firstErrorTime = lastTime
+		}
+		errorLines = append(errorLines, line)
+	}
+	// ffmpeg exits with an error on badly damaged files, which the log describes
+	cmd.Wait()
+	return errorLines, max(firstErrorTime, 0), lastTime, nil
+}
+
+// classifyStreamErrors turns the errors ffmpeg logged into issues
+func classifyStreamErrors(errorLines []string, firstErrorTime float64, deep bool) []IntegrityIssue {
+	issues := []IntegrityIssue{}
+	found := map[string][]string{}
+	order := []string{}
+	for _, line := range errorLines {
+		lower := strings.ToLower(line)
+		kind := IssueCorruptData
+		switch {
+		case strings.Contains(lower, "moov atom not found"):
+			kind = IssueMissingIndex
+		case strings.Contains(lower, "prematurely"), strings.Contains(lower, "truncat"), strings.Contains(lower, "end of file"):
+			kind = IssueTruncated
+		case strings.Contains(lower, "decod"), strings.Contains(lower, "concealing"), strings.Contains(lower, "nal unit"), strings.Contains(lower, "slice"):
+			kind = IssueDecodeErrors
+		case deep && !strings.Contains(lower, "packet") && !strings.Contains(lower, "continuity"):
+			kind = IssueDecodeErrors
+		}
+		if _, seen := found[kind]; !seen {
+			order = append(order, kind)
+		}
+		if len(found[kind]) < maxIntegrityErrors {
+			found[kind] = append(found[kind], line)
+		}
+	}
+	for _, kind := range order {
+		issues = append(issues, IntegrityIssue{Kind: kind, Detail: strings.Join(found[kind], "\n"), Time: firstErrorTime})
+	}
+	return issues
+}
+
+// finish sets the status and proposes repairs for the issues found
+func (r *IntegrityResult) finish() {
+	r.CheckedAt = time.Now()
+	r.Status = IntegrityOK
+	for _, issue := range r.Issues {
+		switch issue.Kind {
+		case IssueMissingIndex, IssueUnreadable, IssueNoVideoStream:
+			r.Status = IntegrityBroken
+		case IssueTruncated, IssueCorruptData, IssueDecodeErrors:
+			if r.Status == IntegrityOK {
+				r.Status = IntegrityWarning
+			}
+		}
+	}
+
+	r.Repairs = []string{}
+	switch {
+	case r.hasIssue(IssueMissingIndex):
+		// ffmpeg can't read the file, only a reference recording can rebuild it
+		r.Repairs = append(r.Repairs, RepairReferenceIndex)
+	case r.hasIssue(IssueUnreadable), r.hasIssue(IssueNoVideoStream):
+		// Nothing ffmpeg can read to remux
+	case r.hasIssue(IssueCorruptData), r.hasIssue(IssueDecodeErrors):
+		r.Repairs = append(r.Repairs, RepairRemuxClean)
+		if r.salvagePoint() > 0 {
+			r.Repairs = append(r.Repairs, RepairSalvage)
+		}
+	case r.hasIssue(IssueTruncated):
+		r.Repairs = append(r.Repairs, RepairRemuxClean)
+	case r.hasIssue(IssueNotFaststart):
+		r.Repairs = append(r.Repairs, RepairFaststart)
+	}
+}
+
+// salvagePoint returns where the first corruption starts, or 0 when the
+// video has none or it's at the very start
+func (r IntegrityResult) salvagePoint() float64 {
+	if r.ErrorCount == 0 {
+		return 0
+	}
+	return r.FirstErrorTime
+}
+
+// hasIssue reports whether an issue of a kind was found
+func (r IntegrityResult) hasIssue(kind string) bool {
+	for _, issue := range r.Issues {
+		if issue.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// save caches an integrity result
+func (s *IntegrityService) save(result IntegrityResult) error {
+	resultPath, err := s.resultPath(result.VideoPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.analysisDir, 0755); err != nil {
+		return err
+	}
+	if err := writeJSONFile(resultPath, result); err != nil {
+		return fmt.Errorf("failed to save integrity check: %v", err)
+	}
+	return nil
+}
+
+// resultPath returns where the integrity check of a video is cached
+func (s *IntegrityService) resultPath(videoPath string) (string, error) {
+	hash, err := s.cacheService.GetFileHash(videoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash video: %v", err)
+	}
+	return filepath.Join(s.analysisDir, hash+"_integrity.json"), nil
+}
@@ -0,0 +1,388 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"FanslyArchivePlayer/backend/models"
+)
+
+// MarkerExportFormat is an editor file format markers can be exported to
+type MarkerExportFormat string
+
+const (
+	MarkerFormatEDL    MarkerExportFormat = "edl"    // CMX3600 EDL with Resolve marker comments
+	MarkerFormatFCPXML MarkerExportFormat = "fcpxml" // Final Cut Pro XML 1.9
+	MarkerFormatCSV    MarkerExportFormat = "csv"    // Marker list in the layout of Resolve's edit index
+)
+
+// Marker kinds, used to filter the export and pick default colours
+const (
+	MarkerKindMarker    = "marker"
+	MarkerKindHighlight = "highlight"
+	MarkerKindTip       = "tip"
+)
+
+// EditorMarker is a point or range on the video timeline
+type EditorMarker struct {
+	Time     float64 `json:"time"`
+	Duration float64 `json:"duration"` // 0 for a point marker
+	Name     string  `json:"name"`
+	Note     string  `json:"note,omitempty"`
+	Color    string  `json:"color,omitempty"` // Resolve colour name such as "Blue", defaults by kind
+	Kind     string  `json:"kind,omitempty"`  // "marker", "highlight" or "tip"
+}
+
+// MarkerExportOptions controls what is exported and in which format
+type MarkerExportOptions struct {
+	Format            MarkerExportFormat `json:"format"`
+	IncludeHighlights bool               `json:"includeHighlights"`
+	IncludeTips       bool               `json:"includeTips"` // Add a marker for every tip in the loaded chat
+}
+
+// resolveMarkerColors are the marker colours Resolve accepts
+var resolveMarkerColors = []string{
+	"Blue", "Cyan", "Green", "Yellow", "Red", "Pink", "Purple", "Fuchsia",
+	"Rose", "Lavender", "Sky", "Mint", "Lemon", "Sand", "Cocoa", "Cream",
+}
+
+// markerTimeline holds the frame rate and size the markers are placed on
+type markerTimeline struct {
+	name          string
+	videoPath     string
+	rateNumerator int // Frame rate as a fraction, e.g. 30000/1001
+	rateDenom     int
+	width         int
+	height        int
+	duration      float64
+}
+
+// MarkerFileExtension returns the file extension for a marker export format
+func MarkerFileExtension(format MarkerExportFormat) string {
+	switch format {
+	case MarkerFormatFCPXML:
+		return ".fcpxml"
+	case MarkerFormatCSV:
+		return ".csv"
+	default:
+		return ".edl"
+	}
+}
+
+// TipMarkers returns a marker for every tip in the chat
+func TipMarkers(messages []models.ChatMessage) []EditorMarker {
+	var markers []EditorMarker
+	for _, msg := range messages {
+		if msg.TipAmount <= 0 {
+			continue
+		}
+		markers = append(markers, EditorMarker{
+			Time: msg.TimeInSeconds,
+			// Tip amounts are stored in thousandths of a dollar
+			Name: fmt.Sprintf("$%.2f tip from %s", float64(msg.TipAmount)/1000, msg.Author.Name),
+			Note: msg.Message,
+			Kind: MarkerKindTip,
+		})
+	}
+	return markers
+}
+
+// ExportMarkers writes the markers for videoPath to outputPath in the chosen
+// format. Frame rate and frame size are read from the video so timecodes line
+// up with the VOD when it's opened in the editor.
+func ExportMarkers(videoPath string, markers []EditorMarker, chatMessages []models.ChatMessage, options MarkerExportOptions, outputPath string) error {
+	var selected []EditorMarker
+	for _, marker := range markers {
+		if marker.Kind == MarkerKindHighlight && !options.IncludeHighlights {
+			continue
+		}
+		selected = append(selected, marker)
+	}
+	if options.IncludeTips {
+		selected = append(selected, TipMarkers(chatMessages)...)
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("no markers to export")
+	}
+	sort.SliceStable(selected, func(i, j int) bool { return selected[i].Time < selected[j].Time })
+
+	timeline, err := probeMarkerTimeline(videoPath)
+	if err != nil {
+		return err
+	}
+
+	var content []byte
+	switch options.Format {
+	case MarkerFormatEDL, "":
+		content = []byte(buildMarkerEDL(timeline, selected))
+	case MarkerFormatFCPXML:
+		content, err = buildMarkerFCPXML(timeline, selected)
+	case MarkerFormatCSV:
+		content, err = buildMarkerCSV(timeline, selected)
+	default:
+		return fmt.Errorf("unknown marker format: %s", options.Format)
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(outputPath, content, 0644)
+}
+
+// probeMarkerTimeline reads the frame rate, size and duration of the video
+func probeMarkerTimeline(videoPath string) (markerTimeline, error) {
+	media, err := probeMedia(videoPath)
+	if err != nil {
+		return markerTimeline{}, fmt.Errorf("failed to read video: %v", err)
+	}
+	numerator, denominator, err := media.FrameRateFraction()
+	if err != nil {
+		return markerTimeline{}, fmt.Errorf("failed to read frame rate: %v", err)
+	}
+	width, height, err := media.VideoSize()
+	if err != nil {
+		return markerTimeline{}, fmt.Errorf("failed to read video size: %v", err)
+	}
+
+	return markerTimeline{
+		name:          strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath)),
+		videoPath:     videoPath,
+		rateNumerator: numerator,
+		rateDenom:     denominator,
+		width:         width,
+		height:        height,
+		duration:      media.Duration,
+	}, nil
+}
+
+// frames converts seconds to a frame count on the timeline
+func (t markerTimeline) frames(seconds float64) int {
+	return int(math.Round(seconds * float64(t.rateNumerator) / float64(t.rateDenom)))
+}
+
+// timecodeBase is the whole number of frames per second used in timecodes,
+// e.g. 30 for 29.97 fps
+func (t markerTimeline) timecodeBase() int {
+	return int(math.Round(float64(t.rateNumerator) / float64(t.rateDenom)))
+}
+
+// timecode formats a frame count as non-drop-frame HH:MM:SS:FF
+func (t markerTimeline) timecode(frames int) string {
+	base := t.timecodeBase()
+	totalSeconds := frames / base
+	return fmt.Sprintf("%02d:%02d:%02d:%02d", totalSeconds/3600, (totalSeconds%3600)/60, totalSeconds%60, frames%base)
+}
+
+// rationalTime formats a frame count as an FCPXML rational time such as "1001/30000s"
+func (t markerTimeline) rationalTime(frames int) string {
+	if frames == 0 {
+		return "0s"
+	}
+	value := frames * t.rateDenom
+	if value%t.rateNumerator == 0 {
+		return fmt.Sprintf("%ds", value/t.rateNumerator)
+	}
+	return fmt.Sprintf("%d/%ds", value, t.rateNumerator)
+}
+
+// markerFrameRange returns the first frame and length of a marker, at least one frame long
+func (t markerTimeline) markerFrameRange(marker EditorMarker) (int, int) {
+	start := t.frames(marker.Time)
+	length := t.frames(marker.Time+marker.Duration) - start
+	if length < 1 {
+		length = 1
+	}
+	return start, length
+}
+
+// buildMarkerEDL writes one event per marker with Resolve's marker comment,
+// which "Import Timeline Markers from EDL" reads
+func buildMarkerEDL(timeline markerTimeline, markers []EditorMarker) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("TITLE: %s\n", timeline.name))
+	sb.WriteString("FCM: NON-DROP FRAME\n\n")
+
+	for i, marker := range markers {
+		start, length := timeline.markerFrameRange(marker)
+		in, out := timeline.timecode(start), timeline.timecode(start+length)
+		sb.WriteString(fmt.Sprintf("%03d  001      V     C        %s %s %s %s  \n", i+1, in, out, in, out))
+
+		name := edlCommentText(marker.Name)
+		if note := edlCommentText(marker.Note); note != "" {
+			name += " - " + note
+		}
+		sb.WriteString(fmt.Sprintf(" |C:Resolve%s |M:%s |D:%d\n\n", markerColor(marker), name, length))
+	}
+	return sb.String()
+}
+
+// edlCommentText keeps text on one line and clear of the "|X:" field separators
+func edlCommentText(text string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(text), " "), "|", "/")
+}
+
+// fcpxml is the subset of the FCPXML 1.9 document needed for markers
+type fcpxml struct {
+	XMLName   xml.Name `xml:"fcpxml"`
+	Version   string   `xml:"version,attr"`
+	Resources struct {
+		Format struct {
+			ID            string `xml:"id,attr"`
+			FrameDuration string `xml:"frameDuration,attr"`
+			Width         int    `xml:"width,attr"`
+			Height        int    `xml:"height,attr"`
+		} `xml:"format"`
+		Asset struct {
+			ID       string `xml:"id,attr"`
+			Name     string `xml:"name,attr"`
+			Start    string `xml:"start,attr"`
+			Duration string `xml:"duration,attr"`
+			HasVideo string `xml:"hasVideo,attr"`
+			HasAudio string `xml:"hasAudio,attr"`
+			Format   string `xml:"format,attr"`
+			MediaRep struct {
+				Kind string `xml:"kind,attr"`
+				Src  string `xml:"src,attr"`
+			} `xml:"media-rep"`
+		} `xml:"asset"`
+	} `xml:"resources"`
+	Event struct {
+		Name    string `xml:"name,attr"`
+		Project struct {
+			Name     string `xml:"name,attr"`
+			Sequence struct {
+				Format   string `xml:"format,attr"`
+				Duration string `xml:"duration,attr"`
+				TCStart  string `xml:"tcStart,attr"`
+				TCFormat string `xml:"tcFormat,attr"`
+				Clip     struct {
+					Ref      string         `xml:"ref,attr"`
+					Offset   string         `xml:"offset,attr"`
+					Name     string         `xml:"name,attr"`
+					Start    string         `xml:"start,attr"`
+					Duration string         `xml:"duration,attr"`
+					Markers  []fcpxmlMarker `xml:"marker"`
+				} `xml:"spine>asset-clip"`
+			} `xml:"sequence"`
+		} `xml:"project"`
+	} `xml:"library>event"`
+}
+
+type fcpxmlMarker struct {
+	Start    string `xml:"start,attr"`
+	Duration string `xml:"duration,attr"`
+	Value    string `xml:"value,attr"`
+	Note     string `xml:"note,attr,omitempty"`
+}
+
+// buildMarkerFCPXML writes a project holding the whole VOD with the markers on it
+func buildMarkerFCPXML(timeline markerTimeline, markers []EditorMarker) ([]byte, error) {
+	duration := timeline.rationalTime(timeline.frames(timeline.duration))
+
+	doc := fcpxml{Version: "1.9"}
+	doc.Resources.Format.ID = "r1"
+	doc.Resources.Format.FrameDuration = timeline.rationalTime(1)
+	doc.Resources.Format.Width = timeline.width
+	doc.Resources.Format.Height = timeline.height
+
+	asset := &doc.Resources.Asset
+	asset.ID = "r2"
+	asset.Name = timeline.name
+	asset.Start = "0s"
+	asset.Duration = duration
+	asset.HasVideo = "1"
+	asset.HasAudio = "1"
+	asset.Format = "r1"
+	asset.MediaRep.Kind = "original-media"
+	asset.MediaRep.Src = fileURL(timeline.videoPath)
+
+	doc.Event.Name = timeline.name
+	doc.Event.Project.Name = timeline.name + " markers"
+	sequence := &doc.Event.Project.Sequence
+	sequence.Format = "r1"
+	sequence.Duration = duration
+	sequence.TCStart = "0s"
+	sequence.TCFormat = "NDF"
+	sequence.Clip.Ref = "r2"
+	sequence.Clip.Offset = "0s"
+	sequence.Clip.Name = timeline.name
+	sequence.Clip.Start = "0s"
+	sequence.Clip.Duration = duration
+
+	for _, marker := range markers {
+		start, length := timeline.markerFrameRange(marker)
+		sequence.Clip.Markers = append(sequence.Clip.Markers, fcpxmlMarker{
+			Start:    timeline.rationalTime(start),
+			Duration: timeline.rationalTime(length),
+			Value:    marker.Name,
+			Note:     marker.Note,
+		})
+	}
+
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []byte(xml.Header + "<!DOCTYPE fcpxml>\n" + string(body) + "\n"), nil
+}
+
+// buildMarkerCSV writes the markers in the column layout of Resolve's edit
+// index, plus start/end seconds so the file can be read back for batch
+// clipping. The index and timecode duration columns are named so the batch
+// clip reader doesn't take the header for a comment or the timecode for the
+// clip length.
+func buildMarkerCSV(timeline markerTimeline, markers []EditorMarker) ([]byte, error) {
+	var sb strings.Builder
+	writer := csv.NewWriter(&sb)
+	writer.Write([]string{"Index", "Name", "Notes", "Color", "Source In", "Source Out", "Duration TC", "Start", "End"})
+	for i, marker := range markers {
+		start, length := timeline.markerFrameRange(marker)
+		writer.Write([]string{
+			fmt.Sprint(i + 1),
+			marker.Name,
+			marker.Note,
+			markerColor(marker),
+			timeline.timecode(start),
+			timeline.timecode(start + length),
+			timeline.timecode(length),
+			fmt.Sprintf("%.3f", marker.Time),
+			fmt.Sprintf("%.3f", marker.Time+marker.Duration),
+		})
+	}
+	writer.Flush()
+	return []byte(sb.String()), writer.Error()
+}
+
+// markerColor returns the marker's Resolve colour, or the default for its kind
+func markerColor(marker EditorMarker) string {
+	for _, color := range resolveMarkerColors {
+		if strings.EqualFold(color, marker.Color) {
+			return color
+		}
+	}
+	switch marker.Kind {
+	case MarkerKindHighlight:
+		return "Green"
+	case MarkerKindTip:
+		return "Yellow"
+	default:
+		return "Blue"
+	}
+}
+
+// fileURL converts a local path to a file:// URL
+func fileURL(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		// Windows drive paths become file:///C:/...
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMarkerCSVReadsBackForBatchClipping(t *testing.T) {
+	timeline := markerTimeline{name: "stream", rateNumerator: 30000, rateDenom: 1001, width: 1920, height: 1080, duration: 7200}
+	markers := []EditorMarker{
+		{Time: 12.5, Duration: 30, Name: "Opening", Note: "first song"},
+		{Time: 3661.25, Duration: 95.5, Name: "Raid, with comma", Kind: MarkerKindHighlight},
+	}
+
+	content, err := buildMarkerCSV(timeline, markers)
+	if err != nil {
+		t.Fatalf("buildMarkerCSV: %v", err)
+	}
+	rows, err := parseMarkerTable(string(content), false)
+	if err != nil {
+		t.Fatalf("parseMarkerTable: %v", err)
+	}
+	if len(rows) != len(markers) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(markers))
+	}
+	for i, row := range rows {
+		marker := markers[i]
+		if row.Problem != "" {
+			t.Errorf("row %d: unexpected problem %q", i, row.Problem)
+		}
+		if row.Title != marker.Name {
+			t.Errorf("row %d: title %q, want %q", i, row.Title, marker.Name)
+		}
+		if math.Abs(row.StartTime-marker.Time) > 0.001 || math.Abs(row.EndTime-(marker.Time+marker.Duration)) > 0.001 {
+			t.Errorf("row %d: range %.3f-%.3f, want %.3f-%.3f", i, row.StartTime, row.EndTime, marker.Time, marker.Time+marker.Duration)
+		}
+	}
+}
+
+func TestMarkerEDLReadsBackForBatchClipping(t *testing.T) {
+	timeline := markerTimeline{name: "stream", rateNumerator: 30000, rateDenom: 1001, width: 1920, height: 1080, duration: 7200}
+	markers := []EditorMarker{
+		{Time: 12.5, Duration: 30, Name: "Opening"},
+		{Time: 3600, Duration: 95.5, Name: "Raid", Kind: MarkerKindHighlight},
+	}
+	frameRate := float64(timeline.rateNumerator) / float64(timeline.rateDenom)
+
+	rows, err := parseEDL(buildMarkerEDL(timeline, markers), frameRate)
+	if err != nil {
+		t.Fatalf("parseEDL: %v", err)
+	}
+	if len(rows) != len(markers) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(markers))
+	}
+	// Times are rounded to whole frames on the way out
+	tolerance := 1 / frameRate
+	for i, row := range rows {
+		marker := markers[i]
+		if row.Title != marker.Name {
+			t.Errorf("row %d: title %q, want %q", i, row.Title, marker.Name)
+		}
+		if math.Abs(row.StartTime-marker.Time) > tolerance || math.Abs(row.EndTime-(marker.Time+marker.Duration)) > tolerance {
+			t.Errorf("row %d: range %.3f-%.3f, want %.3f-%.3f", i, row.StartTime, row.EndTime, marker.Time, marker.Time+marker.Duration)
+		}
+	}
+}
+
+func TestParseDropFrameTimecode(t *testing.T) {
+	// 01:00:00;00 is 107892 frames into drop-frame timecode at 29.97 fps
+	frameRate := 30000.0 / 1001.0
+	if got, want := parseTimecode("01:00:00;00", frameRate), 107892/frameRate; math.Abs(got-want) > 0.0001 {
+		t.Errorf("drop-frame timecode: got %.4f, want %.4f", got, want)
+	}
+	if got, want := parseTimecode("00:10:00;00", frameRate), 17982/frameRate; math.Abs(got-want) > 0.0001 {
+		t.Errorf("drop-frame timecode: got %.4f, want %.4f", got, want)
+	}
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MediaTracks lists the audio and subtitle tracks of a video
+type MediaTracks struct {
+	Audio     []MediaStream `json:"audio"`
+	Subtitles []MediaStream `json:"subtitles"`
+}
+
+// TrackSelection picks the audio tracks used for playback or a clip
+type TrackSelection struct {
+	AudioStreams []int `json:"audioStreams"` // Stream indexes of the audio tracks, empty for the default track
+	MixAudio     bool  `json:"mixAudio"`     // Mix the selected tracks into one track, otherwise the first one is played
+}
+
+// TrackVariant is a copy of a video that plays a selection of audio tracks
+type TrackVariant struct {
+	VideoPath    string         `json:"videoPath"`
+	Selection    TrackSelection `json:"selection"`
+	URL          string         `json:"url,omitempty"`          // Empty until the variant is built
+	Building     bool           `json:"building"`               // Set while the variant is built in the background
+	ErrorMessage string         `json:"errorMessage,omitempty"` // Why building the variant failed
+}
+
+// TrackService builds remuxed playback variants of multi-track videos and
+// converts embedded subtitles to WebVTT. Both are cached per video hash and
+// served by the local media server under /tracks/.
+type TrackService struct {
+	tracksDir    string
+	cacheService *CacheService
+	mu           sync.Mutex
+	fileLocks    map[string]*sync.Mutex // Held while a track file is written
+	building     map[string]bool        // Variants being built in the background, by path
+	onReady      func(TrackVariant)
+}
+
+const tracksDirName = "track_variants"
+
+// Audio codecs the webview plays from an MP4 file without re-encoding
+var playableAudioCodecs = map[string]bool{"aac": true, "mp3": true}
+
+// Subtitle codecs that are images, which can't be converted to WebVTT
+var bitmapSubtitleCodecs = map[string]bool{
+	"hdmv_pgs_subtitle": true,
+	"dvd_subtitle":      true,
+	"dvb_subtitle":      true,
+	"xsub":              true,
+}
+
+// NewTrackService creates a new track service
+func NewTrackService(appDataDir string, cacheService *CacheService) *TrackService {
+	return &TrackService{
+		tracksDir:    filepath.Join(appDataDir, tracksDirName),
+		cacheService: cacheService,
+		fileLocks:    make(map[string]*sync.Mutex),
+		building:     make(map[string]bool),
+	}
+}
+
+// SetReadyHandler sets a function called when a track variant built in the
+// background is ready or failed
+func (s *TrackService) SetReadyHandler(onReady func(TrackVariant)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onReady = onReady
+}
+
+// TracksDir returns the directory served under /tracks/
+func (s *TrackService) TracksDir() string {
+	return s.tracksDir
+}
+
+// GetTracks lists the audio and subtitle tracks of a video
+func (s *TrackService) GetTracks(videoPath string) (MediaTracks, error) {
+	media, err := s.cacheService.GetMediaInfo(videoPath)
+	if err != nil {
+		return MediaTracks{}, fmt.Errorf("failed to read video: %v", err)
+	}
+	return MediaTracks{
+		Audio:     media.AudioStreams(),
+		Subtitles: media.streamsOfType("subtitle"),
+	}, nil
+}
+
+// PrepareTrackVariant returns a copy of the video that plays the selected
+// audio tracks. The video stream is copied as is. A variant that doesn't
+// exist yet is built in the background and the result has Building set; the
+// ready handler receives the variant when it's done.
+func (s *TrackService) PrepareTrackVariant(videoPath string, selection TrackSelection) (TrackVariant, error) {
+	media, err := s.cacheService.GetMediaInfo(videoPath)
+	if err != nil {
+		return TrackVariant{}, fmt.Errorf("failed to read video: %v", err)
+	}
+	if err := selection.validate(media); err != nil {
+		return TrackVariant{}, err
+	}
+	if len(selection.AudioStreams) == 0 {
+		return TrackVariant{}, errors.New("no audio track selected")
+	}
+	hash, err := s.cacheService.GetFileHash(videoPath)
+	if err != nil {
+		return TrackVariant{}, fmt.Errorf("failed to hash video: %v", err)
+	}
+
+	fileName := "audio_" + selection.key() + ".mp4"
+	variantPath := filepath.Join(s.tracksDir, hash, fileName)
+	variant := TrackVariant{VideoPath: videoPath, Selection: selection}
+	if _, err := os.Stat(variantPath); err == nil {
+		variant.URL = trackURL(hash, fileName)
+		return variant, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	variant.Building = true
+	if s.building[variantPath] {
+		return variant, nil
+	}
+	s.building[variantPath] = true
+
+	go func() {
+		err := s.buildTrackVariant(videoPath, media, selection, variantPath)
+
+		s.mu.Lock()
+		delete(s.building, variantPath)
+		onReady := s.onReady
+		s.mu.Unlock()
+
+		result := TrackVariant{VideoPath: videoPath, Selection: selection}
+		if err != nil {
+			fmt.Printf("Failed to build track variant of %s: %v\n", videoPath, err)
+			result.ErrorMessage = err.Error()
+		} else {
+			result.URL = trackURL(hash, fileName)
+		}
+		if onReady != nil {
+			onReady(result)
+		}
+	}()
+	return variant, nil
+}
+
+// buildTrackVariant remuxes the video with the selected audio tracks into variantPath
+func (s *TrackService) buildTrackVariant(videoPath string, media MediaInfo, selection TrackSelection, variantPath string) error {
+	unlock := s.lockFile(variantPath)
+	defer unlock()
+
+	if _, err := os.Stat(variantPath); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(variantPath), 0755); err != nil {
+		return err
+	}
+
+	graph := &filterGraph{}
+	audio := selectClipAudio(graph, 0, selection)
+	args := []string{"-i", videoPath}
+	if !graph.empty() {
+		args = append(args, "-filter_complex", graph.String())
+	}
+	args = append(args, "-map", "0:v:0", "-map", streamMapArg(audio), "-c:v", "copy")
+	if graph.empty() && len(selection.AudioStreams) == 1 && playableAudioCodecs[selectedCodec(media, selection.AudioStreams[0])] {
+		args = append(args, "-c:a", "copy")
+	} else {
+		args = append(args, "-c:a", "aac", "-b:a", "192k")
+	}
+
+	tempPath := strings.TrimSuffix(variantPath, ".mp4") + ".tmp.mp4"
+	args = append(args, "-movflags", "+faststart", "-y", tempPath)
+	if err := runFFmpeg(filepath.Dir(variantPath), args); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	if err := os.Rename(tempPath, variantPath); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
+
+// lockFile locks a track file while it's written, so a second request for
+// the same file waits and then finds it ready while other files are written
+// at the same time. It returns the function that unlocks the file.
+func (s *TrackService) lockFile(path string) func() {
+	s.mu.Lock()
+	lock, exists := s.fileLocks[path]
+	if !exists {
+		lock = &sync.Mutex{}
+		s.fileLocks[path] = lock
+	}
+	s.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// PrepareSubtitleTrack converts an embedded subtitle track to WebVTT and
+// returns its URL
+func (s *TrackService) PrepareSubtitleTrack(videoPath string, streamIndex int) (string, error) {
+	media, err := s.cacheService.GetMediaInfo(videoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read video: %v", err)
+	}
+	var subtitle *MediaStream
+	for i := range media.Streams {
+		if media.Streams[i].Index == streamIndex && media.Streams[i].Type == "subtitle" {
+			subtitle = &media.Streams[i]
+		}
+	}
+	if subtitle == nil {
+		return "", fmt.Errorf("stream %d is not a subtitle track", streamIndex)
+	}
+	if bitmapSubtitleCodecs[subtitle.Codec] {
+		return "", fmt.Errorf("%s subtitles are images and can't be converted to WebVTT", subtitle.Codec)
+	}
+
+	hash, err := s.cacheService.GetFileHash(videoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash video: %v", err)
+	}
+	fileName := fmt.Sprintf("subtitle_%d.vtt", streamIndex)
+	trackPath := filepath.Join(s.tracksDir, hash, fileName)
+
+	unlock := s.lockFile(trackPath)
+	defer unlock()
+
+	if _, err := os.Stat(trackPath); err == nil {
+		return trackURL(hash, fileName), nil
+	}
+	if err := os.MkdirAll(filepath.Dir(trackPath), 0755); err != nil {
+		return "", err
+	}
+
+	tempPath := trackPath + ".tmp"
+	err = runFFmpeg(filepath.Dir(trackPath), []string{
+		"-i", videoPath,
+		"-map", fmt.Sprintf("0:%d", streamIndex),
+		"-c:s", "webvtt",
+		"-f", "webvtt",
+		"-y", tempPath,
+	})
+	if err != nil {
+		os.Remove(tempPath)
+		return "", err
+	}
+	if err := os.Rename(tempPath, trackPath); err != nil {
+		os.Remove(tempPath)
+		return "", err
+	}
+	return trackURL(hash, fileName), nil
+}
+
+// selectClipAudio returns the audio of input for a track selection: the
+// first audio track when nothing is selected, the selected track, or the
+// selected tracks mixed into one
+func selectClipAudio(graph *filterGraph, input int, selection TrackSelection) string {
+	switch {
+	case len(selection.AudioStreams) == 0:
+		return fmt.Sprintf("[%d:a:0]", input)
+	case len(selection.AudioStreams) == 1 || !selection.MixAudio:
+		return fmt.Sprintf("[%d:%d]", input, selection.AudioStreams[0])
+	}
+
+	var inputs strings.Builder
+	for _, stream := range selection.AudioStreams {
+		inputs.WriteString(fmt.Sprintf("[%d:%d]", input, stream))
+	}
+	// Keep each track at its own level rather than averaging them
+	return graph.chain(inputs.String(), fmt.Sprintf("amix=inputs=%d:duration=longest:normalize=0", len(selection.AudioStreams)))
+}
+
+// validate checks that every selected stream is an audio track of the video
+func (t TrackSelection) validate(media MediaInfo) error {
+	for _, index := range t.AudioStreams {
+		if selectedCodec(media, index) == "" {
+			return fmt.Errorf("stream %d is not an audio track", index)
+		}
+	}
+	return nil
+}
+
+// selected reports whether tracks other than the default were chosen
+func (t TrackSelection) selected() bool {
+	return len(t.AudioStreams) > 0
+}
+
+// key names a track selection in cache file names, e.g. "1+2_mix"
+func (t TrackSelection) key() string {
+	parts := make([]string, 0, len(t.AudioStreams))
+	for _, index := range t.AudioStreams {
+		parts = append(parts, strconv.Itoa(index))
+	}
+	key := strings.Join(parts, "+")
+	if t.MixAudio && len(t.AudioStreams) > 1 {
+		key += "_mix"
+	}
+	return key
+}
+
+// selectedCodec returns the codec of an audio stream, or "" if index isn't an audio stream
+func selectedCodec(media MediaInfo, index int) string {
+	for _, stream := range media.AudioStreams() {
+		if stream.Index == index {
+			return stream.Codec
+		}
+	}
+	return ""
+}
+
+// trackURL returns the media server URL of a file in a video's track variants directory
+func trackURL(hash string, fileName string) string {
+	return mediaServerURL + "/tracks/" + hash + "/" + fileName
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// mp4Atom is a top-level box of an MP4 file
+type mp4Atom struct {
+	kind   string
+	offset int64
+	size   int64
+}
+
+// mp4Layout is the top-level structure of an MP4 file
+type mp4Layout struct {
+	atoms     []mp4Atom
+	truncated bool // The last box runs past the end of the file
+}
+
+// mp4Extensions are the containers laid out as MP4 boxes
+var mp4Extensions = map[string]bool{".mp4": true, ".m4v": true, ".mov": true}
+
+// isMP4File reports whether a file is an MP4 by its extension
+func isMP4File(path string) bool {
+	return mp4Extensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// readMP4Layout walks the top-level boxes of an MP4 file, reading only their headers
+func readMP4Layout(path string) (mp4Layout, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return mp4Layout{}, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return mp4Layout{}, err
+	}
+	fileSize := info.Size()
+
+	layout := mp4Layout{}
+	header := make([]byte, 16)
+	for offset := int64(0); offset < fileSize; {
+		if _, err := file.ReadAt(header[:8], offset); err != nil {
+			if err == io.EOF {
+				layout.truncated = true
+				break
+			}
+			return layout, err
+		}
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		atom := mp4Atom{kind: string(header[4:8]), offset: offset, size: size}
+		switch size {
+		case 0:
+			// The box runs to the end of the file
+			atom.size = fileSize - offset
+		case 1:
+			// A 64-bit size follows the type
+			if _, err := file.ReadAt(header[8:16], offset+8); err != nil {
+				layout.truncated = true
+				return layout, nil
+			}
+			atom.size = int64(binary.BigEndian.Uint64(header[8:16]))
+		}
+		if atom.size < 8 {
+			// Not a box header, the rest of the file is unreadable
+			layout.truncated = true
+			break
+		}
+		layout.atoms = append(layout.atoms, atom)
+		if offset+atom.size > fileSize {
+			layout.truncated = true
+			break
+		}
+		offset += atom.size
+	}
+	return layout, nil
+}
+
+// find returns the first top-level box of a type
+func (l mp4Layout) find(kind string) (mp4Atom, bool) {
+	for _, atom := range l.atoms {
+		if atom.kind == kind {
+			return atom, true
+		}
+	}
+	return mp4Atom{}, false
+}
+
+// hasIndex reports whether the file has its moov box, without which nothing can play it
+func (l mp4Layout) hasIndex() bool {
+	_, found := l.find("moov")
+	return found
+}
+
+// faststart reports whether the moov box comes before the media data, so
+// playback can start without fetching the end of the file
+func (l mp4Layout) faststart() bool {
+	moov, found := l.find("moov")
+	if !found {
+		return false
+	}
+	mdat, found := l.find("mdat")
+	return !found || moov.offset < mdat.offset
+}
@@ -0,0 +1,401 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"FanslyArchivePlayer/backend/models"
+)
+
+// RecordingPart is one file of a recording that was split into several files
+type RecordingPart struct {
+	Path     string  `json:"path"`
+	ChatPath string  `json:"chatPath,omitempty"`
+	Offset   float64 `json:"offset"` // Where the part starts on the recording's timeline
+	Duration float64 `json:"duration"`
+}
+
+// RecordingGroup is a recording made of several part files, played as one timeline
+type RecordingGroup struct {
+	Name       string          `json:"name"`
+	Parts      []RecordingPart `json:"parts"`
+	Duration   float64         `json:"duration"`
+	DetectedBy string          `json:"detectedBy"` // "name" for _partN files, "timing" for back-to-back recordings
+}
+
+// RecordingPosition is a point on a recording's timeline resolved to a part
+type RecordingPosition struct {
+	PartIndex int     `json:"partIndex"`
+	PartPath  string  `json:"partPath"`
+	LocalTime float64 `json:"localTime"` // Time within the part
+	VideoURL  string  `json:"videoUrl,omitempty"`
+	Switched  bool    `json:"switched"` // Set when a different part had to be loaded
+}
+
+// RecordingService finds the parts of split recordings
+type RecordingService struct {
+	cacheService *CacheService
+}
+
+// clipInputRange is the range of one input file used for a clip
+type clipInputRange struct {
+	path     string
+	start    float64
+	duration float64
+}
+
+const (
+	// Recorders reconnect quickly, so parts further apart than this are separate streams
+	maxPartGap = 10 * time.Minute
+	// Estimated start times are rough, allow parts to overlap by this much
+	maxPartOverlap = time.Minute
+)
+
+var (
+	partNumberInName     = regexp.MustCompile(`(?i)^(.*?)[ _.-]*(?:part|pt)[ _.-]*(\d+)$`)
+	recordingTimeInName  = regexp.MustCompile(`(20\d{2})[-_.]?(\d{2})[-_.]?(\d{2})[ _T-]?(\d{2})[-_.:]?(\d{2})[-_.:]?(\d{2})`)
+	recordingNameNumbers = regexp.MustCompile(`[\d _.-]+`)
+	recordingExtensions  = map[string]bool{".mp4": true, ".mkv": true, ".webm": true, ".ts": true, ".mov": true, ".flv": true, ".avi": true}
+)
+
+// NewRecordingService creates a new recording service
+func NewRecordingService(cacheService *CacheService) *RecordingService {
+	return &RecordingService{cacheService: cacheService}
+}
+
+// FindRecordingGroup returns the multi-part recording videoPath belongs to,
+// looking at the other videos in its folder
+func (s *RecordingService) FindRecordingGroup(videoPath string) (RecordingGroup, bool) {
+	entries, err := os.ReadDir(filepath.Dir(videoPath))
+	if err != nil {
+		return RecordingGroup{}, false
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if !entry.IsDir() && recordingExtensions[strings.ToLower(filepath.Ext(entry.Name()))] {
+			paths = append(paths, filepath.Join(filepath.Dir(videoPath), entry.Name()))
+		}
+	}
+
+	for _, group := range s.DetectRecordingGroups(paths) {
+		for _, part := range group.Parts {
+			if part.Path == videoPath {
+				return group, true
+			}
+		}
+	}
+	return RecordingGroup{}, false
+}
+
+// DetectRecordingGroups finds recordings split into several files. Files named
+// _part1, _part2 ... are grouped by name; other files in the same folder with
+// the same name apart from dates and numbers are grouped when each one starts
+// shortly after the previous one ended.
+func (s *RecordingService) DetectRecordingGroups(paths []string) []RecordingGroup {
+	var groups []RecordingGroup
+	grouped := make(map[string]bool)
+
+	// Group by _partN names
+	byName := make(map[string][]string)
+	partNumbers := make(map[string]int)
+	for _, path := range paths {
+		stem := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if match := partNumberInName.FindStringSubmatch(stem); match != nil {
+			key := filepath.Join(filepath.Dir(path), strings.ToLower(match[1]))
+			byName[key] = append(byName[key], path)
+			partNumbers[path], _ = strconv.Atoi(match[2])
+		}
+	}
+	for _, path := range paths {
+		// The first part is often saved without a suffix, e.g. stream.mp4 then stream_part2.mp4
+		stem := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		key := filepath.Join(filepath.Dir(path), strings.ToLower(stem))
+		if members, exists := byName[key]; exists && !containsPartNumber(members, partNumbers, 1) {
+			byName[key] = append(members, path)
+			partNumbers[path] = 1
+		}
+	}
+	for _, members := range byName {
+		if len(members) < 2 {
+			continue
+		}
+		sort.Slice(members, func(i, j int) bool { return partNumbers[members[i]] < partNumbers[members[j]] })
+		if group, ok := s.buildRecordingGroup(members, "name"); ok {
+			groups = append(groups, group)
+			for _, member := range members {
+				grouped[member] = true
+			}
+		}
+	}
+
+	// Group the remaining files by timing
+	type timedFile struct {
+		path       string
+		start, end time.Time
+	}
+	byFolder := make(map[string][]timedFile)
+	for _, path := range paths {
+		if grouped[path] {
+			continue
+		}
+		start, end, ok := s.recordingTimes(path)
+		if !ok {
+			continue
+		}
+		stem := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		key := filepath.Join(filepath.Dir(path), strings.ToLower(recordingNameNumbers.ReplaceAllString(stem, "")))
+		byFolder[key] = append(byFolder[key], timedFile{path: path, start: start, end: end})
+	}
+	for _, files := range byFolder {
+		sort.Slice(files, func(i, j int) bool { return files[i].start.Before(files[j].start) })
+
+		run := []string{}
+		var runEnd time.Time
+		flush := func() {
+			if len(run) > 1 {
+				if group, ok := s.buildRecordingGroup(run, "timing"); ok {
+					groups = append(groups, group)
+				}
+			}
+		}
+		for _, file := range files {
+			gap := file.start.Sub(runEnd)
+			if len(run) > 0 && gap >= -maxPartOverlap && gap <= maxPartGap {
+				run = append(run, file.path)
+			} else {
+				flush()
+				run = []string{file.path}
+			}
+			runEnd = file.end
+		}
+		flush()
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Parts[0].Path < groups[j].Parts[0].Path })
+	return groups
+}
+
+// containsPartNumber reports whether one of the paths has the given part number
+func containsPartNumber(paths []string, partNumbers map[string]int, number int) bool {
+	for _, path := range paths {
+		if partNumbers[path] == number {
+			return true
+		}
+	}
+	return false
+}
+
+// recordingTimes estimates when a file's recording started and ended, from a
+// date and time in its name or else from its modification time, which is
+// when the recorder stopped writing
+func (s *RecordingService) recordingTimes(path string) (time.Time, time.Time, bool) {
+	duration, err := s.cacheService.GetVideoDuration(path)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	length := time.Duration(duration * float64(time.Second))
+
+	if match := recordingTimeInName.FindStringSubmatch(filepath.Base(path)); match != nil {
+		if start, err := time.ParseInLocation("20060102150405", strings.Join(match[1:], ""), time.Local); err == nil {
+			return start, start.Add(length), true
+		}
+	}
+
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	end := fileInfo.ModTime()
+	return end.Add(-length), end, true
+}
+
+// buildRecordingGroup lays the parts out on one timeline
+func (s *RecordingService) buildRecordingGroup(paths []string, detectedBy string) (RecordingGroup, bool) {
+	group := RecordingGroup{DetectedBy: detectedBy}
+	for _, path := range paths {
+		duration, err := s.cacheService.GetVideoDuration(path)
+		if err != nil {
+			fmt.Printf("Failed to read duration of %s: %v\n", path, err)
+			return RecordingGroup{}, false
+		}
+
+		part := RecordingPart{Path: path, Offset: group.Duration, Duration: duration}
+		chatPath := strings.TrimSuffix(path, filepath.Ext(path)) + "_chat.json"
+		if _, err := os.Stat(chatPath); err == nil {
+			part.ChatPath = chatPath
+		}
+		group.Parts = append(group.Parts, part)
+		group.Duration += duration
+	}
+
+	stem := strings.TrimSuffix(filepath.Base(paths[0]), filepath.Ext(paths[0]))
+	if match := partNumberInName.FindStringSubmatch(stem); match != nil && match[1] != "" {
+		stem = match[1]
+	}
+	group.Name = stem
+	return group, true
+}
+
+// Locate resolves a time on the recording's timeline to a part and the time within it
+func (g RecordingGroup) Locate(timelineTime float64) (int, float64) {
+	for i, part := range g.Parts {
+		if timelineTime < part.Offset+part.Duration || i == len(g.Parts)-1 {
+			local := timelineTime - part.Offset
+			if local < 0 {
+				local = 0
+			}
+			return i, local
+		}
+	}
+	return 0, 0
+}
+
+// LoadRecordingChat stitches the chat files of the parts into one chat on the
+// recording's timeline. Each part's messages are shifted by the part's
+// offset; messages saved in more than one part's file are kept once.
+func LoadRecordingChat(group RecordingGroup) []models.ChatMessage {
+	var stitched []models.ChatMessage
+	seen := make(map[string]bool)
+	for _, part := range group.Parts {
+		if part.ChatPath == "" {
+			continue
+		}
+		messages, err := ParseChatFile(part.ChatPath)
+		if err != nil {
+			fmt.Printf("Failed to load chat for %s: %v\n", part.Path, err)
+			continue
+		}
+		for _, msg := range messages {
+			if msg.MessageID != "" {
+				if seen[msg.MessageID] {
+					continue
+				}
+				seen[msg.MessageID] = true
+			}
+			msg.TimeInSeconds += part.Offset
+			msg.TimeText = formatTimeText(msg.TimeInSeconds)
+			stitched = append(stitched, msg)
+		}
+	}
+
+	sort.SliceStable(stitched, func(i, j int) bool {
+		return stitched[i].TimeInSeconds < stitched[j].TimeInSeconds
+	})
+	if stitched == nil {
+		return []models.ChatMessage{}
+	}
+	return stitched
+}
+
+// LoadRecordingTranscript stitches the transcripts of the parts into one
+// transcript on the recording's timeline, shifting each part's cues by the
+// part's offset. The first part with a transcript sets its path and format.
+func LoadRecordingTranscript(group RecordingGroup) Transcript {
+	stitched := Transcript{Cues: []models.TranscriptCue{}}
+	for _, part := range group.Parts {
+		for _, transcriptPath := range FindTranscriptFiles(part.Path) {
+			transcript, err := ParseTranscriptFile(transcriptPath)
+			if err != nil {
+				fmt.Printf("Failed to load transcript %s: %v\n", transcriptPath, err)
+				continue
+			}
+			if stitched.Path == "" {
+				stitched.Path, stitched.Format, stitched.Language = transcript.Path, transcript.Format, transcript.Language
+			}
+			for _, cue := range transcript.Cues {
+				cue.Start += part.Offset
+				cue.End += part.Offset
+				stitched.Cues = append(stitched.Cues, cue)
+			}
+			break
+		}
+	}
+	return stitched
+}
+
+// inputRanges returns the input files and ranges covering a clip. Clips of
+// a single file use path; clips of a multi-part recording use the parts the
+// range overlaps, with startTime on the recording's timeline.
+func (s ClipSource) inputRanges(path string, startTime float64, duration float64) []clipInputRange {
+	if len(s.Parts) == 0 {
+		return []clipInputRange{{path: path, start: startTime, duration: duration}}
+	}
+
+	var ranges []clipInputRange
+	endTime := startTime + duration
+	for _, part := range s.Parts {
+		start := maxFloat(startTime, part.Offset)
+		end := minFloat(endTime, part.Offset+part.Duration)
+		// Skip slivers shorter than a frame at the part boundaries
+		if end-start < 0.04 {
+			continue
+		}
+		ranges = append(ranges, clipInputRange{path: part.Path, start: start - part.Offset, duration: end - start})
+	}
+	return ranges
+}
+
+// joinClipInputs concatenates the source inputs of a clip spanning several
+// parts and returns the video and audio labels ("" when no part has audio).
+// Parts without audio are filled with silence, and when the parts' audio
+// differs it's converted to one format, since concat needs matching inputs.
+func joinClipInputs(graph *filterGraph, ranges []clipInputRange, parts []MediaInfo, tracks TrackSelection) (string, string) {
+	hasAudio, uniform := false, true
+	first := parts[0].AudioStreams()
+	for _, part := range parts {
+		streams := part.AudioStreams()
+		hasAudio = hasAudio || len(streams) > 0
+		if len(streams) == 0 || len(streams) != len(first) ||
+			streams[0].SampleRate != first[0].SampleRate || streams[0].ChannelLayout != first[0].ChannelLayout {
+			uniform = false
+		}
+	}
+
+	audio := make([]string, len(ranges))
+	if hasAudio {
+		for i, part := range parts {
+			if !part.HasAudio() {
+				silence := graph.addInput("-f", "lavfi", "-t", formatFFmpegTime(ranges[i].duration),
+					"-i", "anullsrc=r="+polishAudioSampleRate+":cl="+polishAudioChannelSpec)
+				audio[i] = fmt.Sprintf("[%d:a]", silence)
+				continue
+			}
+			// The selection names streams of the first part; parts with
+			// other tracks use their default one
+			selection := tracks
+			if selection.validate(part) != nil {
+				selection = TrackSelection{}
+			}
+			audio[i] = selectClipAudio(graph, i, selection)
+			if !uniform {
+				audio[i] = graph.chain(audio[i], "aformat=sample_rates="+polishAudioSampleRate+":channel_layouts="+polishAudioChannelSpec)
+			}
+		}
+	}
+
+	var inputs strings.Builder
+	for i := range ranges {
+		inputs.WriteString(fmt.Sprintf("[%d:v:0]", i))
+		inputs.WriteString(audio[i])
+	}
+	if !hasAudio {
+		return graph.chain(inputs.String(), fmt.Sprintf("concat=n=%d:v=1:a=0", len(ranges))), ""
+	}
+	joined := graph.chainOutputs(inputs.String(), fmt.Sprintf("concat=n=%d:v=1:a=1", len(ranges)), 2)
+	return joined[0], joined[1]
+}
+
+// maxFloat returns the larger of a and b
+func maxFloat(a float64, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
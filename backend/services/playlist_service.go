@@ -0,0 +1,389 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Playlist is a named list of videos, or a smart playlist whose videos are
+// picked by a rule each time it's played
+type Playlist struct {
+	ID        string             `json:"id"`
+	Name      string             `json:"name"`
+	Items     []PlaylistItem     `json:"items"`
+	Smart     *SmartPlaylistRule `json:"smart,omitempty"`
+	CreatedAt time.Time          `json:"createdAt"`
+	UpdatedAt time.Time          `json:"updatedAt"`
+}
+
+// PlaylistItem is a video in a playlist
+type PlaylistItem struct {
+	VideoPath string `json:"videoPath"`
+	VideoHash string `json:"videoHash,omitempty"` // Used to find the video again if it's moved
+	Model     string `json:"model,omitempty"`
+	Date      string `json:"date,omitempty"` // Stream date, YYYY-MM-DD
+}
+
+// SmartPlaylistRule selects videos from the library
+type SmartPlaylistRule struct {
+	Model         string `json:"model,omitempty"` // Empty for every model
+	UnwatchedOnly bool   `json:"unwatchedOnly"`
+	NewestFirst   bool   `json:"newestFirst"` // Oldest first otherwise
+}
+
+// PlaylistCandidate is a library video a smart playlist can pick
+type PlaylistCandidate struct {
+	Path  string
+	Model string
+}
+
+// QueueState describes what plays after the current video
+type QueueState struct {
+	PlayNext     []PlaylistItem `json:"playNext"`             // Ad-hoc queue, played before the playlist continues
+	PlaylistID   string         `json:"playlistId,omitempty"` // Playlist being played, if any
+	PlaylistName string         `json:"playlistName,omitempty"`
+	Items        []PlaylistItem `json:"items"` // Videos of the playlist being played
+	Index        int            `json:"index"` // Position of the current video in Items, -1 before the first
+}
+
+// NowPlaying is the video loaded by the play queue
+type NowPlaying struct {
+	VideoPath    string  `json:"videoPath"`
+	VideoURL     string  `json:"videoUrl"`
+	ChatPath     string  `json:"chatPath,omitempty"`
+	ContactSheet string  `json:"contactSheet,omitempty"`
+	ResumeFrom   float64 `json:"resumeFrom"`
+	Finished     bool    `json:"finished"` // Set when the queue had nothing left to play
+}
+
+// PlaylistService stores playlists and keeps the play queue
+type PlaylistService struct {
+	appDataDir   string
+	cacheService *CacheService
+	watchHistory *WatchHistoryService
+	mu           sync.Mutex
+	queue        QueueState
+}
+
+const playlistsFileName = "playlists.json"
+
+// NewPlaylistService creates a new playlist service
+func NewPlaylistService(appDataDir string, cacheService *CacheService, watchHistory *WatchHistoryService) *PlaylistService {
+	return &PlaylistService{
+		appDataDir:   appDataDir,
+		cacheService: cacheService,
+		watchHistory: watchHistory,
+		queue:        QueueState{PlayNext: []PlaylistItem{}, Items: []PlaylistItem{}, Index: -1},
+	}
+}
+
+// GetPlaylists returns the saved playlists sorted by name
+func (s *PlaylistService) GetPlaylists() ([]Playlist, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	playlists, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(playlists, func(i, j int) bool {
+		return strings.ToLower(playlists[i].Name) < strings.ToLower(playlists[j].Name)
+	})
+	return playlists, nil
+}
+
+// CreatePlaylist creates an empty playlist, or a smart playlist if rule is set
+func (s *PlaylistService) CreatePlaylist(name string, rule *SmartPlaylistRule) (Playlist, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Playlist{}, errors.New("playlist name is required")
+	}
+	id, err := newRecordID()
+	if err != nil {
+		return Playlist{}, err
+	}
+
+	playlist := Playlist{
+		ID:        id,
+		Name:      name,
+		Items:     []PlaylistItem{},
+		Smart:     rule,
+		CreatedAt: time.Now(),
+	}
+	playlist.UpdatedAt = playlist.CreatedAt
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	playlists, err := s.load()
+	if err != nil {
+		return Playlist{}, err
+	}
+	return playlist, s.save(append(playlists, playlist))
+}
+
+// UpdatePlaylist saves a playlist's name, smart rule and item order
+func (s *PlaylistService) UpdatePlaylist(playlist Playlist) (Playlist, error) {
+	playlist.Name = strings.TrimSpace(playlist.Name)
+	if playlist.Name == "" {
+		return Playlist{}, errors.New("playlist name is required")
+	}
+	if playlist.Items == nil {
+		playlist.Items = []PlaylistItem{}
+	}
+
+	err := s.updatePlaylist(playlist.ID, func(existing *Playlist) error {
+		playlist.CreatedAt = existing.CreatedAt
+		playlist.UpdatedAt = time.Now()
+		*existing = playlist
+		return nil
+	})
+	return playlist, err
+}
+
+// DeletePlaylist removes a playlist
+func (s *PlaylistService) DeletePlaylist(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	playlists, err := s.load()
+	if err != nil {
+		return err
+	}
+	for i := range playlists {
+		if playlists[i].ID == id {
+			return s.save(append(playlists[:i], playlists[i+1:]...))
+		}
+	}
+	return errors.New("playlist not found")
+}
+
+// AddToPlaylist appends videos to a playlist
+func (s *PlaylistService) AddToPlaylist(id string, videoPaths []string, modelForPath func(string) string) error {
+	items := make([]PlaylistItem, 0, len(videoPaths))
+	for _, path := range videoPaths {
+		items = append(items, s.newItem(path, modelForPath(path)))
+	}
+
+	return s.updatePlaylist(id, func(playlist *Playlist) error {
+		if playlist.Smart != nil {
+			return errors.New("videos can't be added to a smart playlist")
+		}
+		playlist.Items = append(playlist.Items, items...)
+		playlist.UpdatedAt = time.Now()
+		return nil
+	})
+}
+
+// RemoveFromPlaylist removes a video from a playlist. The video is passed as
+// GetPlaylistItems shows it, so a video that was moved is matched by its hash.
+func (s *PlaylistService) RemoveFromPlaylist(id string, videoPath string) error {
+	// A video that no longer exists has no hash and is matched by path only
+	hash, _ := s.cacheService.GetFileHash(videoPath)
+
+	return s.updatePlaylist(id, func(playlist *Playlist) error {
+		for i, item := range playlist.Items {
+			if item.VideoPath == videoPath || (hash != "" && item.VideoHash == hash) {
+				playlist.Items = append(playlist.Items[:i], playlist.Items[i+1:]...)
+				playlist.UpdatedAt = time.Now()
+				return nil
+			}
+		}
+		return errors.New("playlist item not found")
+	})
+}
+
+// GetPlaylistItems returns the videos of a playlist. Smart playlists are
+// evaluated against the candidates; moved videos are found by their hash.
+func (s *PlaylistService) GetPlaylistItems(id string, candidates []PlaylistCandidate) (Playlist, []PlaylistItem, error) {
+	s.mu.Lock()
+	playlists, err := s.load()
+	s.mu.Unlock()
+	if err != nil {
+		return Playlist{}, nil, err
+	}
+
+	for _, playlist := range playlists {
+		if playlist.ID != id {
+			continue
+		}
+		if playlist.Smart != nil {
+			return playlist, s.evaluateSmartPlaylist(*playlist.Smart, candidates), nil
+		}
+
+		items := make([]PlaylistItem, 0, len(playlist.Items))
+		for _, item := range playlist.Items {
+			if _, err := os.Stat(item.VideoPath); err != nil {
+				if item.VideoHash == "" {
+					continue
+				}
+				if item.VideoPath = s.cacheService.FindPathByHash(item.VideoHash); item.VideoPath == "" {
+					continue
+				}
+			}
+			items = append(items, item)
+		}
+		return playlist, items, nil
+	}
+	return Playlist{}, nil, errors.New("playlist not found")
+}
+
+// evaluateSmartPlaylist picks and orders the candidates matching the rule
+func (s *PlaylistService) evaluateSmartPlaylist(rule SmartPlaylistRule, candidates []PlaylistCandidate) []PlaylistItem {
+	matching := []PlaylistCandidate{}
+	for _, candidate := range candidates {
+		if rule.Model == "" || strings.EqualFold(candidate.Model, rule.Model) {
+			matching = append(matching, candidate)
+		}
+	}
+	watched := map[string]bool{}
+	if rule.UnwatchedOnly {
+		paths := make([]string, 0, len(matching))
+		for _, candidate := range matching {
+			paths = append(paths, candidate.Path)
+		}
+		var err error
+		if watched, err = s.watchHistory.WatchedVideos(paths); err != nil {
+			fmt.Printf("Failed to read watch history: %v\n", err)
+			watched = map[string]bool{}
+		}
+	}
+
+	items := []PlaylistItem{}
+	for _, candidate := range matching {
+		if watched[candidate.Path] {
+			continue
+		}
+		items = append(items, PlaylistItem{
+			VideoPath: candidate.Path,
+			Model:     candidate.Model,
+			Date:      streamDate(candidate.Path),
+		})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		first, second := items[i], items[j]
+		if rule.NewestFirst {
+			first, second = second, first
+		}
+		if first.Date != second.Date {
+			return first.Date < second.Date
+		}
+		return first.VideoPath < second.VideoPath
+	})
+	return items
+}
+
+// StartPlaylist makes items the playing playlist. The returned item, the one
+// at startIndex, should be loaded next.
+func (s *PlaylistService) StartPlaylist(playlist Playlist, items []PlaylistItem, startIndex int) (PlaylistItem, error) {
+	if startIndex < 0 || startIndex >= len(items) {
+		return PlaylistItem{}, errors.New("playlist has no video at that position")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queue.PlaylistID = playlist.ID
+	s.queue.PlaylistName = playlist.Name
+	s.queue.Items = items
+	s.queue.Index = startIndex
+	return items[startIndex], nil
+}
+
+// QueuePlayNext adds a video to the ad-hoc queue that plays before the playlist continues
+func (s *PlaylistService) QueuePlayNext(videoPath string, model string) {
+	item := s.newItem(videoPath, model)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue.PlayNext = append(s.queue.PlayNext, item)
+}
+
+// ClearQueue empties the ad-hoc queue and stops the playing playlist
+func (s *PlaylistService) ClearQueue() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = QueueState{PlayNext: []PlaylistItem{}, Items: []PlaylistItem{}, Index: -1}
+}
+
+// GetQueue returns the ad-hoc queue and the playing playlist
+func (s *PlaylistService) GetQueue() QueueState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue := s.queue
+	queue.PlayNext = append([]PlaylistItem{}, s.queue.PlayNext...)
+	return queue
+}
+
+// Advance returns the next video to play: the first queued video, otherwise
+// the next video of the playing playlist. ok is false when nothing is left.
+func (s *PlaylistService) Advance() (PlaylistItem, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue.PlayNext) > 0 {
+		item := s.queue.PlayNext[0]
+		s.queue.PlayNext = s.queue.PlayNext[1:]
+		return item, true
+	}
+	if s.queue.Index+1 < len(s.queue.Items) {
+		s.queue.Index++
+		return s.queue.Items[s.queue.Index], true
+	}
+	return PlaylistItem{}, false
+}
+
+// newItem describes a video for a playlist or the queue
+func (s *PlaylistService) newItem(videoPath string, model string) PlaylistItem {
+	item := PlaylistItem{
+		VideoPath: videoPath,
+		Model:     model,
+		Date:      streamDate(videoPath),
+	}
+	if hash, err := s.cacheService.GetFileHash(videoPath); err == nil {
+		item.VideoHash = hash
+	}
+	return item
+}
+
+// updatePlaylist applies a change to one playlist and saves the list
+func (s *PlaylistService) updatePlaylist(id string, update func(playlist *Playlist) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	playlists, err := s.load()
+	if err != nil {
+		return err
+	}
+	for i := range playlists {
+		if playlists[i].ID == id {
+			if err := update(&playlists[i]); err != nil {
+				return err
+			}
+			return s.save(playlists)
+		}
+	}
+	return errors.New("playlist not found")
+}
+
+// load reads the saved playlists. Callers must hold mu.
+func (s *PlaylistService) load() ([]Playlist, error) {
+	playlists := []Playlist{}
+	if err := readJSONFile(filepath.Join(s.appDataDir, playlistsFileName), &playlists); err != nil {
+		return nil, fmt.Errorf("failed to read playlists: %v", err)
+	}
+	return playlists, nil
+}
+
+// save writes the playlists. Callers must hold mu.
+func (s *PlaylistService) save(playlists []Playlist) error {
+	return writeJSONFile(filepath.Join(s.appDataDir, playlistsFileName), playlists)
+}
@@ -0,0 +1,401 @@
+package services
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg" // Contact sheets are JPEG files
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SeekPreviews describes the seek-bar thumbnails track of a video
+type SeekPreviews struct {
+	VideoPath  string `json:"videoPath"`
+	VideoHash  string `json:"videoHash"`
+	TrackURL   string `json:"trackUrl,omitempty"` // WebVTT thumbnails track, empty while nothing is available
+	Source     string `json:"source,omitempty"`   // "sprites" or "contact_sheet"
+	Generating bool   `json:"generating"`         // Set while sprite sheets are being generated in the background
+}
+
+// SeekPreviewService builds thumbnail sprite sheets and WebVTT thumbnail
+// tracks for the seek bar. They are cached per video hash and served by the
+// local media server under /previews/.
+type SeekPreviewService struct {
+	previewsDir  string
+	cacheService *CacheService
+	mu           sync.Mutex
+	queue        []string
+	queued       map[string]bool
+	running      bool
+	onReady      func(SeekPreviews)
+}
+
+const (
+	seekPreviewsDirName     = "seek_previews"
+	spriteTrackFileName     = "thumbnails.vtt"
+	sheetTrackFileName      = "contact_sheet.vtt"
+	seekPreviewSource       = "sprites"
+	contactSheetSource      = "contact_sheet"
+	seekPreviewTileWidth    = 160
+	seekPreviewColumns      = 10
+	seekPreviewRows         = 10
+	minSeekPreviewInterval  = 2.0
+	maxSeekPreviewTiles     = 1000
+	mediaServerURL          = "http://localhost:8080"
+	sheetSeparatorDeviation = 6.0 // Rows and columns this uniform are gaps between contact sheet tiles
+)
+
+// NewSeekPreviewService creates a new seek preview service
+func NewSeekPreviewService(appDataDir string, cacheService *CacheService) *SeekPreviewService {
+	return &SeekPreviewService{
+		previewsDir:  filepath.Join(appDataDir, seekPreviewsDirName),
+		cacheService: cacheService,
+		queued:       make(map[string]bool),
+	}
+}
+
+// SetReadyHandler sets a function called when a video's sprite sheets have
+// been generated in the background
+func (s *SeekPreviewService) SetReadyHandler(onReady func(SeekPreviews)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onReady = onReady
+}
+
+// PreviewsDir returns the directory served under /previews/
+func (s *SeekPreviewService) PreviewsDir() string {
+	return s.previewsDir
+}
+
+// GetSeekPreviews returns the thumbnails track of a video. Until its sprite
+// sheets are generated, the video's contact sheet is sliced into a track
+// instead, and generation is queued.
+func (s *SeekPreviewService) GetSeekPreviews(videoPath string) (SeekPreviews, error) {
+	hash, err := s.cacheService.GetFileHash(videoPath)
+	if err != nil {
+		return SeekPreviews{}, fmt.Errorf("failed to hash video: %v", err)
+	}
+
+	previews := SeekPreviews{VideoPath: videoPath, VideoHash: hash}
+	if s.hasSprites(hash) {
+		previews.TrackURL = previewURL(hash, spriteTrackFileName)
+		previews.Source = seekPreviewSource
+		return previews, nil
+	}
+
+	s.QueueSeekPreviews([]string{videoPath})
+	previews.Generating = true
+
+	if sheetPath := FindContactSheet(s.cacheService, videoPath); sheetPath != "" {
+		if err := s.writeContactSheetTrack(videoPath, hash, sheetPath); err != nil {
+			fmt.Printf("Failed to slice contact sheet: %v\n", err)
+		} else {
+			previews.TrackURL = previewURL(hash, sheetTrackFileName)
+			previews.Source = contactSheetSource
+		}
+	}
+	return previews, nil
+}
+
+// QueueSeekPreviews queues sprite sheet generation for the videos that don't
+// have sprite sheets yet and returns how many were queued. Videos are
+// processed one at a time in the background.
+func (s *SeekPreviewService) QueueSeekPreviews(videoPaths []string) int {
+	var pending []string
+	for _, path := range videoPaths {
+		if hash, err := s.cacheService.GetFileHash(path); err == nil && !s.hasSprites(hash) {
+			pending = append(pending, path)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, path := range pending {
+		if s.queued[path] {
+			continue
+		}
+		s.queued[path] = true
+		s.queue = append(s.queue, path)
+		count++
+	}
+
+	if !s.running && len(s.queue) > 0 {
+		s.running = true
+		go s.processQueue()
+	}
+	return count
+}
+
+// processQueue generates sprite sheets for queued videos until the queue is empty
+func (s *SeekPreviewService) processQueue() {
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.running = false
+			s.mu.Unlock()
+			return
+		}
+		videoPath := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+
+		previews, err := s.GenerateSeekPreviews(videoPath)
+
+		s.mu.Lock()
+		delete(s.queued, videoPath)
+		onReady := s.onReady
+		s.mu.Unlock()
+
+		if err != nil {
+			fmt.Printf("Failed to generate seek previews for %s: %v\n", videoPath, err)
+			continue
+		}
+		if onReady != nil {
+			onReady(previews)
+		}
+	}
+}
+
+// GenerateSeekPreviews builds the sprite sheets and thumbnails track of a video
+func (s *SeekPreviewService) GenerateSeekPreviews(videoPath string) (SeekPreviews, error) {
+	hash, err := s.cacheService.GetFileHash(videoPath)
+	if err != nil {
+		return SeekPreviews{}, fmt.Errorf("failed to hash video: %v", err)
+	}
+	previews := SeekPreviews{
+		VideoPath: videoPath,
+		VideoHash: hash,
+		TrackURL:  previewURL(hash, spriteTrackFileName),
+		Source:    seekPreviewSource,
+	}
+	if s.hasSprites(hash) {
+		return previews, nil
+	}
+
+	media, err := s.cacheService.GetMediaInfo(videoPath)
+	if err != nil {
+		return SeekPreviews{}, fmt.Errorf("failed to read video: %v", err)
+	}
+	duration := media.Duration
+	width, height, err := media.VideoSize()
+	if err != nil {
+		return SeekPreviews{}, fmt.Errorf("failed to read video size: %v", err)
+	}
+	tileHeight := int(math.Round(float64(seekPreviewTileWidth)*float64(height)/float64(width)/2)) * 2
+
+	// Long streams get fewer thumbnails per minute so the sheets stay small
+	interval := math.Max(minSeekPreviewInterval, math.Ceil(duration/maxSeekPreviewTiles))
+	tileCount := int(math.Ceil(duration / interval))
+
+	dir := filepath.Join(s.previewsDir, hash)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return SeekPreviews{}, err
+	}
+
+	// Only keyframes are decoded, which is far faster on long streams and
+	// close enough for a preview
+	err = runFFmpeg(dir, []string{
+		"-skip_frame", "nokey",
+		"-i", videoPath,
+		"-vf", fmt.Sprintf("fps=1/%g,scale=%d:%d,tile=%dx%d", interval, seekPreviewTileWidth, tileHeight, seekPreviewColumns, seekPreviewRows),
+		"-an",
+		"-q:v", "5",
+		"-start_number", "0",
+		"-y", "sprite_%03d.jpg",
+	})
+	if err != nil {
+		return SeekPreviews{}, err
+	}
+
+	var cues []thumbnailCue
+	perSheet := seekPreviewColumns * seekPreviewRows
+	for i := 0; i < tileCount; i++ {
+		position := i % perSheet
+		cues = append(cues, thumbnailCue{
+			start: float64(i) * interval,
+			end:   math.Min(float64(i+1)*interval, duration),
+			image: fmt.Sprintf("sprite_%03d.jpg", i/perSheet),
+			tile: image.Rect(
+				position%seekPreviewColumns*seekPreviewTileWidth,
+				position/seekPreviewColumns*tileHeight,
+				position%seekPreviewColumns*seekPreviewTileWidth+seekPreviewTileWidth,
+				position/seekPreviewColumns*tileHeight+tileHeight,
+			),
+		})
+	}
+	// The track is written last, so its presence means the sprite sheets are complete
+	if err := writeThumbnailTrack(filepath.Join(dir, spriteTrackFileName), cues); err != nil {
+		return SeekPreviews{}, err
+	}
+	return previews, nil
+}
+
+// hasSprites reports whether the sprite sheets of a video have been generated
+func (s *SeekPreviewService) hasSprites(hash string) bool {
+	_, err := os.Stat(filepath.Join(s.previewsDir, hash, spriteTrackFileName))
+	return err == nil
+}
+
+// writeContactSheetTrack slices a contact sheet into a thumbnails track, with
+// the tiles spread evenly over the video. The sheet is served as is.
+func (s *SeekPreviewService) writeContactSheetTrack(videoPath string, hash string, sheetPath string) error {
+	trackPath := filepath.Join(s.previewsDir, hash, sheetTrackFileName)
+	if _, err := os.Stat(trackPath); err == nil {
+		return nil
+	}
+
+	duration, err := s.cacheService.GetVideoDuration(videoPath)
+	if err != nil {
+		return fmt.Errorf("failed to read duration: %v", err)
+	}
+	file, err := os.Open(sheetPath)
+	if err != nil {
+		return err
+	}
+	sheet, _, err := image.Decode(file)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read contact sheet: %v", err)
+	}
+
+	tiles := detectSheetTiles(sheet)
+	if len(tiles) < 4 {
+		return fmt.Errorf("couldn't find the tiles of %s", filepath.Base(sheetPath))
+	}
+
+	sheetURL := mediaServerURL + "/thumbnail/" + url.PathEscape(sheetPath)
+	tileDuration := duration / float64(len(tiles))
+	cues := make([]thumbnailCue, 0, len(tiles))
+	for i, tile := range tiles {
+		cues = append(cues, thumbnailCue{
+			start: float64(i) * tileDuration,
+			end:   float64(i+1) * tileDuration,
+			image: sheetURL,
+			tile:  tile,
+		})
+	}
+
+	if err := os.MkdirAll(filepath.Dir(trackPath), 0755); err != nil {
+		return err
+	}
+	return writeThumbnailTrack(trackPath, cues)
+}
+
+// detectSheetTiles finds the tiles of a contact sheet from the uniform gaps
+// between them. Short bands of content, such as header text lines, are not
+// tiles. Tiles are returned row by row.
+func detectSheetTiles(sheet image.Image) []image.Rectangle {
+	bounds := sheet.Bounds()
+
+	rowBands := contentBands(bounds.Min.Y, bounds.Max.Y, func(y int) float64 {
+		return lineDeviation(sheet, bounds.Min.X, bounds.Max.X, func(x int) (int, int) { return x, y })
+	})
+	rowBands = tallestBands(rowBands)
+
+	var columnBands [][2]int
+	for _, row := range rowBands {
+		bands := contentBands(bounds.Min.X, bounds.Max.X, func(x int) float64 {
+			return lineDeviation(sheet, row[0], row[1], func(y int) (int, int) { return x, y })
+		})
+		if bands = tallestBands(bands); len(bands) > len(columnBands) {
+			columnBands = bands
+		}
+	}
+
+	var tiles []image.Rectangle
+	for _, row := range rowBands {
+		for _, column := range columnBands {
+			tiles = append(tiles, image.Rect(column[0], row[0], column[1], row[1]))
+		}
+	}
+	return tiles
+}
+
+// contentBands returns the runs of lines between from and to whose luma
+// deviation shows they hold content rather than a uniform gap
+func contentBands(from int, to int, deviation func(line int) float64) [][2]int {
+	var bands [][2]int
+	start := -1
+	for line := from; line <= to; line++ {
+		content := line < to && deviation(line) > sheetSeparatorDeviation
+		if content && start < 0 {
+			start = line
+		} else if !content && start >= 0 {
+			bands = append(bands, [2]int{start, line})
+			start = -1
+		}
+	}
+	return bands
+}
+
+// tallestBands keeps the bands close to the size of the largest one, which
+// drops header lines and slivers between tiles
+func tallestBands(bands [][2]int) [][2]int {
+	largest := 0
+	for _, band := range bands {
+		if size := band[1] - band[0]; size > largest {
+			largest = size
+		}
+	}
+	var kept [][2]int
+	for _, band := range bands {
+		if float64(band[1]-band[0]) >= 0.6*float64(largest) {
+			kept = append(kept, band)
+		}
+	}
+	return kept
+}
+
+// lineDeviation returns the standard deviation of the luma along a line of
+// pixels, sampling every other pixel
+func lineDeviation(img image.Image, from int, to int, point func(i int) (int, int)) float64 {
+	var sum, sumSquares, count float64
+	for i := from; i < to; i += 2 {
+		luma := float64(color.GrayModel.Convert(img.At(point(i))).(color.Gray).Y)
+		sum += luma
+		sumSquares += luma * luma
+		count++
+	}
+	if count == 0 {
+		return 0
+	}
+	mean := sum / count
+	return math.Sqrt(math.Max(sumSquares/count-mean*mean, 0))
+}
+
+// thumbnailCue is one thumbnail of a WebVTT thumbnails track
+type thumbnailCue struct {
+	start float64
+	end   float64
+	image string // URL of the image, relative to the track
+	tile  image.Rectangle
+}
+
+// writeThumbnailTrack writes a WebVTT track whose cues point at image regions
+// using #xywh media fragments
+func writeThumbnailTrack(path string, cues []thumbnailCue) error {
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n")
+	for _, cue := range cues {
+		sb.WriteString(fmt.Sprintf("\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n",
+			formatFFmpegTime(cue.start), formatFFmpegTime(cue.end), cue.image,
+			cue.tile.Min.X, cue.tile.Min.Y, cue.tile.Dx(), cue.tile.Dy()))
+	}
+
+	if err := os.WriteFile(path+".tmp", []byte(sb.String()), 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// previewURL returns the media server URL of a file in a video's previews directory
+func previewURL(hash string, fileName string) string {
+	return mediaServerURL + "/previews/" + hash + "/" + fileName
+}
@@ -0,0 +1,437 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"FanslyArchivePlayer/backend/models"
+)
+
+// Discontinuity is a timestamp jump found in a recording
+type Discontinuity struct {
+	VideoTime float64 `json:"videoTime"` // Where the jump happens on the playback timeline
+	Kind      string  `json:"kind"`      // "gap" (timestamps jump ahead) or "reset" (timestamps go back)
+	Jump      float64 `json:"jump"`      // Size of the timestamp jump in seconds
+	Missing   float64 `json:"missing"`   // Estimated recording time lost here that the video timeline doesn't cover
+}
+
+// SyncAnchor pins a chat time to a video time, for drift the scan can't place
+type SyncAnchor struct {
+	VideoTime float64 `json:"videoTime"`
+	ChatTime  float64 `json:"chatTime"`
+}
+
+// TimeMapPoint starts a stretch of the video where chat time is video time plus ChatOffset
+type TimeMapPoint struct {
+	VideoTime  float64 `json:"videoTime"`
+	ChatOffset float64 `json:"chatOffset"`
+}
+
+// TimeMap maps chat times, which follow the wall clock from the start of the
+// recording, to video times. Before its first point chat and video agree.
+type TimeMap struct {
+	Points []TimeMapPoint `json:"points"`
+}
+
+// TimingAnalysis is the discontinuity scan of a video with its chat time map
+type TimingAnalysis struct {
+	VideoPath       string          `json:"videoPath"`
+	Duration        float64         `json:"duration"`
+	Discontinuities []Discontinuity `json:"discontinuities"`
+	WallClockSpan   float64         `json:"wallClockSpan"`   // Seconds between the start and end of the recording, 0 if unknown
+	MissingTime     float64         `json:"missingTime"`     // Recording time the video timeline doesn't cover
+	UnplacedMissing float64         `json:"unplacedMissing"` // Missing time without a discontinuity to put it at; anchors can place it
+	Anchors         []SyncAnchor    `json:"anchors"`
+	TimeMap         TimeMap         `json:"timeMap"`
+	ScannedAt       time.Time       `json:"scannedAt"`
+	Scanned         bool            `json:"scanned"` // False until the packets have been scanned
+}
+
+// TimingService detects recording discontinuities and keeps the chat time
+// map of each video, cached per video hash with the other analyses
+type TimingService struct {
+	analysisDir  string
+	cacheService *CacheService
+	mu           sync.Mutex
+}
+
+const (
+	// Timestamp jumps smaller than this are jitter
+	discontinuityThreshold = 1.0
+	// Less missing time than this isn't worth correcting
+	minMissingTime = 2.0
+)
+
+// NewTimingService creates a new timing service
+func NewTimingService(appDataDir string, cacheService *CacheService) *TimingService {
+	return &TimingService{
+		analysisDir:  filepath.Join(appDataDir, analysisDirName),
+		cacheService: cacheService,
+	}
+}
+
+// GetTimingAnalysis returns the cached timing analysis of a video. Scanned is
+// false if its packets haven't been scanned, though anchors may be set.
+func (s *TimingService) GetTimingAnalysis(videoPath string) (TimingAnalysis, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loadAnalysis(videoPath)
+}
+
+// ScanDiscontinuities reads the packet timestamps of a video to find gaps and
+// resets. The recording time the video doesn't cover is estimated from the
+// wall clock: the start time in the file name or chat, and the time the
+// file was last written.
+func (s *TimingService) ScanDiscontinuities(videoPath string, messages []models.ChatMessage) (TimingAnalysis, error) {
+	media, err := s.cacheService.GetMediaInfo(videoPath)
+	if err != nil {
+		return TimingAnalysis{}, fmt.Errorf("failed to read video: %v", err)
+	}
+	stream := "v:0"
+	if media.HasAudio() {
+		// Audio packets follow each other without reordering
+		stream = "a:0"
+	}
+	discontinuities, err := scanPacketTimestamps(videoPath, stream)
+	if err != nil {
+		return TimingAnalysis{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	analysis, err := s.loadAnalysis(videoPath)
+	if err != nil {
+		return TimingAnalysis{}, err
+	}
+	analysis.Duration = media.Duration
+	analysis.Discontinuities = discontinuities
+	analysis.WallClockSpan = recordingWallClockSpan(videoPath, media, messages)
+	analysis.MissingTime = 0
+	if analysis.WallClockSpan > 0 {
+		// Gaps are already on the timeline, so they aren't missing from it
+		missing := analysis.WallClockSpan - media.Duration
+		if missing >= minMissingTime && missing < media.Duration {
+			analysis.MissingTime = missing
+		}
+	}
+	analysis.UnplacedMissing = placeMissingTime(analysis.Discontinuities, analysis.MissingTime)
+	analysis.ScannedAt = time.Now()
+	analysis.Scanned = true
+
+	if err := s.saveAnalysis(videoPath, &analysis); err != nil {
+		return TimingAnalysis{}, err
+	}
+	return analysis, nil
+}
+
+// AddSyncAnchor pins chatTime to videoTime from videoTime onwards
+func (s *TimingService) AddSyncAnchor(videoPath string, videoTime float64, chatTime float64) (TimingAnalysis, error) {
+	if videoTime < 0 || chatTime < 0 {
+		return TimingAnalysis{}, errors.New("times can't be negative")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	analysis, err := s.loadAnalysis(videoPath)
+	if err != nil {
+		return TimingAnalysis{}, err
+	}
+	// A new anchor at the same time replaces the old one
+	anchors := []SyncAnchor{}
+	for _, anchor := range analysis.Anchors {
+		if math.Abs(anchor.VideoTime-videoTime) >= 0.5 {
+			anchors = append(anchors, anchor)
+		}
+	}
+	analysis.Anchors = append(anchors, SyncAnchor{VideoTime: videoTime, ChatTime: chatTime})
+	sort.Slice(analysis.Anchors, func(i, j int) bool {
+		return analysis.Anchors[i].VideoTime < analysis.Anchors[j].VideoTime
+	})
+
+	if err := s.saveAnalysis(videoPath, &analysis); err != nil {
+		return TimingAnalysis{}, err
+	}
+	return analysis, nil
+}
+
+// ClearSyncAnchors removes the anchors of a video
+func (s *TimingService) ClearSyncAnchors(videoPath string) (TimingAnalysis, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	analysis, err := s.loadAnalysis(videoPath)
+	if err != nil {
+		return TimingAnalysis{}, err
+	}
+	analysis.Anchors = []SyncAnchor{}
+	if err := s.saveAnalysis(videoPath, &analysis); err != nil {
+		return TimingAnalysis{}, err
+	}
+	return analysis, nil
+}
+
+// loadAnalysis reads the cached analysis of a video and builds its time map.
+// Callers hold s.mu.
+func (s *TimingService) loadAnalysis(videoPath string) (TimingAnalysis, error) {
+	analysisPath, err := s.analysisPath(videoPath)
+	if err != nil {
+		return TimingAnalysis{}, err
+	}
+	analysis := TimingAnalysis{Discontinuities: []Discontinuity{}, Anchors: []SyncAnchor{}}
+	if err := readJSONFile(analysisPath, &analysis); err != nil {
+		return TimingAnalysis{}, fmt.Errorf("failed to read timing analysis: %v", err)
+	}
+	analysis.VideoPath = videoPath
+	analysis.Scanned = !analysis.ScannedAt.IsZero()
+	analysis.TimeMap = buildTimeMap(analysis.Discontinuities, analysis.Anchors)
+	return analysis, nil
+}
+
+// saveAnalysis caches the analysis of a video and rebuilds its time map.
+// Callers hold s.mu.
+func (s *TimingService) saveAnalysis(videoPath string, analysis *TimingAnalysis) error {
+	analysisPath, err := s.analysisPath(videoPath)
+	if err != nil {
+		return err
+	}
+	analysis.TimeMap = buildTimeMap(analysis.Discontinuities, analysis.Anchors)
+	if err := os.MkdirAll(s.analysisDir, 0755); err != nil {
+		return err
+	}
+	if err := writeJSONFile(analysisPath, analysis); err != nil {
+		return fmt.Errorf("failed to save timing analysis: %v", err)
+	}
+	return nil
+}
+
+// analysisPath returns where the timing analysis of a video is cached
+func (s *TimingService) analysisPath(videoPath string) (string, error) {
+	hash, err := s.cacheService.GetFileHash(videoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash video: %v", err)
+	}
+	return filepath.Join(s.analysisDir, hash+"_timing.json"), nil
+}
+
+// scanPacketTimestamps reads the decode timestamp and duration of every
+// packet of a stream and reports where they jump. After a reset the playback
+// timeline carries on from where it was, as the muxer does when it rewrites
+// timestamps to keep them increasing.
+func scanPacketTimestamps(videoPath string, stream string) ([]Discontinuity, error) {
+	if _, err := exec.LookPath("ffprobe"); err != nil {
+		return nil, errors.New("ffprobe not found")
+	}
+	cmd := exec.Command("ffprobe",
+		"-v", "error",
+		"-select_streams", stream,
+		"-show_entries", "packet=dts_time,duration_time",
+		"-of", "csv=p=0",
+		videoPath,
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	discontinuities := []Discontinuity{}
+	first := true
+	var segmentStart, timelineBase, expected, lastDuration float64
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		dtsText, durationText, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ",")
+		if dtsText == "" || dtsText == "N/A" {
+			continue
+		}
+		dts := parseProbeFloat(dtsText)
+		if duration := parseProbeFloat(durationText); duration > 0 {
+			lastDuration = duration
+		}
+		if first {
+			segmentStart, expected, first = dts, dts+lastDuration, false
+			continue
+		}
+
+		jump := dts - expected
+		position := timelineBase + expected - segmentStart
+		switch {
+		case jump > discontinuityThreshold:
+			discontinuities = append(discontinuities, Discontinuity{VideoTime: position, Kind: "gap", Jump: jump})
+		case jump < -discontinuityThreshold:
+			discontinuities = append(discontinuities, Discontinuity{VideoTime: position, Kind: "reset", Jump: jump})
+			timelineBase, segmentStart = position, dts
+		}
+		expected = dts + lastDuration
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read packets: %v", err)
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("ffprobe error: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return discontinuities, nil
+}
+
+// recordingWallClockSpan returns how long the recording ran by the wall
+// clock, or 0 if its start is unknown. The start comes from the file name or
+// the chat; the end is when the recorder last wrote the file.
+func recordingWallClockSpan(videoPath string, media MediaInfo, messages []models.ChatMessage) float64 {
+	fileInfo, err := os.Stat(videoPath)
+	if err != nil {
+		return 0
+	}
+
+	var start time.Time
+	if match := recordingTimeInName.FindStringSubmatch(filepath.Base(videoPath)); match != nil {
+		start, _ = time.ParseInLocation("20060102150405", strings.Join(match[1:], ""), time.Local)
+	}
+	if start.IsZero() {
+		start = chatRecordingStart(messages)
+	}
+	if start.IsZero() && media.CreationTime != "" {
+		start, _ = time.Parse(time.RFC3339Nano, media.CreationTime)
+	}
+	if start.IsZero() {
+		return 0
+	}
+	return math.Max(fileInfo.ModTime().Sub(start).Seconds(), 0)
+}
+
+// chatRecordingStart returns when the recording started according to the
+// chat: a message's wall-clock timestamp less its time into the recording
+func chatRecordingStart(messages []models.ChatMessage) time.Time {
+	for _, msg := range messages {
+		if msg.Timestamp <= 0 {
+			continue
+		}
+		sent := time.UnixMilli(msg.Timestamp)
+		// Some exports store seconds instead of milliseconds
+		if msg.Timestamp < 1e11 {
+			sent = time.Unix(msg.Timestamp, 0)
+		}
+		return sent.Add(-time.Duration(msg.TimeInSeconds * float64(time.Second)))
+	}
+	return time.Time{}
+}
+
+// placeMissingTime spreads the missing time over the resets, where the
+// timeline lost its place, and returns what couldn't be placed
+func placeMissingTime(discontinuities []Discontinuity, missing float64) float64 {
+	resets := 0
+	for _, discontinuity := range discontinuities {
+		if discontinuity.Kind == "reset" {
+			resets++
+		}
+	}
+	if resets == 0 {
+		return missing
+	}
+	for i := range discontinuities {
+		if discontinuities[i].Kind == "reset" {
+			discontinuities[i].Missing = missing / float64(resets)
+		}
+	}
+	return 0
+}
+
+// buildTimeMap turns the missing time at discontinuities and the anchors into
+// map points. Missing time adds to the offset from its discontinuity on; an
+// anchor sets the offset from its video time on.
+func buildTimeMap(discontinuities []Discontinuity, anchors []SyncAnchor) TimeMap {
+	type change struct {
+		videoTime float64
+		offset    float64
+		absolute  bool
+	}
+	changes := []change{}
+	for _, discontinuity := range discontinuities {
+		if discontinuity.Missing > 0 {
+			changes = append(changes, change{videoTime: discontinuity.VideoTime, offset: discontinuity.Missing})
+		}
+	}
+	for _, anchor := range anchors {
+		changes = append(changes, change{videoTime: anchor.VideoTime, offset: anchor.ChatTime - anchor.VideoTime, absolute: true})
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].videoTime < changes[j].videoTime
+	})
+
+	timeMap := TimeMap{Points: []TimeMapPoint{}}
+	offset := 0.0
+	for _, c := range changes {
+		if c.absolute {
+			offset = c.offset
+		} else {
+			offset += c.offset
+		}
+		timeMap.Points = append(timeMap.Points, TimeMapPoint{VideoTime: c.videoTime, ChatOffset: offset})
+	}
+	return timeMap
+}
+
+// ChatTime returns the chat time shown at a video time
+func (m TimeMap) ChatTime(videoTime float64) float64 {
+	offset := 0.0
+	for _, point := range m.Points {
+		if point.VideoTime > videoTime {
+			break
+		}
+		offset = point.ChatOffset
+	}
+	return videoTime + offset
+}
+
+// VideoTime returns the video time of a chat time. Chat sent while the
+// recording was down is placed where the recording picks up again.
+func (m TimeMap) VideoTime(chatTime float64) float64 {
+	for i := len(m.Points) - 1; i >= 0; i-- {
+		point := m.Points[i]
+		if chatTime >= point.VideoTime+point.ChatOffset {
+			return chatTime - point.ChatOffset
+		}
+		previousOffset := 0.0
+		if i > 0 {
+			previousOffset = m.Points[i-1].ChatOffset
+		}
+		if chatTime >= point.VideoTime+previousOffset {
+			return point.VideoTime
+		}
+	}
+	return chatTime
+}
+
+// applyTimeMap returns the messages moved to video time
+func applyTimeMap(messages []models.ChatMessage, timeMap TimeMap) []models.ChatMessage {
+	if len(timeMap.Points) == 0 {
+		return messages
+	}
+	mapped := make([]models.ChatMessage, len(messages))
+	for i, msg := range messages {
+		videoTime := timeMap.VideoTime(msg.TimeInSeconds)
+		if videoTime != msg.TimeInSeconds {
+			msg.TimeInSeconds = videoTime
+			msg.TimeText = formatTimeText(videoTime)
+		}
+		mapped[i] = msg
+	}
+	sort.SliceStable(mapped, func(i, j int) bool {
+		return mapped[i].TimeInSeconds < mapped[j].TimeInSeconds
+	})
+	return mapped
+}
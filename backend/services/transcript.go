@@ -0,0 +1,301 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"FanslyArchivePlayer/backend/models"
+)
+
+// Transcript is a subtitle or transcript file loaded into time-ordered cues
+type Transcript struct {
+	Path     string                 `json:"path"`
+	Format   string                 `json:"format"` // srt, vtt or whisper
+	Language string                 `json:"language,omitempty"`
+	Cues     []models.TranscriptCue `json:"cues"`
+}
+
+// TranscriptHit is a transcript cue matching a search
+type TranscriptHit struct {
+	CueIndex int     `json:"cueIndex"`
+	Start    float64 `json:"start"` // Time to seek to
+	End      float64 `json:"end"`
+	Text     string  `json:"text"`
+	Speaker  string  `json:"speaker,omitempty"`
+}
+
+// whisperOutput is the JSON written by Whisper (segments, in seconds) or
+// whisper.cpp (transcription, with offsets in milliseconds)
+type whisperOutput struct {
+	Language string `json:"language"`
+	Segments []struct {
+		Start   float64 `json:"start"`
+		End     float64 `json:"end"`
+		Text    string  `json:"text"`
+		Speaker string  `json:"speaker"`
+	} `json:"segments"`
+	Transcription []struct {
+		Offsets struct {
+			From int64 `json:"from"`
+			To   int64 `json:"to"`
+		} `json:"offsets"`
+		Text string `json:"text"`
+	} `json:"transcription"`
+	Result struct {
+		Language string `json:"language"`
+	} `json:"result"`
+}
+
+// maxTranscriptCueLength bounds how far back a cue can start and still be
+// showing; Whisper segments are at most 30 seconds
+const maxTranscriptCueLength = 30.0
+
+var (
+	transcriptExtensions = map[string]string{".srt": "srt", ".vtt": "vtt", ".json": "whisper"}
+	cueVoiceTag          = regexp.MustCompile(`^<v(?:\.[^ >]*)?\s+([^>]+)>`)
+	cueMarkupTag         = regexp.MustCompile(`<[^>]*>`)
+	transcriptLanguage   = regexp.MustCompile(`^\.([A-Za-z]{2,3}(?:-[A-Za-z0-9]{2,4})?)$`)
+)
+
+// FindTranscriptFiles returns the transcripts next to a video: <base>.srt,
+// <base>.vtt and <base>.json, optionally with a language such as <base>.en.srt
+func FindTranscriptFiles(videoPath string) []string {
+	stem := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
+	entries, err := os.ReadDir(filepath.Dir(videoPath))
+	if err != nil {
+		return []string{}
+	}
+
+	paths := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		ext := strings.ToLower(filepath.Ext(name))
+		if entry.IsDir() || transcriptExtensions[ext] == "" || !strings.HasPrefix(name, stem+".") {
+			continue
+		}
+		// Only a language code may sit between the video name and the extension
+		if language := strings.TrimSuffix(name[len(stem):], filepath.Ext(name)); language != "" && !transcriptLanguage.MatchString(language) {
+			continue
+		}
+		paths = append(paths, filepath.Join(filepath.Dir(videoPath), name))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// ParseTranscriptFile reads an SRT, WebVTT or Whisper JSON transcript
+func ParseTranscriptFile(path string) (Transcript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Transcript{}, fmt.Errorf("failed to read transcript: %v", err)
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	transcript := Transcript{Path: path, Format: transcriptExtensions[ext]}
+	// A language code in the name, e.g. stream.en.srt
+	if match := transcriptLanguage.FindStringSubmatch(filepath.Ext(strings.TrimSuffix(path, filepath.Ext(path)))); match != nil {
+		transcript.Language = match[1]
+	}
+
+	switch transcript.Format {
+	case "srt", "vtt":
+		transcript.Cues = parseTimedText(string(data))
+	case "whisper":
+		language, cues, err := parseWhisperJSON(data)
+		if err != nil {
+			return Transcript{}, err
+		}
+		if language != "" {
+			transcript.Language = language
+		}
+		transcript.Cues = cues
+	default:
+		return Transcript{}, fmt.Errorf("unsupported transcript format: %s", ext)
+	}
+
+	if len(transcript.Cues) == 0 {
+		return Transcript{}, errors.New("no cues found in transcript")
+	}
+	sort.SliceStable(transcript.Cues, func(i, j int) bool {
+		return transcript.Cues[i].Start < transcript.Cues[j].Start
+	})
+	return transcript, nil
+}
+
+// parseTimedText reads the cues of an SRT or WebVTT file. Both are blocks of
+// an optional identifier, a "start --> end" line and the text, separated by
+// blank lines; WebVTT header, NOTE and STYLE blocks have no timing line.
+func parseTimedText(content string) []models.TranscriptCue {
+	content = strings.TrimPrefix(content, "\ufeff")
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	cues := []models.TranscriptCue{}
+	for _, block := range strings.Split(content, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		timing := -1
+		for i, line := range lines {
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		if timing < 0 {
+			continue
+		}
+
+		// WebVTT cue settings may follow the end time
+		fields := strings.Fields(strings.Replace(lines[timing], "-->", " --> ", 1))
+		if len(fields) < 3 {
+			continue
+		}
+		start, err := parseMarkerTime(fields[0])
+		if err != nil {
+			continue
+		}
+		end, err := parseMarkerTime(fields[2])
+		if err != nil {
+			continue
+		}
+
+		text := strings.Join(lines[timing+1:], "\n")
+		speaker := ""
+		if match := cueVoiceTag.FindStringSubmatch(text); match != nil {
+			speaker = strings.TrimSpace(match[1])
+		}
+		text = strings.TrimSpace(html.UnescapeString(cueMarkupTag.ReplaceAllString(text, "")))
+		if text == "" {
+			continue
+		}
+		cues = append(cues, models.TranscriptCue{Start: start, End: end, Text: text, Speaker: speaker})
+	}
+	return cues
+}
+
+// parseWhisperJSON reads the language and segments of a Whisper JSON transcript
+func parseWhisperJSON(data []byte) (string, []models.TranscriptCue, error) {
+	var output whisperOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return "", nil, fmt.Errorf("failed to parse transcript JSON: %v", err)
+	}
+
+	cues := []models.TranscriptCue{}
+	for _, segment := range output.Segments {
+		if text := strings.TrimSpace(segment.Text); text != "" {
+			cues = append(cues, models.TranscriptCue{Start: segment.Start, End: segment.End, Text: text, Speaker: segment.Speaker})
+		}
+	}
+	for _, segment := range output.Transcription {
+		if text := strings.TrimSpace(segment.Text); text != "" {
+			cues = append(cues, models.TranscriptCue{
+				Start: float64(segment.Offsets.From) / 1000,
+				End:   float64(segment.Offsets.To) / 1000,
+				Text:  text,
+			})
+		}
+	}
+
+	language := output.Language
+	if language == "" {
+		language = output.Result.Language
+	}
+	return language, cues, nil
+}
+
+// CuesAt returns the cues showing between currentTime-windowSize and currentTime
+func (t Transcript) CuesAt(currentTime float64, windowSize float64) []models.TranscriptCue {
+	from := sort.Search(len(t.Cues), func(i int) bool {
+		return t.Cues[i].Start >= currentTime-windowSize-maxTranscriptCueLength
+	})
+	to := sort.Search(len(t.Cues), func(i int) bool {
+		return t.Cues[i].Start > currentTime
+	})
+
+	cues := []models.TranscriptCue{}
+	for _, cue := range t.Cues[from:max(from, to)] {
+		if cue.End >= currentTime-windowSize {
+			cues = append(cues, cue)
+		}
+	}
+	return cues
+}
+
+// Search returns the cues containing every word of query, ignoring case
+func (t Transcript) Search(query string) []TranscriptHit {
+	words := strings.Fields(strings.ToLower(query))
+	hits := []TranscriptHit{}
+	if len(words) == 0 {
+		return hits
+	}
+
+	for i, cue := range t.Cues {
+		text := strings.ToLower(cue.Text)
+		matched := true
+		for _, word := range words {
+			if !strings.Contains(text, word) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			hits = append(hits, TranscriptHit{CueIndex: i, Start: cue.Start, End: cue.End, Text: cue.Text, Speaker: cue.Speaker})
+		}
+	}
+	return hits
+}
+
+// WebVTT renders the transcript as a WebVTT subtitle track
+func (t Transcript) WebVTT() string {
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n")
+	escaper := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	for _, cue := range t.Cues {
+		text := escaper.Replace(cue.Text)
+		if cue.Speaker != "" {
+			text = "<v " + escaper.Replace(cue.Speaker) + ">" + text
+		}
+		sb.WriteString(fmt.Sprintf("\n%s --> %s\n%s\n", formatFFmpegTime(cue.Start), formatFFmpegTime(cue.End), text))
+	}
+	return sb.String()
+}
+
+// PrepareTranscriptTrack writes a transcript of a video as WebVTT and returns
+// its URL. Tracks are keyed by the transcript's content, so an edited
+// transcript gets a new track.
+func (s *TrackService) PrepareTranscriptTrack(videoPath string, transcript Transcript) (string, error) {
+	hash, err := s.cacheService.GetFileHash(videoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash video: %v", err)
+	}
+	// Transcripts are small, so they're hashed directly rather than cached
+	transcriptHash, err := ComputeFileHash(transcript.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash transcript: %v", err)
+	}
+	fileName := "transcript_" + transcriptHash + ".vtt"
+	trackPath := filepath.Join(s.tracksDir, hash, fileName)
+
+	unlock := s.lockFile(trackPath)
+	defer unlock()
+
+	if _, err := os.Stat(trackPath); err == nil {
+		return trackURL(hash, fileName), nil
+	}
+	if err := os.MkdirAll(filepath.Dir(trackPath), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(trackPath+".tmp", []byte(transcript.WebVTT()), 0644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(trackPath+".tmp", trackPath); err != nil {
+		os.Remove(trackPath + ".tmp")
+		return "", err
+	}
+	return trackURL(hash, fileName), nil
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// moveToTrash moves a file to the system trash / recycle bin instead of deleting it
+func moveToTrash(path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(absPath); err != nil {
+		return err
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		// Escape single quotes for the PowerShell string literal
+		quoted := strings.ReplaceAll(absPath, "'", "''")
+		cmd := exec.Command("powershell", "-NoProfile", "-Command",
+			"Add-Type -AssemblyName Microsoft.VisualBasic; "+
+				"[Microsoft.VisualBasic.FileIO.FileSystem]::DeleteFile('"+quoted+"', 'OnlyErrorDialogs', 'SendToRecycleBin')")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to move file to recycle bin: %v: %s", err, string(output))
+		}
+		return nil
+	case "darwin":
+		return moveToMacTrash(absPath)
+	default:
+		// Prefer gio, which also handles trash directories on other mounts
+		if _, err := exec.LookPath("gio"); err == nil {
+			if err := exec.Command("gio", "trash", absPath).Run(); err == nil {
+				return nil
+			}
+		}
+		return moveToFreedesktopTrash(absPath)
+	}
+}
+
+// moveToMacTrash moves a file to ~/.Trash. A file on another volume can't be
+// renamed into it, so it goes to that volume's .Trashes/<uid> folder, where
+// Finder keeps the trash of external drives, or is copied to ~/.Trash and
+// then removed.
+func moveToMacTrash(absPath string) error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	homeTrash := filepath.Join(homeDir, ".Trash")
+	err = os.Rename(absPath, uniqueTrashPath(homeTrash, filepath.Base(absPath)))
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	if volume := macVolumeRoot(absPath); volume != "" {
+		volumeTrash := filepath.Join(volume, ".Trashes", strconv.Itoa(os.Getuid()))
+		if err := os.MkdirAll(volumeTrash, 0700); err == nil {
+			if err := os.Rename(absPath, uniqueTrashPath(volumeTrash, filepath.Base(absPath))); err == nil {
+				return nil
+			}
+		}
+	}
+
+	target := uniqueTrashPath(homeTrash, filepath.Base(absPath))
+	if err := copyToTrash(absPath, target); err != nil {
+		return fmt.Errorf("failed to move file to trash: %v", err)
+	}
+	return os.Remove(absPath)
+}
+
+// macVolumeRoot returns the mount point of the volume under /Volumes a path
+// is on, or an empty string for the startup volume
+func macVolumeRoot(absPath string) string {
+	rest, found := strings.CutPrefix(absPath, "/Volumes/")
+	if !found {
+		return ""
+	}
+	name, _, _ := strings.Cut(rest, "/")
+	if name == "" {
+		return ""
+	}
+	return filepath.Join("/Volumes", name)
+}
+
+// copyToTrash copies a file to target, removing the partial copy if it fails
+func copyToTrash(source string, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(target)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(target)
+		return err
+	}
+	return nil
+}
+
+// moveToFreedesktopTrash implements the freedesktop.org trash specification for the home trash
+func moveToFreedesktopTrash(absPath string) error {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		dataHome = filepath.Join(homeDir, ".local", "share")
+	}
+
+	filesDir := filepath.Join(dataHome, "Trash", "files")
+	infoDir := filepath.Join(dataHome, "Trash", "info")
+	if err := os.MkdirAll(filesDir, 0700); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(infoDir, 0700); err != nil {
+		return err
+	}
+
+	target := uniqueTrashPath(filesDir, filepath.Base(absPath))
+	name := filepath.Base(target)
+
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: absPath}).EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+	infoPath := filepath.Join(infoDir, name+".trashinfo")
+	if err := os.WriteFile(infoPath, []byte(info), 0600); err != nil {
+		return err
+	}
+
+	if err := os.Rename(absPath, target); err != nil {
+		os.Remove(infoPath)
+		return fmt.Errorf("failed to move file to trash: %v", err)
+	}
+	return nil
+}
+
+// uniqueTrashPath returns a path in dir for name that does not exist yet
+func uniqueTrashPath(dir string, name string) string {
+	target := filepath.Join(dir, name)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(target); os.IsNotExist(err) {
+			return target
+		}
+		target = filepath.Join(dir, fmt.Sprintf("%s.%d%s", base, i, ext))
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
 	"FanslyArchivePlayer/backend/models"
@@ -14,13 +15,20 @@ import (
 // VideoService handles video and chat operations
 type VideoService struct {
 	CurrentVideoPath string
-	ChatMessages     []models.ChatMessage
+	ChatMessages     []models.ChatMessage // Chat on the video's timeline, with the time map applied
+	Transcript       Transcript           // The transcript of the current video, without cues if it has none
+	cacheService     *CacheService
+	recordedChat     []models.ChatMessage // Chat as recorded, before the time map
+	timeMap          TimeMap
 }
 
 // NewVideoService creates a new video service
-func NewVideoService() *VideoService {
+func NewVideoService(cacheService *CacheService) *VideoService {
 	return &VideoService{
 		ChatMessages: []models.ChatMessage{},
+		recordedChat: []models.ChatMessage{},
+		Transcript:   Transcript{Cues: []models.TranscriptCue{}},
+		cacheService: cacheService,
 	}
 }
 
@@ -96,17 +104,106 @@ func (s *VideoService) LoadVideo(path string) error {
 	if err != nil {
 		return fmt.Errorf("video file not found: %v", err)
 	}
+	// An MP4 left by a killed recorder has no index, and would load as a
+	// player that never starts
+	if isMP4File(path) {
+		if layout, err := readMP4Layout(path); err == nil && !layout.hasIndex() {
+			return fmt.Errorf("video has no index (moov atom), the recording may be unfinished or broken")
+		}
+	}
+
+	s.CurrentVideoPath = path
+
+	// Load the first transcript sidecar that parses
+	s.ClearTranscript()
+	for _, transcriptPath := range FindTranscriptFiles(path) {
+		transcript, err := ParseTranscriptFile(transcriptPath)
+		if err != nil {
+			fmt.Printf("Failed to load transcript %s: %v\n", transcriptPath, err)
+			continue
+		}
+		s.Transcript = transcript
+		break
+	}
+	return nil
+}
 
+// SetPlayingPart switches the video file being played to another part of the
+// loaded multi-part recording. Chat and transcript stay on the recording's
+// timeline, so they aren't reloaded.
+func (s *VideoService) SetPlayingPart(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("video file not found: %v", err)
+	}
 	s.CurrentVideoPath = path
 	return nil
 }
 
+// SetTranscript replaces the loaded transcript, e.g. with the stitched
+// transcript of a multi-part recording
+func (s *VideoService) SetTranscript(transcript Transcript) {
+	s.Transcript = transcript
+}
+
+// LoadTranscriptFile loads an SRT, WebVTT or Whisper JSON transcript
+func (s *VideoService) LoadTranscriptFile(path string) error {
+	transcript, err := ParseTranscriptFile(path)
+	if err != nil {
+		return err
+	}
+	s.Transcript = transcript
+	return nil
+}
+
+// ClearTranscript unloads the transcript
+func (s *VideoService) ClearTranscript() {
+	s.Transcript = Transcript{Cues: []models.TranscriptCue{}}
+}
+
 // LoadChatFile loads a chat JSON file
 func (s *VideoService) LoadChatFile(path string) error {
+	messages, err := ParseChatFile(path)
+	if err != nil {
+		return err
+	}
+	s.SetChatMessages(messages)
+	return nil
+}
+
+// SetChatMessages replaces the loaded chat, e.g. with the stitched chat of a multi-part recording
+func (s *VideoService) SetChatMessages(messages []models.ChatMessage) {
+	s.recordedChat = messages
+	s.ChatMessages = applyTimeMap(messages, s.timeMap)
+}
+
+// SetTimeMap sets the map from chat time to video time and moves the loaded
+// chat onto the video's timeline with it
+func (s *VideoService) SetTimeMap(timeMap TimeMap) {
+	s.timeMap = timeMap
+	s.ChatMessages = applyTimeMap(s.recordedChat, timeMap)
+}
+
+// RecordedChatTime returns the time of a loaded message as recorded, before the time map
+func (s *VideoService) RecordedChatTime(messageID string) (float64, bool) {
+	for _, msg := range s.recordedChat {
+		if msg.MessageID == messageID {
+			return msg.TimeInSeconds, true
+		}
+	}
+	return 0, false
+}
+
+// RecordedChat returns the loaded chat as recorded, before the time map
+func (s *VideoService) RecordedChat() []models.ChatMessage {
+	return s.recordedChat
+}
+
+// ParseChatFile reads a chat JSON file and returns its messages sorted by time
+func ParseChatFile(path string) ([]models.ChatMessage, error) {
 	// Read the file
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("failed to read chat file: %v", err)
+		return nil, fmt.Errorf("failed to read chat file: %v", err)
 	}
 
 	// Try to parse as an array of messages first
@@ -122,7 +219,7 @@ func (s *VideoService) LoadChatFile(path string) error {
 			var singleMessage models.ChatMessage
 			err = json.Unmarshal(data, &singleMessage)
 			if err != nil {
-				return fmt.Errorf("failed to parse chat JSON: %v", err)
+				return nil, fmt.Errorf("failed to parse chat JSON: %v", err)
 			}
 			messages = []models.ChatMessage{singleMessage}
 		} else {
@@ -132,7 +229,7 @@ func (s *VideoService) LoadChatFile(path string) error {
 
 	// If we still have no messages, return an error
 	if len(messages) == 0 {
-		return fmt.Errorf("no chat messages found in file")
+		return nil, fmt.Errorf("no chat messages found in file")
 	}
 
 	// Process each message to extract tip amount if present
@@ -147,8 +244,7 @@ func (s *VideoService) LoadChatFile(path string) error {
 		return messages[i].TimeInSeconds < messages[j].TimeInSeconds
 	})
 
-	s.ChatMessages = messages
-	return nil
+	return messages, nil
 }
 
 // GetMessagesAtTime returns messages within a time window
@@ -188,11 +284,77 @@ func (s *VideoService) GetVideoFileInfo() map[string]string {
 		info["chatFile"] = chatPath
 	}
 
-	// Check for thumbnail
-	thumbnailPath := basePath + "_contact_sheet.jpg"
-	if _, err := os.Stat(thumbnailPath); err == nil {
+	// Check for transcripts; the first one that parses is loaded with the video
+	if transcripts := FindTranscriptFiles(s.CurrentVideoPath); len(transcripts) > 0 {
+		info["transcripts"] = strconv.Itoa(len(transcripts))
+	}
+	if s.Transcript.Path != "" {
+		info["transcriptFile"] = s.Transcript.Path
+		info["transcriptCues"] = strconv.Itoa(len(s.Transcript.Cues))
+	}
+
+	// Check for thumbnail, from fansly-scraper or generated by the player
+	if thumbnailPath := FindContactSheet(s.cacheService, s.CurrentVideoPath); thumbnailPath != "" {
 		info["thumbnailPath"] = thumbnailPath
 	}
 
+	// Summarize the media probe; GetMediaInfo has the full details
+	media, err := s.GetMediaInfo()
+	if err != nil {
+		fmt.Printf("Failed to probe video: %v\n", err)
+		return info
+	}
+	// Media info cached before the long name was stored only has the short name
+	info["container"] = media.ContainerLongName
+	if info["container"] == "" {
+		info["container"] = media.Container
+	}
+	info["duration"] = strconv.FormatFloat(media.Duration, 'f', 3, 64)
+	if media.BitRate > 0 {
+		info["bitRate"] = strconv.FormatInt(media.BitRate, 10)
+	}
+	if media.CreationTime != "" {
+		info["creationTime"] = media.CreationTime
+	}
+	if video, found := media.VideoStream(); found {
+		info["videoCodec"] = video.Codec
+		info["resolution"] = fmt.Sprintf("%dx%d", video.Width, video.Height)
+		if video.FPS > 0 {
+			info["fps"] = strconv.FormatFloat(video.FPS, 'f', 3, 64)
+		}
+	}
+	if audio := media.AudioStreams(); len(audio) > 0 {
+		info["audioCodec"] = audio[0].Codec
+		info["audioChannels"] = strconv.Itoa(audio[0].Channels)
+		info["audioTracks"] = strconv.Itoa(len(audio))
+	}
+	if len(media.Chapters) > 0 {
+		info["chapters"] = strconv.Itoa(len(media.Chapters))
+	}
+
 	return info
 }
+
+// GetMediaInfo returns the ffprobe details of the current video
+func (s *VideoService) GetMediaInfo() (MediaInfo, error) {
+	if s.CurrentVideoPath == "" {
+		return MediaInfo{}, fmt.Errorf("no video is currently loaded")
+	}
+	return s.cacheService.GetMediaInfo(s.CurrentVideoPath)
+}
+
+// FindChatMessage returns the loaded chat message with the given ID
+func (s *VideoService) FindChatMessage(messageID string) (models.ChatMessage, bool) {
+	for _, msg := range s.ChatMessages {
+		if msg.MessageID == messageID {
+			return msg, true
+		}
+	}
+	return models.ChatMessage{}, false
+}
+
+// ClearChat unloads the chat, e.g. when the next video has no chat file
+func (s *VideoService) ClearChat() {
+	s.ChatMessages = []models.ChatMessage{}
+	s.recordedChat = []models.ChatMessage{}
+}
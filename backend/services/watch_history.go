@@ -0,0 +1,316 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// WatchEntry is the playback state of one video
+type WatchEntry struct {
+	VideoHash      string    `json:"videoHash"`
+	VideoPath      string    `json:"videoPath"` // Last known location, empty if the video can't be found
+	ChatPath       string    `json:"chatPath,omitempty"`
+	Position       float64   `json:"position"`   // Last playback position in seconds
+	ResumeFrom     float64   `json:"resumeFrom"` // Where playback should continue, 0 to start over
+	Duration       float64   `json:"duration"`
+	PercentWatched float64   `json:"percentWatched"`
+	Watched        bool      `json:"watched"`
+	LastOpened     time.Time `json:"lastOpened"`
+}
+
+// WatchHistoryService remembers where each video was stopped. Entries are
+// keyed by the video's content hash so a renamed or moved video still resumes.
+// The history is kept in memory; playback positions are saved at most every
+// few seconds, other changes right away.
+type WatchHistoryService struct {
+	appDataDir   string
+	cacheService *CacheService
+	mu           sync.Mutex
+	history      map[string]WatchEntry // Loaded on first use
+	dirty        bool                  // Set while changes haven't been saved
+	lastSaved    time.Time
+	hashes       map[string]hashedVideo // Hashes of the videos updated this session
+}
+
+// hashedVideo is a video's hash with the file state it was computed for
+type hashedVideo struct {
+	hash         string
+	lastModified time.Time
+	fileSize     int64
+}
+
+const (
+	watchHistoryFileName = "watch_history.json"
+	// A video counts as watched once playback gets this close to the end
+	watchedThreshold = 95.0
+	// Positions this close to the start or end aren't worth resuming from
+	resumeMargin = 30.0
+	// Playback positions are saved at most this often
+	positionSaveInterval = 15 * time.Second
+)
+
+// NewWatchHistoryService creates a new watch history service
+func NewWatchHistoryService(appDataDir string, cacheService *CacheService) *WatchHistoryService {
+	return &WatchHistoryService{
+		appDataDir:   appDataDir,
+		cacheService: cacheService,
+		hashes:       make(map[string]hashedVideo),
+	}
+}
+
+// RecordOpened notes that a video was opened
+func (s *WatchHistoryService) RecordOpened(videoPath string) error {
+	return s.update(videoPath, func(entry *WatchEntry) {
+		entry.LastOpened = time.Now()
+	})
+}
+
+// SetChatFile remembers the chat file loaded alongside a video
+func (s *WatchHistoryService) SetChatFile(videoPath string, chatPath string) error {
+	return s.update(videoPath, func(entry *WatchEntry) {
+		entry.ChatPath = chatPath
+	})
+}
+
+// UpdatePosition saves the playback position. Reaching the end of the video
+// marks it as watched.
+func (s *WatchHistoryService) UpdatePosition(videoPath string, position float64, duration float64) error {
+	if position < 0 {
+		return errors.New("position can't be negative")
+	}
+	return s.updatePosition(videoPath, func(entry *WatchEntry) {
+		entry.Position = position
+		if duration > 0 {
+			entry.Duration = duration
+		}
+		if entry.Duration > 0 {
+			percent := position / entry.Duration * 100
+			if percent > 100 {
+				percent = 100
+			}
+			// Seeking back doesn't undo what was already watched
+			if percent > entry.PercentWatched {
+				entry.PercentWatched = percent
+			}
+			if percent >= watchedThreshold {
+				entry.Watched = true
+			}
+		}
+	})
+}
+
+// MarkWatched sets or clears the watched flag of a video
+func (s *WatchHistoryService) MarkWatched(videoPath string, watched bool) error {
+	return s.update(videoPath, func(entry *WatchEntry) {
+		entry.Watched = watched
+		if watched {
+			entry.PercentWatched = 100
+		} else {
+			entry.PercentWatched = 0
+			entry.Position = 0
+		}
+	})
+}
+
+// Flush saves changes not saved yet, such as the latest playback position
+func (s *WatchHistoryService) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dirty {
+		return nil
+	}
+	return s.save()
+}
+
+// GetWatchEntry returns the playback state of a video and whether it has been opened before
+func (s *WatchHistoryService) GetWatchEntry(videoPath string) (WatchEntry, bool, error) {
+	hash, err := s.videoHash(videoPath)
+	if err != nil {
+		return WatchEntry{}, false, fmt.Errorf("failed to hash video: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history, err := s.load()
+	if err != nil {
+		return WatchEntry{}, false, err
+	}
+	entry, exists := history[hash]
+	entry.ResumeFrom = entry.resumePosition()
+	return entry, exists, nil
+}
+
+// WatchedVideos returns which of videoPaths are marked as watched, reading
+// the history and hash cache once for all of them
+func (s *WatchHistoryService) WatchedVideos(videoPaths []string) (map[string]bool, error) {
+	hashes := s.cacheService.GetFileHashes(videoPaths)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	watched := make(map[string]bool, len(videoPaths))
+	for _, path := range videoPaths {
+		if hash, ok := hashes[path]; ok && history[hash].Watched {
+			watched[path] = true
+		}
+	}
+	return watched, nil
+}
+
+// resumePosition returns where playback of the entry should continue, 0 to
+// start from the beginning
+func (e WatchEntry) resumePosition() float64 {
+	if e.Watched || e.Position < resumeMargin {
+		return 0
+	}
+	if e.Duration > 0 && e.Position > e.Duration-resumeMargin {
+		return 0
+	}
+	return e.Position
+}
+
+// GetContinueWatching returns started but unfinished videos, most recently
+// opened first. Videos that can no longer be found are left out.
+func (s *WatchHistoryService) GetContinueWatching(limit int) ([]WatchEntry, error) {
+	entries, err := s.GetWatchHistory()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []WatchEntry{}
+	for _, entry := range entries {
+		if entry.VideoPath == "" || entry.ResumeFrom == 0 {
+			continue
+		}
+		result = append(result, entry)
+		if limit > 0 && len(result) == limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// GetWatchHistory returns every video in the history, most recently opened
+// first. Videos that were moved are found again by their hash.
+func (s *WatchHistoryService) GetWatchHistory() ([]WatchEntry, error) {
+	s.mu.Lock()
+	history, err := s.load()
+	entries := make([]WatchEntry, 0, len(history))
+	for _, entry := range history {
+		entries = append(entries, entry)
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, entry := range entries {
+		if _, err := os.Stat(entry.VideoPath); err != nil {
+			entry.VideoPath = s.cacheService.FindPathByHash(entry.VideoHash)
+		}
+		entry.ResumeFrom = entry.resumePosition()
+		entries[i] = entry
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastOpened.After(entries[j].LastOpened)
+	})
+	return entries, nil
+}
+
+// update applies a change to a video's entry and saves the history
+func (s *WatchHistoryService) update(videoPath string, change func(entry *WatchEntry)) error {
+	return s.apply(videoPath, change, false)
+}
+
+// updatePosition applies a playback position change to a video's entry. The
+// history is saved when the video becomes watched or the last save was a
+// while ago; Flush saves the rest.
+func (s *WatchHistoryService) updatePosition(videoPath string, change func(entry *WatchEntry)) error {
+	return s.apply(videoPath, change, true)
+}
+
+// apply changes a video's entry in memory and saves the history, unless the
+// change may wait and was saved recently
+func (s *WatchHistoryService) apply(videoPath string, change func(entry *WatchEntry), throttled bool) error {
+	hash, err := s.videoHash(videoPath)
+	if err != nil {
+		return fmt.Errorf("failed to hash video: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history, err := s.load()
+	if err != nil {
+		return err
+	}
+	entry := history[hash]
+	wasWatched := entry.Watched
+	entry.VideoHash = hash
+	entry.VideoPath = videoPath
+	change(&entry)
+	history[hash] = entry
+	s.dirty = true
+
+	if throttled && entry.Watched == wasWatched && time.Since(s.lastSaved) < positionSaveInterval {
+		return nil
+	}
+	return s.save()
+}
+
+// videoHash returns the hash of a video, remembering it while the file is
+// unchanged so playback updates don't read the hash cache each time
+func (s *WatchHistoryService) videoHash(videoPath string) (string, error) {
+	fileInfo, err := os.Stat(videoPath)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	known, exists := s.hashes[videoPath]
+	s.mu.Unlock()
+	if exists && known.lastModified.Equal(fileInfo.ModTime()) && known.fileSize == fileInfo.Size() {
+		return known.hash, nil
+	}
+
+	hash, err := s.cacheService.GetFileHash(videoPath)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	s.hashes[videoPath] = hashedVideo{hash: hash, lastModified: fileInfo.ModTime(), fileSize: fileInfo.Size()}
+	s.mu.Unlock()
+	return hash, nil
+}
+
+// load returns the watch history, reading it on first use. Callers must hold mu.
+func (s *WatchHistoryService) load() (map[string]WatchEntry, error) {
+	if s.history != nil {
+		return s.history, nil
+	}
+	history := make(map[string]WatchEntry)
+	if err := readJSONFile(filepath.Join(s.appDataDir, watchHistoryFileName), &history); err != nil {
+		return nil, fmt.Errorf("failed to read watch history: %v", err)
+	}
+	s.history = history
+	return history, nil
+}
+
+// save writes the watch history. Callers must hold mu.
+func (s *WatchHistoryService) save() error {
+	if err := writeJSONFile(filepath.Join(s.appDataDir, watchHistoryFileName), s.history); err != nil {
+		return err
+	}
+	s.dirty = false
+	s.lastSaved = time.Now()
+	return nil
+}
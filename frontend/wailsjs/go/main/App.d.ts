@@ -4,15 +4,77 @@ import {services} from '../models';
 import {models} from '../models';
 import {fansly} from '../models';
 
+export function AddBookmark(arg1:services.Bookmark):Promise<services.Bookmark>;
+
+export function AddToPlaylist(arg1:string,arg2:Array<string>):Promise<void>;
+
 export function BrowseForFile(arg1:string,arg2:string):Promise<string>;
 
 export function BrowseForFolder(arg1:string):Promise<string>;
 
+export function CheckLibraryIntegrity(arg1:boolean):Promise<void>;
+
+export function CheckVideoIntegrity(arg1:string,arg2:boolean):Promise<services.IntegrityResult>;
+
+export function ClearChatSync():Promise<services.TimingAnalysis>;
+
+export function ClearPlayQueue():Promise<void>;
+
+export function CreateAnimatedClip(arg1:number,arg2:number,arg3:string,arg4:services.AnimatedClipOptions):Promise<services.ClipResult>;
+
+export function CreateBatchClips(arg1:Array<services.BatchClipRow>,arg2:services.ClipOptions):Promise<Array<services.ClipResult>>;
+
 export function CreateClip(arg1:number,arg2:number,arg3:string):Promise<services.ClipResult>;
 
+export function CreateClipAroundMessage(arg1:string,arg2:number,arg3:number,arg4:string,arg5:services.ClipOptions):Promise<services.ClipResult>;
+
+export function CreateClipWithOptions(arg1:number,arg2:number,arg3:string,arg4:services.ClipOptions):Promise<services.ClipResult>;
+
+export function CreateCompilation(arg1:Array<services.CompilationSegment>,arg2:string,arg3:services.CompilationOptions):Promise<services.ClipResult>;
+
+export function CreatePlaylist(arg1:string,arg2:services.SmartPlaylistRule):Promise<services.Playlist>;
+
+export function DeleteBookmark(arg1:string):Promise<void>;
+
+export function DeleteClip(arg1:string):Promise<void>;
+
+export function DeleteClipPreset(arg1:string):Promise<void>;
+
+export function DeletePlaylist(arg1:string):Promise<void>;
+
+export function ExportBookmarksMarkdown():Promise<string>;
+
+export function ExportMarkers(arg1:Array<services.EditorMarker>,arg2:services.MarkerExportOptions):Promise<string>;
+
+export function FindNonFaststartVideos():Promise<Array<services.FaststartCandidate>>;
+
+export function FlushWatchHistory():Promise<void>;
+
+export function GenerateContactSheet(arg1:string,arg2:services.ContactSheetOptions):Promise<string>;
+
+export function GenerateLibrarySeekPreviews():Promise<number>;
+
+export function GenerateMissingContactSheets(arg1:Array<string>,arg2:services.ContactSheetOptions):Promise<void>;
+
+export function GetAllBookmarks():Promise<Array<services.VideoBookmarks>>;
+
 export function GetAllChatMessages():Promise<Array<models.ChatMessage>>;
 
-export function GetClips():Promise<Array<string>>;
+export function GetAudioAnalysis():Promise<services.AudioAnalysis>;
+
+export function GetBookmarkMarkers():Promise<Array<services.EditorMarker>>;
+
+export function GetBookmarks():Promise<Array<services.Bookmark>>;
+
+export function GetClipMetadata(arg1:string):Promise<services.ClipMetadata>;
+
+export function GetClipNamingOptions():Promise<services.ClipNamingOptions>;
+
+export function GetClipPresets():Promise<Array<services.ClipPreset>>;
+
+export function GetClips():Promise<Array<services.ClipRecord>>;
+
+export function GetContinueWatching(arg1:number):Promise<Array<services.WatchEntry>>;
 
 export function GetCurrentClipsDir():Promise<string>;
 
@@ -20,22 +82,106 @@ export function GetFanslyConfig():Promise<fansly.Config>;
 
 export function GetFanslyStreams():Promise<fansly.StreamsResult>;
 
+export function GetIntegrityReport():Promise<Array<services.IntegrityResult>>;
+
+export function GetMediaInfo():Promise<services.MediaInfo>;
+
+export function GetMediaTracks():Promise<services.MediaTracks>;
+
 export function GetMessagesAtTime(arg1:number,arg2:number):Promise<Array<models.ChatMessage>>;
 
+export function GetPlayQueue():Promise<services.QueueState>;
+
+export function GetPlaylistItems(arg1:string):Promise<Array<services.PlaylistItem>>;
+
+export function GetPlaylists():Promise<Array<services.Playlist>>;
+
+export function GetRecordingGroups():Promise<Array<services.RecordingGroup>>;
+
+export function GetSeekPreviews():Promise<services.SeekPreviews>;
+
+export function GetSkipSegments():Promise<services.DeadAirAnalysis>;
+
+export function GetSubtitleTrack(arg1:number):Promise<string>;
+
+export function GetTimingAnalysis():Promise<services.TimingAnalysis>;
+
+export function GetTranscript():Promise<services.Transcript>;
+
+export function GetTranscriptAtTime(arg1:number,arg2:number):Promise<Array<models.TranscriptCue>>;
+
+export function GetTranscriptTrack():Promise<string>;
+
 export function GetVideoFileInfo():Promise<Record<string, string>>;
 
+export function GetWatchHistory():Promise<Array<services.WatchEntry>>;
+
+export function GetWatchState():Promise<services.WatchEntry>;
+
+export function GrabFrame(arg1:number,arg2:string,arg3:services.FrameGrabOptions):Promise<services.ClipResult>;
+
+export function GrabFrameBurst(arg1:number,arg2:number,arg3:string,arg4:services.FrameBurstOptions):Promise<services.FrameBurstResult>;
+
+export function JumpToClipSource(arg1:string):Promise<services.ClipSourceMoment>;
+
 export function LoadChatFromPath(arg1:string):Promise<string>;
 
 export function LoadFanslyStream(arg1:string):Promise<fansly.StreamResult>;
 
+export function LoadRecording(arg1:string):Promise<services.RecordingGroup>;
+
+export function LoadTranscriptFile(arg1:string):Promise<services.Transcript>;
+
 export function LoadVideoFromPath(arg1:string):Promise<string>;
 
+export function MarkVideoWatched(arg1:string,arg2:boolean):Promise<void>;
+
 export function OpenChatFile(arg1:Array<string>):Promise<string>;
 
 export function OpenClipsFolder():Promise<void>;
 
 export function OpenVideoFile():Promise<string>;
 
+export function OptimizeFaststart(arg1:Array<string>):Promise<void>;
+
+export function PlayNext():Promise<services.NowPlaying>;
+
+export function PlayPlaylist(arg1:string,arg2:string):Promise<services.NowPlaying>;
+
+export function PreviewBatchClipsFromChapters():Promise<services.BatchClipPreview>;
+
+export function PreviewBatchClipsFromFile(arg1:string):Promise<services.BatchClipPreview>;
+
+export function QueuePlayNext(arg1:string):Promise<void>;
+
+export function RemoveFromPlaylist(arg1:string,arg2:string):Promise<void>;
+
+export function RenameClip(arg1:string,arg2:string):Promise<services.ClipRecord>;
+
+export function RepairVideo(arg1:string,arg2:string,arg3:string):Promise<services.RepairResult>;
+
+export function SaveClipPreset(arg1:services.ClipPreset):Promise<void>;
+
 export function SaveFanslyConfig(arg1:fansly.Config):Promise<void>;
 
+export function ScanDiscontinuities():Promise<services.TimingAnalysis>;
+
+export function SearchTranscript(arg1:string):Promise<Array<services.TranscriptHit>>;
+
+export function SeekRecording(arg1:number):Promise<services.RecordingPosition>;
+
+export function SelectAudioTracks(arg1:services.TrackSelection):Promise<services.TrackVariant>;
+
+export function SetClipNamingOptions(arg1:services.ClipNamingOptions):Promise<void>;
+
 export function SetClipStorageOption(arg1:string,arg2:string):Promise<void>;
+
+export function SetClipTags(arg1:string,arg2:Array<string>):Promise<services.ClipRecord>;
+
+export function SyncChatMessage(arg1:string,arg2:number):Promise<services.TimingAnalysis>;
+
+export function UpdateBookmark(arg1:services.Bookmark):Promise<services.Bookmark>;
+
+export function UpdatePlaylist(arg1:services.Playlist):Promise<services.Playlist>;
+
+export function UpdateWatchPosition(arg1:number,arg2:number):Promise<void>;
@@ -2,6 +2,14 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AddBookmark(arg1) {
+  return window['go']['main']['App']['AddBookmark'](arg1);
+}
+
+export function AddToPlaylist(arg1, arg2) {
+  return window['go']['main']['App']['AddToPlaylist'](arg1, arg2);
+}
+
 export function BrowseForFile(arg1, arg2) {
   return window['go']['main']['App']['BrowseForFile'](arg1, arg2);
 }
@@ -10,18 +18,134 @@ export function BrowseForFolder(arg1) {
   return window['go']['main']['App']['BrowseForFolder'](arg1);
 }
 
+export function CheckLibraryIntegrity(arg1) {
+  return window['go']['main']['App']['CheckLibraryIntegrity'](arg1);
+}
+
+export function CheckVideoIntegrity(arg1, arg2) {
+  return window['go']['main']['App']['CheckVideoIntegrity'](arg1, arg2);
+}
+
+export function ClearChatSync() {
+  return window['go']['main']['App']['ClearChatSync']();
+}
+
+export function ClearPlayQueue() {
+  return window['go']['main']['App']['ClearPlayQueue']();
+}
+
+export function CreateAnimatedClip(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['CreateAnimatedClip'](arg1, arg2, arg3, arg4);
+}
+
+export function CreateBatchClips(arg1, arg2) {
+  return window['go']['main']['App']['CreateBatchClips'](arg1, arg2);
+}
+
 export function CreateClip(arg1, arg2, arg3) {
   return window['go']['main']['App']['CreateClip'](arg1, arg2, arg3);
 }
 
+export function CreateClipAroundMessage(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['CreateClipAroundMessage'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function CreateClipWithOptions(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['CreateClipWithOptions'](arg1, arg2, arg3, arg4);
+}
+
+export function CreateCompilation(arg1, arg2, arg3) {
+  return window['go']['main']['App']['CreateCompilation'](arg1, arg2, arg3);
+}
+
+export function CreatePlaylist(arg1, arg2) {
+  return window['go']['main']['App']['CreatePlaylist'](arg1, arg2);
+}
+
+export function DeleteBookmark(arg1) {
+  return window['go']['main']['App']['DeleteBookmark'](arg1);
+}
+
+export function DeleteClip(arg1) {
+  return window['go']['main']['App']['DeleteClip'](arg1);
+}
+
+export function DeleteClipPreset(arg1) {
+  return window['go']['main']['App']['DeleteClipPreset'](arg1);
+}
+
+export function DeletePlaylist(arg1) {
+  return window['go']['main']['App']['DeletePlaylist'](arg1);
+}
+
+export function ExportBookmarksMarkdown() {
+  return window['go']['main']['App']['ExportBookmarksMarkdown']();
+}
+
+export function ExportMarkers(arg1, arg2) {
+  return window['go']['main']['App']['ExportMarkers'](arg1, arg2);
+}
+
+export function FindNonFaststartVideos() {
+  return window['go']['main']['App']['FindNonFaststartVideos']();
+}
+
+export function FlushWatchHistory() {
+  return window['go']['main']['App']['FlushWatchHistory']();
+}
+
+export function GenerateContactSheet(arg1, arg2) {
+  return window['go']['main']['App']['GenerateContactSheet'](arg1, arg2);
+}
+
+export function GenerateLibrarySeekPreviews() {
+  return window['go']['main']['App']['GenerateLibrarySeekPreviews']();
+}
+
+export function GenerateMissingContactSheets(arg1, arg2) {
+  return window['go']['main']['App']['GenerateMissingContactSheets'](arg1, arg2);
+}
+
+export function GetAllBookmarks() {
+  return window['go']['main']['App']['GetAllBookmarks']();
+}
+
 export function GetAllChatMessages() {
   return window['go']['main']['App']['GetAllChatMessages']();
 }
 
+export function GetAudioAnalysis() {
+  return window['go']['main']['App']['GetAudioAnalysis']();
+}
+
+export function GetBookmarkMarkers() {
+  return window['go']['main']['App']['GetBookmarkMarkers']();
+}
+
+export function GetBookmarks() {
+  return window['go']['main']['App']['GetBookmarks']();
+}
+
+export function GetClipMetadata(arg1) {
+  return window['go']['main']['App']['GetClipMetadata'](arg1);
+}
+
+export function GetClipNamingOptions() {
+  return window['go']['main']['App']['GetClipNamingOptions']();
+}
+
+export function GetClipPresets() {
+  return window['go']['main']['App']['GetClipPresets']();
+}
+
 export function GetClips() {
   return window['go']['main']['App']['GetClips']();
 }
 
+export function GetContinueWatching(arg1) {
+  return window['go']['main']['App']['GetContinueWatching'](arg1);
+}
+
 export function GetCurrentClipsDir() {
   return window['go']['main']['App']['GetCurrentClipsDir']();
 }
@@ -34,14 +158,90 @@ export function GetFanslyStreams() {
   return window['go']['main']['App']['GetFanslyStreams']();
 }
 
+export function GetIntegrityReport() {
+  return window['go']['main']['App']['GetIntegrityReport']();
+}
+
+export function GetMediaInfo() {
+  return window['go']['main']['App']['GetMediaInfo']();
+}
+
+export function GetMediaTracks() {
+  return window['go']['main']['App']['GetMediaTracks']();
+}
+
 export function GetMessagesAtTime(arg1, arg2) {
   return window['go']['main']['App']['GetMessagesAtTime'](arg1, arg2);
 }
 
+export function GetPlayQueue() {
+  return window['go']['main']['App']['GetPlayQueue']();
+}
+
+export function GetPlaylistItems(arg1) {
+  return window['go']['main']['App']['GetPlaylistItems'](arg1);
+}
+
+export function GetPlaylists() {
+  return window['go']['main']['App']['GetPlaylists']();
+}
+
+export function GetRecordingGroups() {
+  return window['go']['main']['App']['GetRecordingGroups']();
+}
+
+export function GetSeekPreviews() {
+  return window['go']['main']['App']['GetSeekPreviews']();
+}
+
+export function GetSkipSegments() {
+  return window['go']['main']['App']['GetSkipSegments']();
+}
+
+export function GetSubtitleTrack(arg1) {
+  return window['go']['main']['App']['GetSubtitleTrack'](arg1);
+}
+
+export function GetTimingAnalysis() {
+  return window['go']['main']['App']['GetTimingAnalysis']();
+}
+
+export function GetTranscript() {
+  return window['go']['main']['App']['GetTranscript']();
+}
+
+export function GetTranscriptAtTime(arg1, arg2) {
+  return window['go']['main']['App']['GetTranscriptAtTime'](arg1, arg2);
+}
+
+export function GetTranscriptTrack() {
+  return window['go']['main']['App']['GetTranscriptTrack']();
+}
+
 export function GetVideoFileInfo() {
   return window['go']['main']['App']['GetVideoFileInfo']();
 }
 
+export function GetWatchHistory() {
+  return window['go']['main']['App']['GetWatchHistory']();
+}
+
+export function GetWatchState() {
+  return window['go']['main']['App']['GetWatchState']();
+}
+
+export function GrabFrame(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GrabFrame'](arg1, arg2, arg3);
+}
+
+export function GrabFrameBurst(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['GrabFrameBurst'](arg1, arg2, arg3, arg4);
+}
+
+export function JumpToClipSource(arg1) {
+  return window['go']['main']['App']['JumpToClipSource'](arg1);
+}
+
 export function LoadChatFromPath(arg1) {
   return window['go']['main']['App']['LoadChatFromPath'](arg1);
 }
@@ -50,10 +250,22 @@ export function LoadFanslyStream(arg1) {
   return window['go']['main']['App']['LoadFanslyStream'](arg1);
 }
 
+export function LoadRecording(arg1) {
+  return window['go']['main']['App']['LoadRecording'](arg1);
+}
+
+export function LoadTranscriptFile(arg1) {
+  return window['go']['main']['App']['LoadTranscriptFile'](arg1);
+}
+
 export function LoadVideoFromPath(arg1) {
   return window['go']['main']['App']['LoadVideoFromPath'](arg1);
 }
 
+export function MarkVideoWatched(arg1, arg2) {
+  return window['go']['main']['App']['MarkVideoWatched'](arg1, arg2);
+}
+
 export function OpenChatFile(arg1) {
   return window['go']['main']['App']['OpenChatFile'](arg1);
 }
@@ -66,10 +278,90 @@ export function OpenVideoFile() {
   return window['go']['main']['App']['OpenVideoFile']();
 }
 
+export function OptimizeFaststart(arg1) {
+  return window['go']['main']['App']['OptimizeFaststart'](arg1);
+}
+
+export function PlayNext() {
+  return window['go']['main']['App']['PlayNext']();
+}
+
+export function PlayPlaylist(arg1, arg2) {
+  return window['go']['main']['App']['PlayPlaylist'](arg1, arg2);
+}
+
+export function PreviewBatchClipsFromChapters() {
+  return window['go']['main']['App']['PreviewBatchClipsFromChapters']();
+}
+
+export function PreviewBatchClipsFromFile(arg1) {
+  return window['go']['main']['App']['PreviewBatchClipsFromFile'](arg1);
+}
+
+export function QueuePlayNext(arg1) {
+  return window['go']['main']['App']['QueuePlayNext'](arg1);
+}
+
+export function RemoveFromPlaylist(arg1, arg2) {
+  return window['go']['main']['App']['RemoveFromPlaylist'](arg1, arg2);
+}
+
+export function RenameClip(arg1, arg2) {
+  return window['go']['main']['App']['RenameClip'](arg1, arg2);
+}
+
+export function RepairVideo(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RepairVideo'](arg1, arg2, arg3);
+}
+
+export function SaveClipPreset(arg1) {
+  return window['go']['main']['App']['SaveClipPreset'](arg1);
+}
+
 export function SaveFanslyConfig(arg1) {
   return window['go']['main']['App']['SaveFanslyConfig'](arg1);
 }
 
+export function ScanDiscontinuities() {
+  return window['go']['main']['App']['ScanDiscontinuities']();
+}
+
+export function SearchTranscript(arg1) {
+  return window['go']['main']['App']['SearchTranscript'](arg1);
+}
+
+export function SeekRecording(arg1) {
+  return window['go']['main']['App']['SeekRecording'](arg1);
+}
+
+export function SelectAudioTracks(arg1) {
+  return window['go']['main']['App']['SelectAudioTracks'](arg1);
+}
+
+export function SetClipNamingOptions(arg1) {
+  return window['go']['main']['App']['SetClipNamingOptions'](arg1);
+}
+
 export function SetClipStorageOption(arg1, arg2) {
   return window['go']['main']['App']['SetClipStorageOption'](arg1, arg2);
 }
+
+export function SetClipTags(arg1, arg2) {
+  return window['go']['main']['App']['SetClipTags'](arg1, arg2);
+}
+
+export function SyncChatMessage(arg1, arg2) {
+  return window['go']['main']['App']['SyncChatMessage'](arg1, arg2);
+}
+
+export function UpdateBookmark(arg1) {
+  return window['go']['main']['App']['UpdateBookmark'](arg1);
+}
+
+export function UpdatePlaylist(arg1) {
+  return window['go']['main']['App']['UpdatePlaylist'](arg1);
+}
+
+export function UpdateWatchPosition(arg1, arg2) {
+  return window['go']['main']['App']['UpdateWatchPosition'](arg1, arg2);
+}
@@ -198,26 +198,1574 @@ export namespace models {
 		    return a;
 		}
 	}
+	export class TranscriptCue {
+	    start: number;
+	    end: number;
+	    text: string;
+	    speaker?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TranscriptCue(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.start = source["start"];
+	        this.end = source["end"];
+	        this.text = source["text"];
+	        this.speaker = source["speaker"];
+	    }
+	}
 
 }
 
 export namespace services {
 	
-	export class ClipResult {
+	export class AnimatedClipOptions {
+	    format: string;
+	    width: number;
+	    fps: number;
+	    crossfade: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new AnimatedClipOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.format = source["format"];
+	        this.width = source["width"];
+	        this.fps = source["fps"];
+	        this.crossfade = source["crossfade"];
+	    }
+	}
+	export class AudioAnalysis {
+	    videoPath: string;
+	    duration: number;
+	    waveformInterval: number;
+	    waveform: number[];
+	    loudnessInterval: number;
+	    loudness: number[];
+	    integratedLoudness: number;
+	    loudnessRange: number;
+	    truePeak: number;
+	    suggestedGain: number;
+	    // Go type: time
+	    analyzedAt: any;
+	    analyzed: boolean;
+	    analyzing: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new AudioAnalysis(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.videoPath = source["videoPath"];
+	        this.duration = source["duration"];
+	        this.waveformInterval = source["waveformInterval"];
+	        this.waveform = source["waveform"];
+	        this.loudnessInterval = source["loudnessInterval"];
+	        this.loudness = source["loudness"];
+	        this.integratedLoudness = source["integratedLoudness"];
+	        this.loudnessRange = source["loudnessRange"];
+	        this.truePeak = source["truePeak"];
+	        this.suggestedGain = source["suggestedGain"];
+	        this.analyzedAt = this.convertValues(source["analyzedAt"], null);
+	        this.analyzed = source["analyzed"];
+	        this.analyzing = source["analyzing"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BatchClipRow {
+	    row: number;
+	    startTime: number;
+	    endTime: number;
+	    title: string;
+	    problem?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new BatchClipRow(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.row = source["row"];
+	        this.startTime = source["startTime"];
+	        this.endTime = source["endTime"];
+	        this.title = source["title"];
+	        this.problem = source["problem"];
+	    }
+	}
+	export class BatchClipPreview {
+	    videoPath: string;
+	    videoDuration: number;
+	    rows: BatchClipRow[];
+	    validRows: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new BatchClipPreview(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.videoPath = source["videoPath"];
+	        this.videoDuration = source["videoDuration"];
+	        this.rows = this.convertValues(source["rows"], BatchClipRow);
+	        this.validRows = source["validRows"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Bookmark {
+	    id: string;
+	    time: number;
+	    endTime?: number;
+	    name: string;
+	    note?: string;
+	    color?: string;
+	    tags: string[];
+	    // Go type: time
+	    createdAt: any;
+	    // Go type: time
+	    updatedAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Bookmark(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.time = source["time"];
+	        this.endTime = source["endTime"];
+	        this.name = source["name"];
+	        this.note = source["note"];
+	        this.color = source["color"];
+	        this.tags = source["tags"];
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	        this.updatedAt = this.convertValues(source["updatedAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ChatOverlayOptions {
+	    enabled: boolean;
+	    position: string;
+	    width: number;
+	    fontSize: number;
+	    backgroundOpacity: number;
+	    highlightTips: boolean;
+	    maxMessages: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ChatOverlayOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.position = source["position"];
+	        this.width = source["width"];
+	        this.fontSize = source["fontSize"];
+	        this.backgroundOpacity = source["backgroundOpacity"];
+	        this.highlightTips = source["highlightTips"];
+	        this.maxMessages = source["maxMessages"];
+	    }
+	}
+	export class CompilationSegment {
+	    sourcePath: string;
+	    startTime: number;
+	    endTime: number;
+	    title?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CompilationSegment(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.sourcePath = source["sourcePath"];
+	        this.startTime = source["startTime"];
+	        this.endTime = source["endTime"];
+	        this.title = source["title"];
+	    }
+	}
+	export class ClipMetadata {
+	    version: number;
+	    sourcePath: string;
+	    sourceHash?: string;
+	    model?: string;
+	    startTime: number;
+	    duration: number;
+	    title: string;
+	    preset?: string;
+	    // Go type: time
+	    createdAt: any;
+	    tags: string[];
+	    chat?: models.ChatMessage[];
+	    segments?: CompilationSegment[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ClipMetadata(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.version = source["version"];
+	        this.sourcePath = source["sourcePath"];
+	        this.sourceHash = source["sourceHash"];
+	        this.model = source["model"];
+	        this.startTime = source["startTime"];
+	        this.duration = source["duration"];
+	        this.title = source["title"];
+	        this.preset = source["preset"];
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	        this.tags = source["tags"];
+	        this.chat = this.convertValues(source["chat"], models.ChatMessage);
+	        this.segments = this.convertValues(source["segments"], CompilationSegment);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ClipNamingOptions {
+	    template: string;
+	    modelSubfolders: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ClipNamingOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.template = source["template"];
+	        this.modelSubfolders = source["modelSubfolders"];
+	    }
+	}
+	export class CropOptions {
+	    mode: string;
+	    x: number;
+	    y: number;
+	    width: number;
+	    height: number;
+	    aspect: string;
+	    focusX: number;
+	    focusY: number;
+	    blurFill: boolean;
+	    stackChat: boolean;
+	    chatHeight: number;
+	    outputWidth: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new CropOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mode = source["mode"];
+	        this.x = source["x"];
+	        this.y = source["y"];
+	        this.width = source["width"];
+	        this.height = source["height"];
+	        this.aspect = source["aspect"];
+	        this.focusX = source["focusX"];
+	        this.focusY = source["focusY"];
+	        this.blurFill = source["blurFill"];
+	        this.stackChat = source["stackChat"];
+	        this.chatHeight = source["chatHeight"];
+	        this.outputWidth = source["outputWidth"];
+	    }
+	}
+	export class WatermarkOptions {
+	    text?: string;
+	    imagePath?: string;
+	    position: string;
+	    opacity: number;
+	    scale: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new WatermarkOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.text = source["text"];
+	        this.imagePath = source["imagePath"];
+	        this.position = source["position"];
+	        this.opacity = source["opacity"];
+	        this.scale = source["scale"];
+	    }
+	}
+	export class PolishOptions {
+	    normalizeLoudness: boolean;
+	    targetLoudness: number;
+	    audioFadeIn: number;
+	    audioFadeOut: number;
+	    videoFadeIn: number;
+	    videoFadeOut: number;
+	    watermark: WatermarkOptions;
+	    introPath?: string;
+	    outroPath?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PolishOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.normalizeLoudness = source["normalizeLoudness"];
+	        this.targetLoudness = source["targetLoudness"];
+	        this.audioFadeIn = source["audioFadeIn"];
+	        this.audioFadeOut = source["audioFadeOut"];
+	        this.videoFadeIn = source["videoFadeIn"];
+	        this.videoFadeOut = source["videoFadeOut"];
+	        this.watermark = this.convertValues(source["watermark"], WatermarkOptions);
+	        this.introPath = source["introPath"];
+	        this.outroPath = source["outroPath"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TrackSelection {
+	    audioStreams: number[];
+	    mixAudio: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new TrackSelection(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.audioStreams = source["audioStreams"];
+	        this.mixAudio = source["mixAudio"];
+	    }
+	}
+	export class ClipOptions {
+	    preset?: string;
+	    chatOverlay: ChatOverlayOptions;
+	    crop: CropOptions;
+	    polish: PolishOptions;
+	    tracks: TrackSelection;
+	
+	    static createFrom(source: any = {}) {
+	        return new ClipOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.preset = source["preset"];
+	        this.chatOverlay = this.convertValues(source["chatOverlay"], ChatOverlayOptions);
+	        this.crop = this.convertValues(source["crop"], CropOptions);
+	        this.polish = this.convertValues(source["polish"], PolishOptions);
+	        this.tracks = this.convertValues(source["tracks"], TrackSelection);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ClipPreset {
+	    name: string;
+	    options: ClipOptions;
+	
+	    static createFrom(source: any = {}) {
+	        return new ClipPreset(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.options = this.convertValues(source["options"], ClipOptions);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ClipRecord {
+	    filePath: string;
+	    fileName: string;
+	    fileSize: number;
+	    // Go type: time
+	    modifiedAt: any;
+	    thumbnailPath?: string;
+	    metadata?: ClipMetadata;
+	
+	    static createFrom(source: any = {}) {
+	        return new ClipRecord(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.filePath = source["filePath"];
+	        this.fileName = source["fileName"];
+	        this.fileSize = source["fileSize"];
+	        this.modifiedAt = this.convertValues(source["modifiedAt"], null);
+	        this.thumbnailPath = source["thumbnailPath"];
+	        this.metadata = this.convertValues(source["metadata"], ClipMetadata);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ClipResult {
+	    success: boolean;
+	    filePath: string;
+	    errorMessage?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ClipResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.filePath = source["filePath"];
+	        this.errorMessage = source["errorMessage"];
+	    }
+	}
+	export class ClipSourceMoment {
+	    videoPath: string;
+	    videoUrl?: string;
+	    chatPath?: string;
+	    startTime: number;
+	    duration: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ClipSourceMoment(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.videoPath = source["videoPath"];
+	        this.videoUrl = source["videoUrl"];
+	        this.chatPath = source["chatPath"];
+	        this.startTime = source["startTime"];
+	        this.duration = source["duration"];
+	    }
+	}
+	export class CompilationOptions {
+	    width: number;
+	    height: number;
+	    fps: number;
+	    crossfade: number;
+	    titleCards: boolean;
+	    titleCardDuration: number;
+	    trimDeadAir: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new CompilationOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.width = source["width"];
+	        this.height = source["height"];
+	        this.fps = source["fps"];
+	        this.crossfade = source["crossfade"];
+	        this.titleCards = source["titleCards"];
+	        this.titleCardDuration = source["titleCardDuration"];
+	        this.trimDeadAir = source["trimDeadAir"];
+	    }
+	}
+	export class ContactSheetOptions {
+	    columns: number;
+	    rows: number;
+	    tileWidth: number;
+	    location: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ContactSheetOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.columns = source["columns"];
+	        this.rows = source["rows"];
+	        this.tileWidth = source["tileWidth"];
+	        this.location = source["location"];
+	    }
+	}
+	export class SkipSegment {
+	    start: number;
+	    end: number;
+	    kind: string;
+	    confidence: number;
+	    signals: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SkipSegment(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.start = source["start"];
+	        this.end = source["end"];
+	        this.kind = source["kind"];
+	        this.confidence = source["confidence"];
+	        this.signals = source["signals"];
+	    }
+	}
+	export class DeadAirAnalysis {
+	    videoPath: string;
+	    duration: number;
+	    segments: SkipSegment[];
+	    analyzed: boolean;
+	    analyzing: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new DeadAirAnalysis(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.videoPath = source["videoPath"];
+	        this.duration = source["duration"];
+	        this.segments = this.convertValues(source["segments"], SkipSegment);
+	        this.analyzed = source["analyzed"];
+	        this.analyzing = source["analyzing"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Discontinuity {
+	    videoTime: number;
+	    kind: string;
+	    jump: number;
+	    missing: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Discontinuity(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.videoTime = source["videoTime"];
+	        this.kind = source["kind"];
+	        this.jump = source["jump"];
+	        this.missing = source["missing"];
+	    }
+	}
+	export class EditorMarker {
+	    time: number;
+	    duration: number;
+	    name: string;
+	    note?: string;
+	    color?: string;
+	    kind?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new EditorMarker(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.time = source["time"];
+	        this.duration = source["duration"];
+	        this.name = source["name"];
+	        this.note = source["note"];
+	        this.color = source["color"];
+	        this.kind = source["kind"];
+	    }
+	}
+	export class FaststartCandidate {
+	    videoPath: string;
+	    size: number;
+	    indexOffset: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FaststartCandidate(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.videoPath = source["videoPath"];
+	        this.size = source["size"];
+	        this.indexOffset = source["indexOffset"];
+	    }
+	}
+	export class FrameBurstOptions {
+	    format: string;
+	    chatOverlay: ChatOverlayOptions;
+	    interval: number;
+	    columns: number;
+	    tileWidth: number;
+	    saveFrames: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new FrameBurstOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.format = source["format"];
+	        this.chatOverlay = this.convertValues(source["chatOverlay"], ChatOverlayOptions);
+	        this.interval = source["interval"];
+	        this.columns = source["columns"];
+	        this.tileWidth = source["tileWidth"];
+	        this.saveFrames = source["saveFrames"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class FrameBurstResult {
+	    success: boolean;
+	    gridPath: string;
+	    framePaths: string[];
+	    errorMessage?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new FrameBurstResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.gridPath = source["gridPath"];
+	        this.framePaths = source["framePaths"];
+	        this.errorMessage = source["errorMessage"];
+	    }
+	}
+	export class FrameGrabOptions {
+	    format: string;
+	    chatOverlay: ChatOverlayOptions;
+	
+	    static createFrom(source: any = {}) {
+	        return new FrameGrabOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.format = source["format"];
+	        this.chatOverlay = this.convertValues(source["chatOverlay"], ChatOverlayOptions);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class IntegrityIssue {
+	    kind: string;
+	    detail: string;
+	    time?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new IntegrityIssue(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.detail = source["detail"];
+	        this.time = source["time"];
+	    }
+	}
+	export class IntegrityResult {
+	    videoPath: string;
+	    status: string;
+	    issues: IntegrityIssue[];
+	    errorCount: number;
+	    firstErrorTime: number;
+	    duration: number;
+	    repairs: string[];
+	    deep: boolean;
+	    // Go type: time
+	    checkedAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new IntegrityResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.videoPath = source["videoPath"];
+	        this.status = source["status"];
+	        this.issues = this.convertValues(source["issues"], IntegrityIssue);
+	        this.errorCount = source["errorCount"];
+	        this.firstErrorTime = source["firstErrorTime"];
+	        this.duration = source["duration"];
+	        this.repairs = source["repairs"];
+	        this.deep = source["deep"];
+	        this.checkedAt = this.convertValues(source["checkedAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class MarkerExportOptions {
+	    format: string;
+	    includeHighlights: boolean;
+	    includeTips: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new MarkerExportOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.format = source["format"];
+	        this.includeHighlights = source["includeHighlights"];
+	        this.includeTips = source["includeTips"];
+	    }
+	}
+	export class MediaChapter {
+	    start: number;
+	    end: number;
+	    title: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new MediaChapter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.start = source["start"];
+	        this.end = source["end"];
+	        this.title = source["title"];
+	    }
+	}
+	export class MediaStream {
+	    index: number;
+	    type: string;
+	    codec: string;
+	    codecLongName: string;
+	    profile?: string;
+	    bitRate?: number;
+	    width?: number;
+	    height?: number;
+	    frameRate?: string;
+	    fps?: number;
+	    pixelFormat?: string;
+	    sampleRate?: number;
+	    channels?: number;
+	    channelLayout?: string;
+	    language?: string;
+	    title?: string;
+	    default: boolean;
+	    tags: Record<string, string>;
+	
+	    static createFrom(source: any = {}) {
+	        return new MediaStream(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.index = source["index"];
+	        this.type = source["type"];
+	        this.codec = source["codec"];
+	        this.codecLongName = source["codecLongName"];
+	        this.profile = source["profile"];
+	        this.bitRate = source["bitRate"];
+	        this.width = source["width"];
+	        this.height = source["height"];
+	        this.frameRate = source["frameRate"];
+	        this.fps = source["fps"];
+	        this.pixelFormat = source["pixelFormat"];
+	        this.sampleRate = source["sampleRate"];
+	        this.channels = source["channels"];
+	        this.channelLayout = source["channelLayout"];
+	        this.language = source["language"];
+	        this.title = source["title"];
+	        this.default = source["default"];
+	        this.tags = source["tags"];
+	    }
+	}
+	export class MediaInfo {
+	    container: string;
+	    containerLongName: string;
+	    duration: number;
+	    startTime: number;
+	    size: number;
+	    bitRate: number;
+	    creationTime?: string;
+	    streams: MediaStream[];
+	    chapters: MediaChapter[];
+	    tags: Record<string, string>;
+	
+	    static createFrom(source: any = {}) {
+	        return new MediaInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.container = source["container"];
+	        this.containerLongName = source["containerLongName"];
+	        this.duration = source["duration"];
+	        this.startTime = source["startTime"];
+	        this.size = source["size"];
+	        this.bitRate = source["bitRate"];
+	        this.creationTime = source["creationTime"];
+	        this.streams = this.convertValues(source["streams"], MediaStream);
+	        this.chapters = this.convertValues(source["chapters"], MediaChapter);
+	        this.tags = source["tags"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class MediaTracks {
+	    audio: MediaStream[];
+	    subtitles: MediaStream[];
+	
+	    static createFrom(source: any = {}) {
+	        return new MediaTracks(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.audio = this.convertValues(source["audio"], MediaStream);
+	        this.subtitles = this.convertValues(source["subtitles"], MediaStream);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class NowPlaying {
+	    videoPath: string;
+	    videoUrl: string;
+	    chatPath?: string;
+	    contactSheet?: string;
+	    resumeFrom: number;
+	    finished: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new NowPlaying(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.videoPath = source["videoPath"];
+	        this.videoUrl = source["videoUrl"];
+	        this.chatPath = source["chatPath"];
+	        this.contactSheet = source["contactSheet"];
+	        this.resumeFrom = source["resumeFrom"];
+	        this.finished = source["finished"];
+	    }
+	}
+	export class PlaylistItem {
+	    videoPath: string;
+	    videoHash?: string;
+	    model?: string;
+	    date?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PlaylistItem(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.videoPath = source["videoPath"];
+	        this.videoHash = source["videoHash"];
+	        this.model = source["model"];
+	        this.date = source["date"];
+	    }
+	}
+	export class SmartPlaylistRule {
+	    model?: string;
+	    unwatchedOnly: boolean;
+	    newestFirst: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SmartPlaylistRule(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.model = source["model"];
+	        this.unwatchedOnly = source["unwatchedOnly"];
+	        this.newestFirst = source["newestFirst"];
+	    }
+	}
+	export class Playlist {
+	    id: string;
+	    name: string;
+	    items: PlaylistItem[];
+	    smart?: SmartPlaylistRule;
+	    // Go type: time
+	    createdAt: any;
+	    // Go type: time
+	    updatedAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Playlist(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.items = this.convertValues(source["items"], PlaylistItem);
+	        this.smart = this.convertValues(source["smart"], SmartPlaylistRule);
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	        this.updatedAt = this.convertValues(source["updatedAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class QueueState {
+	    playNext: PlaylistItem[];
+	    playlistId?: string;
+	    playlistName?: string;
+	    items: PlaylistItem[];
+	    index: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new QueueState(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.playNext = this.convertValues(source["playNext"], PlaylistItem);
+	        this.playlistId = source["playlistId"];
+	        this.playlistName = source["playlistName"];
+	        this.items = this.convertValues(source["items"], PlaylistItem);
+	        this.index = source["index"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RecordingPart {
+	    path: string;
+	    chatPath?: string;
+	    offset: number;
+	    duration: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new RecordingPart(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.chatPath = source["chatPath"];
+	        this.offset = source["offset"];
+	        this.duration = source["duration"];
+	    }
+	}
+	export class RecordingGroup {
+	    name: string;
+	    parts: RecordingPart[];
+	    duration: number;
+	    detectedBy: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RecordingGroup(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.parts = this.convertValues(source["parts"], RecordingPart);
+	        this.duration = source["duration"];
+	        this.detectedBy = source["detectedBy"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RecordingPosition {
+	    partIndex: number;
+	    partPath: string;
+	    localTime: number;
+	    videoUrl?: string;
+	    switched: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RecordingPosition(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.partIndex = source["partIndex"];
+	        this.partPath = source["partPath"];
+	        this.localTime = source["localTime"];
+	        this.videoUrl = source["videoUrl"];
+	        this.switched = source["switched"];
+	    }
+	}
+	export class RepairResult {
 	    success: boolean;
-	    filePath: string;
+	    action: string;
+	    outputPath: string;
+	    duration: number;
 	    errorMessage?: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new ClipResult(source);
+	        return new RepairResult(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.success = source["success"];
-	        this.filePath = source["filePath"];
+	        this.action = source["action"];
+	        this.outputPath = source["outputPath"];
+	        this.duration = source["duration"];
+	        this.errorMessage = source["errorMessage"];
+	    }
+	}
+	export class SeekPreviews {
+	    videoPath: string;
+	    videoHash: string;
+	    trackUrl?: string;
+	    source?: string;
+	    generating: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SeekPreviews(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.videoPath = source["videoPath"];
+	        this.videoHash = source["videoHash"];
+	        this.trackUrl = source["trackUrl"];
+	        this.source = source["source"];
+	        this.generating = source["generating"];
+	    }
+	}
+	export class SyncAnchor {
+	    videoTime: number;
+	    chatTime: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SyncAnchor(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.videoTime = source["videoTime"];
+	        this.chatTime = source["chatTime"];
+	    }
+	}
+	export class TimeMapPoint {
+	    videoTime: number;
+	    chatOffset: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TimeMapPoint(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.videoTime = source["videoTime"];
+	        this.chatOffset = source["chatOffset"];
+	    }
+	}
+	export class TimeMap {
+	    points: TimeMapPoint[];
+	
+	    static createFrom(source: any = {}) {
+	        return new TimeMap(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.points = this.convertValues(source["points"], TimeMapPoint);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TimingAnalysis {
+	    videoPath: string;
+	    duration: number;
+	    discontinuities: Discontinuity[];
+	    wallClockSpan: number;
+	    missingTime: number;
+	    unplacedMissing: number;
+	    anchors: SyncAnchor[];
+	    timeMap: TimeMap;
+	    // Go type: time
+	    scannedAt: any;
+	    scanned: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new TimingAnalysis(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.videoPath = source["videoPath"];
+	        this.duration = source["duration"];
+	        this.discontinuities = this.convertValues(source["discontinuities"], Discontinuity);
+	        this.wallClockSpan = source["wallClockSpan"];
+	        this.missingTime = source["missingTime"];
+	        this.unplacedMissing = source["unplacedMissing"];
+	        this.anchors = this.convertValues(source["anchors"], SyncAnchor);
+	        this.timeMap = this.convertValues(source["timeMap"], TimeMap);
+	        this.scannedAt = this.convertValues(source["scannedAt"], null);
+	        this.scanned = source["scanned"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TrackVariant {
+	    videoPath: string;
+	    selection: TrackSelection;
+	    url?: string;
+	    building: boolean;
+	    errorMessage?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TrackVariant(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.videoPath = source["videoPath"];
+	        this.selection = this.convertValues(source["selection"], TrackSelection);
+	        this.url = source["url"];
+	        this.building = source["building"];
 	        this.errorMessage = source["errorMessage"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Transcript {
+	    path: string;
+	    format: string;
+	    language?: string;
+	    cues: models.TranscriptCue[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Transcript(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.format = source["format"];
+	        this.language = source["language"];
+	        this.cues = this.convertValues(source["cues"], models.TranscriptCue);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TranscriptHit {
+	    cueIndex: number;
+	    start: number;
+	    end: number;
+	    text: string;
+	    speaker?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TranscriptHit(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.cueIndex = source["cueIndex"];
+	        this.start = source["start"];
+	        this.end = source["end"];
+	        this.text = source["text"];
+	        this.speaker = source["speaker"];
+	    }
+	}
+	export class VideoBookmarks {
+	    videoHash: string;
+	    videoPath: string;
+	    bookmarks: Bookmark[];
+	
+	    static createFrom(source: any = {}) {
+	        return new VideoBookmarks(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.videoHash = source["videoHash"];
+	        this.videoPath = source["videoPath"];
+	        this.bookmarks = this.convertValues(source["bookmarks"], Bookmark);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class WatchEntry {
+	    videoHash: string;
+	    videoPath: string;
+	    chatPath?: string;
+	    position: number;
+	    resumeFrom: number;
+	    duration: number;
+	    percentWatched: number;
+	    watched: boolean;
+	    // Go type: time
+	    lastOpened: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new WatchEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.videoHash = source["videoHash"];
+	        this.videoPath = source["videoPath"];
+	        this.chatPath = source["chatPath"];
+	        this.position = source["position"];
+	        this.resumeFrom = source["resumeFrom"];
+	        this.duration = source["duration"];
+	        this.percentWatched = source["percentWatched"];
+	        this.watched = source["watched"];
+	        this.lastOpened = this.convertValues(source["lastOpened"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 
 }